@@ -1 +1,21 @@
 package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/logging"
+)
+
+// Base returns the root deploy-runner command every other command is added to
+func Base() app.Command {
+	cmd := app.ContainerCommand(
+		"deploy-runner",
+		"Runs terraform deploys for registered stacks",
+		"deploy-runner plans and applies terraform stacks on request, serializing runs per stack workspace.",
+		app.NewHelpWriter(),
+	)
+	cmd.StringFlag(config.ConfigPath.String(), "config", "Yaml, json or toml config file, its values are overridden by env vars and flags")
+	cmd.BindEnv(config.EnvConfigPath)
+	cmd.AddComponent(logging.Component)
+	return cmd
+}
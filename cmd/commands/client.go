@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/client"
+	"deploy-runner/internal/output"
+)
+
+// clientCommand returns a container for commands that call a running server, they share the server, token and output
+// flags
+func clientCommand(use, short, long string) app.Command {
+	cmd := app.ContainerCommand(use, short, long, app.NewHelpWriter())
+	cmd.StringFlag(config.ServerURL.String(), "server", "Url of the deploy-runner server")
+	cmd.StringFlag(config.ServerToken.String(), "token", "Bearer token sent with every request to the server")
+	cmd.StringFlag(config.OutputFormat.String(), "output", "Output format, table, json, yaml or go-template=<template>")
+	cmd.AddComponent(client.Component, output.Component)
+	return cmd
+}
+
+// clientAction returns a command of a clientCommand that runs the action created by adapterFunc
+func clientAction(use, short string, adapterFunc interface{}, envVars ...config.EnvVar) app.Command {
+	cmd := app.ActionCommand(use, short, "", adapterFunc, envVars...)
+	cmd.BindEnv(config.EnvServerURL)
+	cmd.BindEnv(config.EnvServerToken)
+	return cmd
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/client"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	t.Run("TestServeValidate", testServeValidate)
+	t.Run("TestClientValidate", testClientValidate)
+	t.Run("TestRunValidate", testRunValidate)
+	t.Run("TestTestValidate", testTestValidate)
+}
+
+func newConfig(t *testing.T) *viper.Viper {
+	cfg := viper.New()
+	assert.NoError(t, config.LoadConfig(cfg))
+	cfg.Set(config.StorePath.String(), filepath.Join(t.TempDir(), "runs.db"))
+	return cfg
+}
+
+func testServeValidate(t *testing.T) {
+	serve := Serve()
+	Base().AddCommand(serve)
+	assert.NoError(t, serve.Validate(newConfig(t)))
+}
+
+func testClientValidate(t *testing.T) {
+	tests := []struct {
+		container func() app.Command
+		adapters  []interface{}
+	}{
+		{Runs, []interface{}{client.NewSubmitAction, client.NewListAction, client.NewGetAction, client.NewLogsAction,
+			client.NewApproveAction, client.NewCancelAction}},
+		{Stacks, []interface{}{client.NewStacksListAction, client.NewStacksGetAction}},
+		{Plans, []interface{}{client.NewPlansShowAction, client.NewPlansPendingAction}},
+	}
+	for _, test := range tests {
+		parent := test.container()
+		Base().AddCommand(parent)
+		for _, adapter := range test.adapters {
+			cmd := clientAction("action", "", adapter)
+			parent.AddCommand(cmd)
+			assert.NoError(t, cmd.Validate(newConfig(t)))
+		}
+	}
+}
+
+func testRunValidate(t *testing.T) {
+	run := Run()
+	Base().AddCommand(run)
+	assert.NoError(t, run.Validate(newConfig(t)))
+}
+
+func testTestValidate(t *testing.T) {
+	test := Test()
+	Base().AddCommand(test)
+	assert.NoError(t, test.Validate(newConfig(t)))
+}
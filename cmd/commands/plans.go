@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/client"
+)
+
+// Plans returns the client commands that show the plans of runs on a running deploy-runner server
+func Plans() app.Command {
+	cmd := clientCommand(
+		"plans",
+		"Shows the plans of runs on a deploy-runner server",
+		"Shows the resource changes planned by a run and lists the plans waiting for approval.",
+	)
+
+	pending := clientAction("pending", "Lists plans waiting for approval newest first", client.NewPlansPendingAction)
+	pending.StringFlag(config.ClientStack.String(), "stack", "Only list plans of this stack")
+	pending.IntFlag(config.ClientLimit.String(), "limit", "Max number of plans to list")
+
+	show := clientAction("show <run-id>", "Prints the plan of a run", client.NewPlansShowAction)
+	show.CompleteArgs(client.NewRunCompleter)
+
+	cmd.AddCommand(show, pending)
+	return cmd
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/git"
+	"deploy-runner/internal/local"
+	"deploy-runner/internal/locking"
+	"deploy-runner/internal/orchestrator"
+	"deploy-runner/internal/queue"
+	"deploy-runner/internal/stacks"
+	"deploy-runner/internal/store"
+	"deploy-runner/internal/terraform"
+)
+
+// Run returns the command that executes a single run in this process without a server
+func Run() app.Command {
+	cmd := app.ActionCommand(
+		"run <stack>",
+		"Executes a run of a stack locally",
+		"Clones, initializes, plans and applies a stack in this process the same way the server does, streaming the "+
+			"terraform output to the terminal. The run is recorded in the store at STORE_PATH which can not be open "+
+			"in a running server at the same time.",
+		local.NewRunAction,
+	)
+	cmd.StringFlag(config.ClientWorkspace.String(), "workspace", "Workspace to run, defaults to the first workspace of the stack")
+	cmd.StringFlag(config.ClientRef.String(), "ref", "Branch, tag or commit SHA to run, defaults to the default branch")
+	cmd.BoolFlag(config.ClientPlanOnly.String(), "plan-only", "Only plan the run")
+	cmd.BoolFlag(config.ClientApprove.String(), "approve", "Approve a plan that needs approval and apply it, the requester has to be an approver of the stack")
+	cmd.StringFlag(config.ClientRequester.String(), "requester", "Requester recorded on the run, defaults to the current user")
+	cmd.BindEnv(config.EnvClientRequester)
+	cmd.CompleteArgs(stacks.NewCompleter)
+	cmd.AddComponent(
+		git.Component,
+		terraform.Component,
+		store.Component,
+		locking.Component,
+		queue.Component,
+		orchestrator.Component,
+		stacks.Component,
+	)
+	return cmd
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/client"
+)
+
+// Runs returns the client commands that manage runs on a running deploy-runner server
+func Runs() app.Command {
+	cmd := clientCommand(
+		"runs",
+		"Manages runs on a deploy-runner server",
+		"Submits, lists, approves and cancels runs and prints their logs by calling the api of a deploy-runner server.",
+	)
+	submit := clientAction("submit <stack>", "Submits a run of a stack", client.NewSubmitAction)
+	submit.StringFlag(config.ClientWorkspace.String(), "workspace", "Workspace to run, defaults to the first workspace of the stack")
+	submit.StringFlag(config.ClientRef.String(), "ref", "Branch, tag or commit SHA to run, defaults to the default branch")
+	submit.BoolFlag(config.ClientPlanOnly.String(), "plan-only", "Only plan the run")
+
+	list := clientAction("list", "Lists runs newest first", client.NewListAction)
+	list.StringFlag(config.ClientStack.String(), "stack", "Only list runs of this stack")
+	list.EnumFlag(config.ClientStatus.String(), "status", "Only list runs in this status", runStatuses())
+	list.IntFlag(config.ClientLimit.String(), "limit", "Max number of runs to list")
+
+	submit.CompleteArgs(client.NewStackCompleter)
+
+	get := clientAction("get <run-id>", "Prints a run", client.NewGetAction)
+	get.CompleteArgs(client.NewRunCompleter)
+
+	logs := clientAction("logs <run-id>", "Prints the terraform output of a run", client.NewLogsAction)
+	logs.BoolFlag(config.ClientFollow.String(), "follow", "Keep printing new output until the run is done")
+	logs.CompleteArgs(client.NewRunCompleter)
+
+	approve := clientAction("approve <run-id>", "Approves the plan of a run awaiting approval", client.NewApproveAction)
+	approve.CompleteArgs(client.NewPendingRunCompleter)
+
+	cancel := clientAction("cancel <run-id>", "Cancels a run", client.NewCancelAction)
+	cancel.CompleteArgs(client.NewRunCompleter)
+
+	cmd.AddCommand(submit, list, get, logs, approve, cancel)
+	return cmd
+}
+
+func runStatuses() []string {
+	statuses := make([]string, 0, len(internal.RunStatuses))
+	for _, status := range internal.RunStatuses {
+		statuses = append(statuses, string(status))
+	}
+	return statuses
+}
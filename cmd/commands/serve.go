@@ -1 +1,48 @@
 package commands
+
+import (
+	"deploy-runner/internal/api"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/commitstatus"
+	"deploy-runner/internal/events"
+	"deploy-runner/internal/git"
+	"deploy-runner/internal/groups"
+	"deploy-runner/internal/kafka"
+	"deploy-runner/internal/locking"
+	"deploy-runner/internal/orchestrator"
+	"deploy-runner/internal/pipelines"
+	"deploy-runner/internal/queue"
+	"deploy-runner/internal/services"
+	"deploy-runner/internal/stacks"
+	"deploy-runner/internal/store"
+	"deploy-runner/internal/terraform"
+	"deploy-runner/internal/webhooks"
+)
+
+// Serve returns the command that runs the http api and the run workers until it receives a shutdown signal
+func Serve() app.Command {
+	cmd := app.ServiceCommand(
+		"serve",
+		"Runs the deploy-runner server",
+		"Runs the http api, the run workers and the kafka consumer until the process is interrupted.",
+		app.NewHelpWriter(),
+	)
+	cmd.AddComponent(
+		git.Component,
+		terraform.Component,
+		services.Component,
+		api.Component,
+		store.Component,
+		locking.Component,
+		queue.Component,
+		orchestrator.Component,
+		stacks.Component,
+		events.Component,
+		kafka.Component,
+		webhooks.Component,
+		commitstatus.Component,
+		groups.Component,
+		pipelines.Component,
+	)
+	return cmd
+}
@@ -0,0 +1,20 @@
+package commands
+
+import (
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/client"
+)
+
+// Stacks returns the client commands that show the stacks registered with a running deploy-runner server
+func Stacks() app.Command {
+	cmd := clientCommand(
+		"stacks",
+		"Shows the stacks of a deploy-runner server",
+		"Lists the stacks registered with a deploy-runner server and shows their configuration.",
+	)
+	get := clientAction("get <stack>", "Prints a stack", client.NewStacksGetAction)
+	get.CompleteArgs(client.NewStackCompleter)
+
+	cmd.AddCommand(clientAction("list", "Lists stacks", client.NewStacksListAction), get)
+	return cmd
+}
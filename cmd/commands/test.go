@@ -1 +1,36 @@
 package commands
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/checks"
+	"deploy-runner/internal/git"
+	"deploy-runner/internal/stacks"
+	"deploy-runner/internal/terraform"
+)
+
+// Test returns the command that checks a terraform module is formatted and valid so CI can gate merges on it
+func Test() app.Command {
+	cmd := app.ActionCommand(
+		"test [stack]",
+		"Checks a terraform module is formatted and valid",
+		"Clones the module of a stack, or of --repository and --path, and runs terraform fmt -check, init "+
+			"-backend=false, validate and optionally terraform test against it. The report is written as json or JUnit "+
+			"XML and the command exits non zero when a check failed.",
+		checks.NewTestAction,
+	)
+	cmd.StringFlag(config.TestRepository.String(), "repository", "Repository to clone when no stack is given")
+	cmd.StringFlag(config.TestPath.String(), "path", "Path of the module in the repository when no stack is given")
+	cmd.StringFlag(config.ClientRef.String(), "ref", "Branch, tag or commit SHA to check, defaults to the default branch")
+	cmd.BoolFlag(config.TestTerraformTest.String(), "terraform-test", "Also run terraform test, needs terraform 1.6 or newer")
+	cmd.EnumFlag(config.TestFormat.String(), "format", "Report format", []string{"json", "junit"}, app.Default("json"))
+	cmd.StringFlag(config.TestReportFile.String(), "report-file", "File the report is written to, defaults to stdout")
+	cmd.CompleteArgs(stacks.NewCompleter)
+	cmd.AddComponent(
+		git.Component,
+		terraform.Component,
+		stacks.Component,
+		checks.Component,
+	)
+	return cmd
+}
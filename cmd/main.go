@@ -1,5 +1,24 @@
 package main
 
-func main(){
+import (
+	"deploy-runner/cmd/commands"
+	"deploy-runner/config"
+	"deploy-runner/internal/app"
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+)
 
+func main() {
+	cfg := viper.New()
+	if err := config.LoadConfig(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	root := commands.Base()
+	root.AddCommand(commands.Serve(), commands.Runs(), commands.Stacks(), commands.Plans(), commands.Run(), commands.Test(), app.CompletionCommand(), app.DocsCommand())
+	if err := root.ToCobra(cfg).Execute(); err != nil {
+		os.Exit(1)
+	}
 }
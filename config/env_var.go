@@ -6,6 +6,12 @@ type EnvVar struct {
 	Description string `json:"desc"`
 }
 
+var EnvConfigPath = EnvVar{
+	Key:         ConfigPath,
+	Name:        "CONFIG_PATH",
+	Description: "Yaml, json or toml config file, its values are overridden by env vars and flags",
+}
+
 var EnvLogFormat = EnvVar{
 	Key:         LogFormat,
 	Name:        "LOG_FORMAT",
@@ -51,5 +57,185 @@ var EnvGrpcAddress = EnvVar{
 var EnvKafkaBootstrapServerAddress = EnvVar{
 	Key:         KafkaBootstrapServerAddress,
 	Name:        "KAFKA_BOOTSTRAP_SERVER_ADDRESS",
-	Description: "",
+	Description: "Comma separated list of kafka brokers, kafka is disabled when empty",
+}
+
+var EnvKafkaDeployRequestsTopic = EnvVar{
+	Key:         KafkaDeployRequestsTopic,
+	Name:        "KAFKA_DEPLOY_REQUESTS_TOPIC",
+	Description: "Topic deploy requests are consumed from",
+}
+
+var EnvKafkaConsumerGroup = EnvVar{
+	Key:         KafkaConsumerGroup,
+	Name:        "KAFKA_CONSUMER_GROUP",
+	Description: "Consumer group used to consume deploy requests",
+}
+
+var EnvKafkaDeadLetterTopic = EnvVar{
+	Key:         KafkaDeadLetterTopic,
+	Name:        "KAFKA_DEAD_LETTER_TOPIC",
+	Description: "Topic malformed deploy requests are sent to",
+}
+
+var EnvKafkaRunEventsTopic = EnvVar{
+	Key:         KafkaRunEventsTopic,
+	Name:        "KAFKA_RUN_EVENTS_TOPIC",
+	Description: "Topic run lifecycle events are published to",
+}
+
+var EnvHttpAddress = EnvVar{
+	Key:         HttpAddress,
+	Name:        "HTTP_ADDRESS",
+	Description: "The address port of the http api server",
+}
+
+var EnvWorkDir = EnvVar{
+	Key:         WorkDir,
+	Name:        "WORK_DIR",
+	Description: "Root directory stack workspaces are checked out into",
+}
+
+var EnvTerraformExecPath = EnvVar{
+	Key:         TerraformExecPath,
+	Name:        "TERRAFORM_EXEC_PATH",
+	Description: "Path to the terraform binary",
+}
+
+var EnvTerraformInstallDir = EnvVar{
+	Key:         TerraformInstallDir,
+	Name:        "TERRAFORM_INSTALL_DIR",
+	Description: "Directory with one <version>/terraform binary per terraform version stacks can pin",
+}
+
+var EnvTerraformLockTimeout = EnvVar{
+	Key:         TerraformLockTimeout,
+	Name:        "TERRAFORM_LOCK_TIMEOUT",
+	Description: "Duration terraform waits for a held state lock before failing",
+}
+
+var EnvWorkerCount = EnvVar{
+	Key:         WorkerCount,
+	Name:        "WORKER_COUNT",
+	Description: "Number of runs executed in parallel",
+}
+
+var EnvStorePath = EnvVar{
+	Key:         StorePath,
+	Name:        "STORE_PATH",
+	Description: "Path of the embedded run store database file",
+}
+
+var EnvRunLogDir = EnvVar{
+	Key:         RunLogDir,
+	Name:        "RUN_LOG_DIR",
+	Description: "Directory terraform output of each run is written to",
+}
+
+var EnvTerraformInterruptGracePeriod = EnvVar{
+	Key:         TerraformInterruptGracePeriod,
+	Name:        "TERRAFORM_INTERRUPT_GRACE_PERIOD",
+	Description: "Duration terraform has to exit after being interrupted before it is killed",
+}
+
+var EnvTerraformInitTimeout = EnvVar{
+	Key:         TerraformInitTimeout,
+	Name:        "TERRAFORM_INIT_TIMEOUT",
+	Description: "Max duration of the checkout and terraform init phase of a run",
+}
+
+var EnvTerraformPlanTimeout = EnvVar{
+	Key:         TerraformPlanTimeout,
+	Name:        "TERRAFORM_PLAN_TIMEOUT",
+	Description: "Max duration of the terraform plan phase of a run",
+}
+
+var EnvTerraformApplyTimeout = EnvVar{
+	Key:         TerraformApplyTimeout,
+	Name:        "TERRAFORM_APPLY_TIMEOUT",
+	Description: "Max duration of the terraform apply phase of a run",
+}
+
+var EnvRetryInitAttempts = EnvVar{
+	Key:         RetryInitAttempts,
+	Name:        "RETRY_INIT_ATTEMPTS",
+	Description: "Max tries of the init phase when it fails with a retryable error",
+}
+
+var EnvRetryPlanAttempts = EnvVar{
+	Key:         RetryPlanAttempts,
+	Name:        "RETRY_PLAN_ATTEMPTS",
+	Description: "Max tries of the plan phase when it fails with a retryable error",
+}
+
+var EnvRetryInitialBackoff = EnvVar{
+	Key:         RetryInitialBackoff,
+	Name:        "RETRY_INITIAL_BACKOFF",
+	Description: "Wait before the first retry, doubled for each following retry",
+}
+
+var EnvRetryMaxBackoff = EnvVar{
+	Key:         RetryMaxBackoff,
+	Name:        "RETRY_MAX_BACKOFF",
+	Description: "Max wait between retries",
+}
+
+var EnvWebhookMaxAttempts = EnvVar{
+	Key:         WebhookMaxAttempts,
+	Name:        "WEBHOOK_MAX_ATTEMPTS",
+	Description: "Max tries of a webhook delivery before it is marked as failed",
+}
+
+var EnvWebhookInitialBackoff = EnvVar{
+	Key:         WebhookInitialBackoff,
+	Name:        "WEBHOOK_INITIAL_BACKOFF",
+	Description: "Wait before the first retry of a webhook delivery, doubled for each following retry",
+}
+
+var EnvWebhookMaxBackoff = EnvVar{
+	Key:         WebhookMaxBackoff,
+	Name:        "WEBHOOK_MAX_BACKOFF",
+	Description: "Max wait between retries of a webhook delivery",
+}
+
+var EnvWebhookTimeout = EnvVar{
+	Key:         WebhookTimeout,
+	Name:        "WEBHOOK_TIMEOUT",
+	Description: "Max duration of a single webhook delivery attempt",
+}
+
+var EnvGithubWebhookSecret = EnvVar{
+	Key:         GithubWebhookSecret,
+	Name:        "GITHUB_WEBHOOK_SECRET",
+	Description: "Secret GitHub webhooks are signed with, GitHub webhooks are rejected when empty",
+}
+
+var EnvGitlabWebhookToken = EnvVar{
+	Key:         GitlabWebhookToken,
+	Name:        "GITLAB_WEBHOOK_TOKEN",
+	Description: "Secret token sent with GitLab webhooks, GitLab webhooks are rejected when empty",
+}
+
+var EnvServerURL = EnvVar{
+	Key:         ServerURL,
+	Name:        "SERVER_URL",
+	Description: "Url of the deploy-runner server the client commands call",
+}
+
+var EnvServerToken = EnvVar{
+	Key:         ServerToken,
+	Name:        "SERVER_TOKEN",
+	Description: "Bearer token sent with every request to the deploy-runner server",
+}
+
+var EnvClientRequester = EnvVar{
+	Key:         ClientRequester,
+	Name:        "CLIENT_REQUESTER",
+	Description: "Requester recorded on runs executed by the local run command, defaults to the current user",
+}
+
+var EnvOutputFormat = EnvVar{
+	Key:         OutputFormat,
+	Name:        "OUTPUT_FORMAT",
+	Description: "Output format of the client commands, table, json, yaml or go-template=<template>",
 }
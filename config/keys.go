@@ -7,14 +7,132 @@ func (k Key) String() string {
 }
 
 var AppEnv Key = "APP_ENV"
+
+// ConfigPath is a yaml, json or toml file merged over the embedded config of the APP_ENV environment
+var ConfigPath Key = "CONFIG_PATH"
 var LogFormat Key = "LOG_FORMAT"
 var LogLevel Key = "LOG_LEVEL"
 var DevMode Key = "DEV_MODE"
 var LogSampleEvery Key = "LOG_SAMPLE_EVERY"
 var LogSampleInitial Key = "LOG_SAMPLE_INITIAL"
 var LogSamplingRate Key = "LOG_SAMPLING_RATE"
+var GrpcAddress Key = "GRPC_ADDRESS"
+var KafkaBootstrapServerAddress Key = "KAFKA_BOOTSTRAP_SERVER_ADDRESS"
+
+// KafkaDeployRequestsTopic and KafkaConsumerGroup are where deploy requests are consumed from, messages that can not
+// be turned into a run are sent to KafkaDeadLetterTopic
+var KafkaDeployRequestsTopic Key = "KAFKA_DEPLOY_REQUESTS_TOPIC"
+var KafkaConsumerGroup Key = "KAFKA_CONSUMER_GROUP"
+var KafkaDeadLetterTopic Key = "KAFKA_DEAD_LETTER_TOPIC"
 
-// AllowedGitRepositories This key represents a struct of repository url -> key for pulling the repository
+// KafkaRunEventsTopic is where run lifecycle events are published as CloudEvents
+var KafkaRunEventsTopic Key = "KAFKA_RUN_EVENTS_TOPIC"
+
+// AllowedGitRepositories This key represents a list of repositories, each a url and the key for pulling the repository
 var AllowedGitRepositories Key = "ALLOWED_GIT_REPOSITORIES"
 
+// Stacks This key represents a map of stack name -> repository, module path, workspaces, terraform version, backend
+// config, variables, approvers, schedules and phase timeouts of the stack
+var Stacks Key = "STACKS"
+
+// Pipelines This key represents a map of pipeline name -> stack and the stages the stack's workspaces are applied in
+var Pipelines Key = "PIPELINES"
+
+var HttpAddress Key = "HTTP_ADDRESS"
+
+// WorkDir is the root directory stack workspaces are checked out into, one directory per stack/workspace
+var WorkDir Key = "WORK_DIR"
+var TerraformExecPath Key = "TERRAFORM_EXEC_PATH"
+
+// TerraformInstallDir holds one directory per terraform version with the terraform binary in it, stacks that pin a
+// terraform version use <dir>/<version>/terraform
+var TerraformInstallDir Key = "TERRAFORM_INSTALL_DIR"
+
+// TerraformLockTimeout is passed as -lock-timeout to terraform so it waits for a held state lock before failing
+var TerraformLockTimeout Key = "TERRAFORM_LOCK_TIMEOUT"
+
+// WorkerCount is the number of runs that can be executed in parallel
+var WorkerCount Key = "WORKER_COUNT"
+
+// StorePath is the file the embedded run store database is kept in
+var StorePath Key = "STORE_PATH"
+
+// RunLogDir is the directory the terraform output of each run is written to
+var RunLogDir Key = "RUN_LOG_DIR"
+
+// TerraformInterruptGracePeriod is how long terraform has to exit after being interrupted before it is killed
+var TerraformInterruptGracePeriod Key = "TERRAFORM_INTERRUPT_GRACE_PERIOD"
+
+// TerraformInitTimeout, TerraformPlanTimeout and TerraformApplyTimeout bound how long each phase of a run can take
+var TerraformInitTimeout Key = "TERRAFORM_INIT_TIMEOUT"
+var TerraformPlanTimeout Key = "TERRAFORM_PLAN_TIMEOUT"
+var TerraformApplyTimeout Key = "TERRAFORM_APPLY_TIMEOUT"
+
+// RetryInitAttempts and RetryPlanAttempts are the max number of tries of the init and plan phases when they fail with
+// a retryable error, apply is never retried
+var RetryInitAttempts Key = "RETRY_INIT_ATTEMPTS"
+var RetryPlanAttempts Key = "RETRY_PLAN_ATTEMPTS"
+var RetryInitialBackoff Key = "RETRY_INITIAL_BACKOFF"
+var RetryMaxBackoff Key = "RETRY_MAX_BACKOFF"
+
+// RetryableErrors This key represents a list of regular expressions matched against terraform errors to decide if
+// they are transient and can be retried
+var RetryableErrors Key = "RETRYABLE_ERRORS"
+
+// WebhookSubscriptions This key represents a list of webhook subscriptions, each with a name, url, secret and the event
+// types it receives
+var WebhookSubscriptions Key = "WEBHOOK_SUBSCRIPTIONS"
+
+// WebhookMaxAttempts, WebhookInitialBackoff and WebhookMaxBackoff control how failed webhook deliveries are retried
+var WebhookMaxAttempts Key = "WEBHOOK_MAX_ATTEMPTS"
+var WebhookInitialBackoff Key = "WEBHOOK_INITIAL_BACKOFF"
+var WebhookMaxBackoff Key = "WEBHOOK_MAX_BACKOFF"
+
+// WebhookTimeout bounds how long a single webhook delivery attempt can take
+var WebhookTimeout Key = "WEBHOOK_TIMEOUT"
+
+// GithubWebhookSecret and GitlabWebhookToken verify inbound push and pull request webhooks from each git host
+var GithubWebhookSecret Key = "GITHUB_WEBHOOK_SECRET"
+var GitlabWebhookToken Key = "GITLAB_WEBHOOK_TOKEN"
+
+// CommitStatusRepositories This key represents a list of repositories that run results are reported to, each with the
+// repository url, git host provider, api url and token
+var CommitStatusRepositories Key = "COMMIT_STATUS_REPOSITORIES"
+
+// ApiTokens This key represents a list of api callers, each with a name and the bearer token it authenticates with.
+// The name is recorded as the approver of runs and promotions made by the caller
+var ApiTokens Key = "API_TOKENS"
+
+// ServerURL is the deploy-runner server the client commands call, ServerToken is sent to it as a bearer token that
+// has to be one of the server's ApiTokens
+var ServerURL Key = "SERVER_URL"
+var ServerToken Key = "SERVER_TOKEN"
+
+// ClientRequester is recorded as the requester of runs executed by the local run command, the current user is used
+// when it is empty. Runs changed through a server are recorded under the caller of the server token
+var ClientRequester Key = "CLIENT_REQUESTER"
+
+// ClientWorkspace, ClientRef, ClientPlanOnly, ClientStack, ClientStatus, ClientLimit, ClientFollow and ClientApprove
+// hold the flags of the runs client commands and the local run command
+var ClientWorkspace Key = "CLIENT_WORKSPACE"
+var ClientRef Key = "CLIENT_REF"
+var ClientPlanOnly Key = "CLIENT_PLAN_ONLY"
+var ClientStack Key = "CLIENT_STACK"
+var ClientStatus Key = "CLIENT_STATUS"
+var ClientLimit Key = "CLIENT_LIMIT"
+var ClientFollow Key = "CLIENT_FOLLOW"
+var ClientApprove Key = "CLIENT_APPROVE"
+
+// TestRepository, TestPath, TestTerraformTest, TestFormat and TestReportFile hold the flags of the test command, the
+// repository and path are used when no stack is given
+var TestRepository Key = "TEST_REPOSITORY"
+var TestPath Key = "TEST_PATH"
+var TestTerraformTest Key = "TEST_TERRAFORM_TEST"
+var TestFormat Key = "TEST_FORMAT"
+var TestReportFile Key = "TEST_REPORT_FILE"
+
+// OutputFormat is how the client commands print their results, table, json, yaml or go-template=<template>
+var OutputFormat Key = "OUTPUT_FORMAT"
 
+// DocsFormat is the format the docs command generates, markdown or man
+var DocsFormat Key = "DOCS_FORMAT"
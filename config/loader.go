@@ -2,29 +2,131 @@ package config
 
 import (
 	"bytes"
-	_ "embed"
+	"embed"
+	"fmt"
+	"github.com/pelletier/go-toml"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-const (
-	fileType = "yaml"
-	appEnv = "APP_ENV"
-)
+const fileType = "yaml"
 
 //go:embed defaults/defaults.yaml
 var defaultYamlFile []byte
-//go:embed defaults/dev.yaml
-var devYamlFile []byte
 
-func LoadConfig(cfg *viper.Viper){
+// overlays holds an embedded <APP_ENV>.yaml per environment that is merged over the defaults
+//go:embed defaults/dev.yaml defaults/staging.yaml defaults/prod.yaml
+var overlays embed.FS
+
+// LoadConfig reads the embedded defaults and merges the overlay of the APP_ENV environment over them, dev is used when
+// APP_ENV is not set. The config file, env vars and flags are layered on top once the command line is parsed, see
+// LoadFile
+func LoadConfig(cfg *viper.Viper) error {
 	currEnvironment, ok := os.LookupEnv(AppEnv.String())
 	if !ok {
 		currEnvironment = "dev"
 	}
 	cfg.SetConfigType(fileType)
-	_ = cfg.ReadConfig(bytes.NewReader(defaultYamlFile))
-	if currEnvironment == "dev" {
-		_ = cfg.ReadConfig(bytes.NewReader(devYamlFile))
+	if err := cfg.ReadConfig(bytes.NewReader(defaultYamlFile)); err != nil {
+		return fmt.Errorf("unable to read default config: %w", err)
+	}
+
+	overlay, err := overlays.ReadFile("defaults/" + currEnvironment + ".yaml")
+	if err != nil {
+		return fmt.Errorf("unknown %s %s, expected dev, staging or prod", AppEnv, currEnvironment)
+	}
+	if err := cfg.MergeConfig(bytes.NewReader(overlay)); err != nil {
+		return fmt.Errorf("unable to read %s config: %w", currEnvironment, err)
+	}
+	return nil
+}
+
+// LoadFile merges the yaml, json or toml file at CONFIG_PATH over the embedded config, values from the file are
+// overridden by env vars and flags. Nothing is loaded when CONFIG_PATH is empty
+func LoadFile(cfg *viper.Viper) error {
+	path := cfg.GetString(ConfigPath.String())
+	if path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file %s: %w", path, err)
+	}
+	// The type of the embedded config is yaml so the type of the file is set from its extension
+	fileType := strings.TrimPrefix(filepath.Ext(path), ".")
+	if err := checkKeys(fileType, data); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	cfg.SetConfigType(fileType)
+	if err := cfg.MergeConfig(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("unable to read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// checkKeys rejects keys with upper case letters below the top level. Viper lowercases every key when the config is
+// loaded, a stack variable like AMI_ID would otherwise silently be passed to terraform as ami_id.
+func checkKeys(fileType string, data []byte) error {
+	var raw interface{}
+	switch fileType {
+	case "toml":
+		tree, err := toml.LoadBytes(data)
+		if err != nil {
+			return err
+		}
+		raw = tree.ToMap()
+	default:
+		// Json is read as yaml as well, the file is decoded by viper after the check
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range entries(raw) {
+		if err := checkNestedKeys(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkNestedKeys(path string, value interface{}) error {
+	if list, ok := value.([]interface{}); ok {
+		for i, item := range list {
+			if err := checkNestedKeys(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for key, nested := range entries(value) {
+		if key != strings.ToLower(key) {
+			return fmt.Errorf("key %s.%s must be lower case, keys are lowercased when the config is loaded", path, key)
+		}
+		if err := checkNestedKeys(path+"."+key, nested); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entries returns the entries of a decoded map, yaml decodes maps with interface keys
+func entries(value interface{}) map[string]interface{} {
+	switch m := value.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			converted[fmt.Sprint(k)] = v
+		}
+		return converted
 	}
+	return nil
 }
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	t.Run("TestOverlays", testOverlays)
+	t.Run("TestDefaultEnvironment", testDefaultEnvironment)
+	t.Run("TestUnknownEnvironment", testUnknownEnvironment)
+	t.Run("TestUpperCaseKeys", testUpperCaseKeys)
+}
+
+func testOverlays(t *testing.T) {
+	tests := []struct {
+		env      string
+		logLevel string
+		workDir  string
+	}{
+		{"dev", "info", "/tmp/deploy-runner/work"},
+		{"staging", "debug", "/var/lib/deploy-runner/work"},
+		{"prod", "info", "/var/lib/deploy-runner/work"},
+	}
+	for _, tt := range tests {
+		t.Setenv(AppEnv.String(), tt.env)
+		cfg := viper.New()
+		assert.NoError(t, LoadConfig(cfg), tt.env)
+
+		// Keys set by the overlay replace the defaults, the others keep their default
+		assert.Equal(t, tt.logLevel, cfg.GetString(LogLevel.String()), tt.env)
+		assert.Equal(t, tt.workDir, cfg.GetString(WorkDir.String()), tt.env)
+		assert.Equal(t, "8080", cfg.GetString(HttpAddress.String()), tt.env)
+	}
+}
+
+func testDefaultEnvironment(t *testing.T) {
+	t.Setenv(AppEnv.String(), "")
+	assert.NoError(t, os.Unsetenv(AppEnv.String()))
+
+	cfg := viper.New()
+	assert.NoError(t, LoadConfig(cfg))
+	assert.Equal(t, "/tmp/deploy-runner/work", cfg.GetString(WorkDir.String()))
+}
+
+func testUnknownEnvironment(t *testing.T) {
+	t.Setenv(AppEnv.String(), "qa")
+	assert.EqualError(t, LoadConfig(viper.New()), "unknown APP_ENV qa, expected dev, staging or prod")
+}
+
+// loadFile writes a config file with the extension of fileType and loads it over the embedded config
+func loadFile(t *testing.T, fileType, content string) (*viper.Viper, error) {
+	path := filepath.Join(t.TempDir(), "config."+fileType)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+	cfg := viper.New()
+	assert.NoError(t, LoadConfig(cfg))
+	cfg.Set(ConfigPath.String(), path)
+	return cfg, LoadFile(cfg)
+}
+
+func testUpperCaseKeys(t *testing.T) {
+	cfg, err := loadFile(t, "yaml", "STACKS:\n  network:\n    repository: https://example.com/infra.git\n    variables:\n      region: eu-west-1\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", cfg.GetString("STACKS.network.variables.region"))
+
+	_, err = loadFile(t, "yaml", "STACKS:\n  network:\n    variables:\n      AMI_ID: ami-123\n")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "key STACKS.network.variables.AMI_ID must be lower case")
+	}
+
+	_, err = loadFile(t, "json", `{"STACKS": {"network": {"backend_config": {"Bucket": "state"}}}}`)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "key STACKS.network.backend_config.Bucket must be lower case")
+	}
+
+	_, err = loadFile(t, "toml", "[STACKS.network.inputs.VPC_ID]\nstack = \"vpc\"\n")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "key STACKS.network.inputs.VPC_ID must be lower case")
+	}
+}
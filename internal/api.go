@@ -1 +1,9 @@
 package internal
+
+import "github.com/go-chi/chi/v5"
+
+// ApiRoutes is implemented by each group of http handlers so they can be mounted on the api router. Implementations
+// are provided to fx in the "routes" group.
+type ApiRoutes interface {
+	Mount(r chi.Router)
+}
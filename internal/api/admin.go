@@ -0,0 +1,71 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type forceUnlockRequest struct {
+	LockID string `json:"lock_id"`
+	Reason string `json:"reason"`
+}
+
+type adminRoutes struct {
+	orchestrator internal.Orchestrator
+	audit        internal.BackgroundLog
+}
+
+func NewAdminRoutes(orchestrator internal.Orchestrator, log internal.BackgroundLog) routesOut {
+	return routesOut{Routes: &adminRoutes{
+		orchestrator: orchestrator,
+		audit:        log.ChildLog("audit"),
+	}}
+}
+
+func (a *adminRoutes) Mount(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/stacks/{stack}/workspaces/{workspace}/force-unlock", a.forceUnlock)
+	})
+}
+
+func (a *adminRoutes) forceUnlock(w http.ResponseWriter, r *http.Request) {
+	stack := chi.URLParam(r, "stack")
+	workspace := chi.URLParam(r, "workspace")
+
+	requester, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+	var req forceUnlockRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if req.LockID == "" || req.Reason == "" {
+		writeError(w, http.StatusBadRequest, "lock_id and reason are required")
+		return
+	}
+
+	auditFields := []interface{}{"action", "force-unlock", "stack", stack, "workspace", workspace, "lock_id", req.LockID,
+		"requester", requester, "reason", req.Reason, "remote_addr", r.RemoteAddr}
+	a.audit.InfowCtx(r.Context(), "Force unlock requested", auditFields...)
+
+	err := a.orchestrator.ForceUnlock(r.Context(), stack, workspace, req.LockID)
+	if err != nil {
+		a.audit.ErrwCtx(r.Context(), err, "Force unlock failed", auditFields...)
+		switch {
+		case errors.Is(err, internal.ErrStackBusy):
+			writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, internal.ErrWorkspaceNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	a.audit.InfowCtx(r.Context(), "Force unlock succeeded", auditFields...)
+	w.WriteHeader(http.StatusNoContent)
+}
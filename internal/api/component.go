@@ -0,0 +1,14 @@
+package api
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"go.uber.org/fx"
+)
+
+type routesOut struct {
+	fx.Out
+	Routes internal.ApiRoutes `group:"routes"`
+}
+
+var Component = internal.NewComponent("api", []config.EnvVar{config.EnvGithubWebhookSecret, config.EnvGitlabWebhookToken}, NewAdminRoutes, NewRunRoutes, NewWebhookRoutes, NewGitHookRoutes, NewStackRoutes, NewGroupRoutes, NewPipelineRoutes)
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxHookBodySize = 10 << 20
+
+// errHookUnauthorized is returned by a git host parser when a webhook's signature or token does not verify
+var errHookUnauthorized = errors.New("webhook signature does not match")
+
+// gitTrigger is a push or pull request from a git host that should trigger runs, it is nil when a webhook is ignored
+type gitTrigger struct {
+	// repositories are the urls the git host gave for the repository
+	repositories []string
+	sha          string
+	planOnly     bool
+	requester    string
+	pullRequest  int
+
+	// changedFiles are the paths changed by a push, nil when the git host did not send a complete list
+	changedFiles []string
+}
+
+type gitHookResponse struct {
+	Runs    []*internal.Run  `json:"runs,omitempty"`
+	Failed  []gitHookFailure `json:"failed,omitempty"`
+	Ignored string           `json:"ignored,omitempty"`
+}
+
+// gitHookFailure is a run a webhook should have triggered that could not be submitted
+type gitHookFailure struct {
+	Stack     string `json:"stack"`
+	Workspace string `json:"workspace"`
+	Error     string `json:"error"`
+}
+
+type gitHookRoutes struct {
+	log          internal.BackgroundLog
+	orchestrator internal.Orchestrator
+	stacks       internal.StackRegistry
+	runs         internal.RunStore
+	deliveries   internal.GitHookStore
+	githubSecret string
+	gitlabToken  string
+
+	// mu handles one triggering webhook at a time so a delivery the git host sends again before the first one was
+	// answered does not submit its runs twice
+	mu sync.Mutex
+}
+
+func NewGitHookRoutes(cfg *viper.Viper, log internal.BackgroundLog, orchestrator internal.Orchestrator, stacks internal.StackRegistry, runs internal.RunStore, deliveries internal.GitHookStore) routesOut {
+	return routesOut{Routes: &gitHookRoutes{
+		log:          log.ChildLog("git-hooks"),
+		orchestrator: orchestrator,
+		stacks:       stacks,
+		runs:         runs,
+		deliveries:   deliveries,
+		githubSecret: cfg.GetString(config.GithubWebhookSecret.String()),
+		gitlabToken:  cfg.GetString(config.GitlabWebhookToken.String()),
+	}}
+}
+
+func (a *gitHookRoutes) Mount(r chi.Router) {
+	r.Route("/hooks", func(r chi.Router) {
+		r.Post("/github", a.hook(a.githubSecret, "X-GitHub-Delivery", parseGithubHook))
+		r.Post("/gitlab", a.hook(a.gitlabToken, "X-Gitlab-Event-UUID", parseGitlabHook))
+	})
+}
+
+// hook handles a webhook from a git host, parse verifies the webhook and turns it into a trigger. deliveryHeader is
+// the header holding the id the git host keeps when it redelivers a webhook.
+func (a *gitHookRoutes) hook(secret, deliveryHeader string, parse func(r *http.Request, body []byte, secret string) (*gitTrigger, string, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" {
+			writeError(w, http.StatusForbidden, "webhooks from this git host are not configured")
+			return
+		}
+
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxHookBodySize))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "unable to read body: "+err.Error())
+			return
+		}
+
+		trigger, ignored, err := parse(r, body, secret)
+		if errors.Is(err, errHookUnauthorized) {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid webhook: "+err.Error())
+			return
+		}
+		if trigger == nil {
+			writeJSON(w, http.StatusOK, gitHookResponse{Ignored: ignored})
+			return
+		}
+
+		a.trigger(w, r, r.Header.Get(deliveryHeader), trigger)
+	}
+}
+
+func (a *gitHookRoutes) trigger(w http.ResponseWriter, r *http.Request, delivery string, trigger *gitTrigger) {
+	allowed := false
+	stacks := make(map[string]internal.Stack)
+	for _, repo := range trigger.repositories {
+		allowed = allowed || a.stacks.RepositoryAllowed(repo)
+		for _, s := range a.stacks.ForRepository(repo) {
+			stacks[s.Name] = s
+		}
+	}
+	if !allowed {
+		writeError(w, http.StatusForbidden, "repository is not allowed")
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if delivery != "" {
+		runs, err := a.submitted(r, delivery)
+		if err != nil {
+			a.log.Errw(err, "Unable to look up git webhook delivery", "delivery", delivery)
+			writeError(w, http.StatusInternalServerError, "unable to look up delivery")
+			return
+		}
+		if runs != nil {
+			a.log.Infow("Git webhook was delivered again, its runs are not submitted twice", "delivery", delivery, "runs", len(runs))
+			writeJSON(w, http.StatusOK, gitHookResponse{Runs: runs})
+			return
+		}
+	}
+
+	// A failed submit does not stop the other runs from being submitted, the webhook is only answered with an error when
+	// nothing was submitted so a git host redelivering it does not queue the submitted runs twice
+	resp := gitHookResponse{Runs: make([]*internal.Run, 0)}
+	for _, s := range a.stacks.List() {
+		if _, ok := stacks[s.Name]; !ok || !affects(trigger.changedFiles, s.Path) {
+			continue
+		}
+
+		for _, workspace := range s.Workspaces {
+			run, err := a.orchestrator.Submit(r.Context(), internal.DeployRequest{
+				Stack:       s.Name,
+				Workspace:   workspace,
+				Ref:         trigger.sha,
+				Requester:   trigger.requester,
+				PlanOnly:    trigger.planOnly,
+				PullRequest: trigger.pullRequest,
+			})
+			if err != nil {
+				a.log.Errw(err, "Unable to submit run from git webhook", "stack", s.Name, "workspace", workspace, "sha", trigger.sha)
+				resp.Failed = append(resp.Failed, gitHookFailure{Stack: s.Name, Workspace: workspace, Error: err.Error()})
+				continue
+			}
+			resp.Runs = append(resp.Runs, run)
+		}
+	}
+
+	switch {
+	case len(resp.Runs) == 0 && len(resp.Failed) == 0:
+		writeJSON(w, http.StatusOK, gitHookResponse{Ignored: "no stacks are affected"})
+		return
+	case len(resp.Runs) == 0:
+		writeJSON(w, http.StatusInternalServerError, resp)
+		return
+	}
+	if delivery != "" {
+		a.record(r, delivery, resp.Runs)
+	}
+	a.log.Infow("Git webhook triggered runs", "delivery", delivery, "sha", trigger.sha, "plan_only", trigger.planOnly, "requester", trigger.requester, "runs", len(resp.Runs), "failed", len(resp.Failed))
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+// submitted returns the runs an earlier delivery with the same id submitted, or nil when the delivery is new
+func (a *gitHookRoutes) submitted(r *http.Request, delivery string) ([]*internal.Run, error) {
+	recorded, err := a.deliveries.GetHookDelivery(r.Context(), delivery)
+	if errors.Is(err, internal.ErrHookDeliveryNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	runs := make([]*internal.Run, 0, len(recorded.RunIDs))
+	for _, id := range recorded.RunIDs {
+		run, err := a.runs.Get(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// record remembers the runs a delivery submitted, a failure is only logged since the runs were submitted
+func (a *gitHookRoutes) record(r *http.Request, delivery string, runs []*internal.Run) {
+	recorded := &internal.GitHookDelivery{ID: delivery, ReceivedAt: time.Now().UTC(), RunIDs: make([]string, 0, len(runs))}
+	for _, run := range runs {
+		recorded.RunIDs = append(recorded.RunIDs, run.ID)
+	}
+	if err := a.deliveries.SaveHookDelivery(r.Context(), recorded); err != nil {
+		a.log.Errw(err, "Unable to record git webhook delivery, a redelivery would submit its runs again", "delivery", delivery)
+	}
+}
+
+// affects reports if any changed file is inside a stack's module path, every stack is affected when the changed
+// files are unknown
+func affects(changedFiles []string, stackPath string) bool {
+	dir := path.Clean(stackPath)
+	if changedFiles == nil || dir == "." {
+		return true
+	}
+	for _, f := range changedFiles {
+		if strings.HasPrefix(path.Clean(f), dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"deploy-runner/internal/stacks"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testGithubSecret = "github-secret"
+	testGitlabToken  = "gitlab-token"
+)
+
+func TestGitHooks(t *testing.T) {
+	t.Run("TestGithubPush", testGithubPush)
+	t.Run("TestGithubPullRequest", testGithubPullRequest)
+	t.Run("TestGithubBadSignature", testGithubBadSignature)
+	t.Run("TestGitlabMergeRequest", testGitlabMergeRequest)
+	t.Run("TestPartialSubmit", testPartialSubmit)
+	t.Run("TestRedelivery", testRedelivery)
+}
+
+type submitRecorder struct {
+	internal.Orchestrator
+	requests []internal.DeployRequest
+	runs     []*internal.Run
+
+	// failWorkspace is a workspace runs can not be submitted for
+	failWorkspace string
+}
+
+func (o *submitRecorder) Submit(ctx context.Context, req internal.DeployRequest) (*internal.Run, error) {
+	if req.Workspace == o.failWorkspace {
+		return nil, errors.New("queue is full")
+	}
+	o.requests = append(o.requests, req)
+	run := internal.NewRun(req, internal.Stack{})
+	o.runs = append(o.runs, run)
+	return run, nil
+}
+
+// submittedRuns is a RunStore holding the runs a submitRecorder submitted
+type submittedRuns struct {
+	internal.RunStore
+	o *submitRecorder
+}
+
+func (s *submittedRuns) Get(ctx context.Context, id string) (*internal.Run, error) {
+	for _, run := range s.o.runs {
+		if run.ID == id {
+			return run, nil
+		}
+	}
+	return nil, internal.ErrRunNotFound
+}
+
+type memHookStore struct {
+	deliveries map[string]internal.GitHookDelivery
+}
+
+func (s *memHookStore) SaveHookDelivery(ctx context.Context, delivery *internal.GitHookDelivery) error {
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (s *memHookStore) GetHookDelivery(ctx context.Context, id string) (*internal.GitHookDelivery, error) {
+	delivery, ok := s.deliveries[id]
+	if !ok {
+		return nil, internal.ErrHookDeliveryNotFound
+	}
+	return &delivery, nil
+}
+
+func newGitHookRouter(t *testing.T) (*chi.Mux, *submitRecorder) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.GithubWebhookSecret.String(), testGithubSecret)
+	cfg.Set(config.GitlabWebhookToken.String(), testGitlabToken)
+	cfg.Set(config.AllowedGitRepositories.String(), []map[string]interface{}{{"url": "https://github.com/example/infra.git"}, {"url": "https://gitlab.com/example/infra.git"}})
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "git@github.com:example/infra.git", "path": "network", "workspaces": []string{"dev", "prod"}},
+		"cluster": map[string]interface{}{"repository": "https://github.com/example/infra.git", "path": "cluster"},
+		"dns":     map[string]interface{}{"repository": "https://gitlab.com/example/infra.git", "path": "dns"},
+	})
+
+	registry, err := stacks.NewRegistry(cfg)
+	assert.NoError(t, err)
+	o := &submitRecorder{}
+	rt := chi.NewRouter()
+	deliveries := &memHookStore{deliveries: make(map[string]internal.GitHookDelivery)}
+	NewGitHookRoutes(cfg, logging.NewBackgroundLog(cfg), o, registry, &submittedRuns{o: o}, deliveries).Routes.Mount(rt)
+	return rt, o
+}
+
+func githubRequest(event string, payload interface{}, secret string) *http.Request {
+	body, _ := json.Marshal(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	r := httptest.NewRequest(http.MethodPost, "/hooks/github", bytes.NewReader(body))
+	r.Header.Set("X-GitHub-Event", event)
+	r.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	return r
+}
+
+var githubRepo = map[string]interface{}{
+	"full_name":      "example/infra",
+	"clone_url":      "https://github.com/example/infra.git",
+	"ssh_url":        "git@github.com:example/infra.git",
+	"default_branch": "main",
+}
+
+func testGithubPush(t *testing.T) {
+	rt, o := newGitHookRouter(t)
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, githubRequest("push", map[string]interface{}{
+		"ref":        "refs/heads/main",
+		"after":      "0123456789abcdef0123456789abcdef01234567",
+		"repository": githubRepo,
+		"sender":     map[string]interface{}{"login": "alice"},
+		"commits":    []map[string]interface{}{{"modified": []string{"network/main.tf", "README.md"}}},
+	}, testGithubSecret))
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, []internal.DeployRequest{
+		{Stack: "network", Workspace: "dev", Ref: "0123456789abcdef0123456789abcdef01234567", Requester: "github:alice"},
+		{Stack: "network", Workspace: "prod", Ref: "0123456789abcdef0123456789abcdef01234567", Requester: "github:alice"},
+	}, o.requests)
+
+	// Pushes to other branches are left to pull requests
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, githubRequest("push", map[string]interface{}{"ref": "refs/heads/feature", "repository": githubRepo}, testGithubSecret))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, o.requests, 2)
+}
+
+func testGithubPullRequest(t *testing.T) {
+	rt, o := newGitHookRouter(t)
+
+	pr := func(headRepo map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"action":       "synchronize",
+			"number":       7,
+			"pull_request": map[string]interface{}{"head": map[string]interface{}{"sha": "abc123", "repo": headRepo}},
+			"repository":   githubRepo,
+			"sender":       map[string]interface{}{"login": "bob"},
+		}
+	}
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, githubRequest("pull_request", pr(githubRepo), testGithubSecret))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	// Changed files are not known for pull requests so every stack in the repository is planned
+	assert.Len(t, o.requests, 3)
+	for _, req := range o.requests {
+		assert.True(t, req.PlanOnly)
+		assert.Equal(t, "abc123", req.Ref)
+		assert.Equal(t, "github:bob", req.Requester)
+		assert.Equal(t, 7, req.PullRequest)
+	}
+
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, githubRequest("pull_request", pr(map[string]interface{}{"full_name": "mallory/infra"}), testGithubSecret))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "fork")
+	assert.Len(t, o.requests, 3)
+}
+
+func testGithubBadSignature(t *testing.T) {
+	rt, o := newGitHookRouter(t)
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, githubRequest("push", map[string]interface{}{"ref": "refs/heads/main", "repository": githubRepo}, "wrong"))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Empty(t, o.requests)
+}
+
+func testGitlabMergeRequest(t *testing.T) {
+	rt, o := newGitHookRouter(t)
+
+	mr := func(sourceProject int) *http.Request {
+		body, _ := json.Marshal(map[string]interface{}{
+			"user":    map[string]interface{}{"username": "carol"},
+			"project": map[string]interface{}{"id": 1, "git_http_url": "https://gitlab.com/example/infra.git", "default_branch": "main"},
+			"object_attributes": map[string]interface{}{
+				"iid":               3,
+				"action":            "open",
+				"source_project_id": sourceProject,
+				"target_project_id": 1,
+				"last_commit":       map[string]interface{}{"id": "def456"},
+			},
+		})
+		r := httptest.NewRequest(http.MethodPost, "/hooks/gitlab", bytes.NewReader(body))
+		r.Header.Set("X-Gitlab-Event", "Merge Request Hook")
+		r.Header.Set("X-Gitlab-Token", testGitlabToken)
+		return r
+	}
+
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, mr(1))
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, []internal.DeployRequest{{Stack: "dns", Workspace: "default", Ref: "def456", Requester: "gitlab:carol", PlanOnly: true, PullRequest: 3}}, o.requests)
+
+	w = httptest.NewRecorder()
+	rt.ServeHTTP(w, mr(2))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, o.requests, 1)
+}
+
+func testPartialSubmit(t *testing.T) {
+	push := func(rt http.Handler, changed string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, githubRequest("push", map[string]interface{}{
+			"ref":        "refs/heads/main",
+			"after":      "0123456789abcdef0123456789abcdef01234567",
+			"repository": githubRepo,
+			"commits":    []map[string]interface{}{{"modified": []string{changed}}},
+		}, testGithubSecret))
+		return w
+	}
+
+	// The dev run is queued so the webhook is accepted, the prod run that could not be submitted is listed
+	rt, o := newGitHookRouter(t)
+	o.failWorkspace = "prod"
+	w := push(rt, "network/main.tf")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var resp gitHookResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Runs, 1)
+	assert.Equal(t, []gitHookFailure{{Stack: "network", Workspace: "prod", Error: "queue is full"}}, resp.Failed)
+
+	// The webhook fails when nothing was submitted so the git host delivers it again
+	o.failWorkspace = "default"
+	w = push(rt, "cluster/main.tf")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Len(t, o.requests, 1)
+}
+
+func testRedelivery(t *testing.T) {
+	push := func(rt http.Handler, delivery string) *httptest.ResponseRecorder {
+		r := githubRequest("push", map[string]interface{}{
+			"ref":        "refs/heads/main",
+			"after":      "0123456789abcdef0123456789abcdef01234567",
+			"repository": githubRepo,
+			"commits":    []map[string]interface{}{{"modified": []string{"network/main.tf"}}},
+		}, testGithubSecret)
+		r.Header.Set("X-GitHub-Delivery", delivery)
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, r)
+		return w
+	}
+
+	rt, o := newGitHookRouter(t)
+	w := push(rt, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var first gitHookResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+
+	// The git host delivering the webhook again gets the runs it already submitted
+	w = push(rt, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var again gitHookResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.Len(t, o.requests, 2)
+	if assert.Len(t, again.Runs, 2) {
+		assert.Equal(t, first.Runs[0].ID, again.Runs[0].ID)
+		assert.Equal(t, first.Runs[1].ID, again.Runs[1].ID)
+	}
+
+	// Another delivery of the same push is a new webhook
+	w = push(rt, "9a8b7c6d-cc78-11e3-81ab-4c9367dc0958")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Len(t, o.requests, 4)
+}
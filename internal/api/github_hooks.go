@@ -0,0 +1,131 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// GitHub only sends the first 20 commits of a push so the changed files of larger pushes are unknown
+const githubMaxPushCommits = 20
+
+type githubRepository struct {
+	FullName      string `json:"full_name"`
+	CloneURL      string `json:"clone_url"`
+	SSHURL        string `json:"ssh_url"`
+	HTMLURL       string `json:"html_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func (r githubRepository) urls() []string {
+	return []string{r.CloneURL, r.SSHURL, r.HTMLURL}
+}
+
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+type githubCommit struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+type githubPush struct {
+	Ref        string           `json:"ref"`
+	After      string           `json:"after"`
+	Deleted    bool             `json:"deleted"`
+	Repository githubRepository `json:"repository"`
+	Sender     githubUser       `json:"sender"`
+	Commits    []githubCommit   `json:"commits"`
+}
+
+type githubPullRequest struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Head struct {
+			SHA  string            `json:"sha"`
+			Repo *githubRepository `json:"repo"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository githubRepository `json:"repository"`
+	Sender     githubUser       `json:"sender"`
+}
+
+// parseGithubHook verifies a GitHub webhook's X-Hub-Signature-256 header and turns push and pull_request events
+// into triggers
+func parseGithubHook(r *http.Request, body []byte, secret string) (*gitTrigger, string, error) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Hub-Signature-256"))) {
+		return nil, "", errHookUnauthorized
+	}
+
+	switch event := r.Header.Get("X-GitHub-Event"); event {
+	case "push":
+		var push githubPush
+		if err := json.Unmarshal(body, &push); err != nil {
+			return nil, "", err
+		}
+		return githubPushTrigger(push)
+	case "pull_request":
+		var pr githubPullRequest
+		if err := json.Unmarshal(body, &pr); err != nil {
+			return nil, "", err
+		}
+		return githubPullRequestTrigger(pr)
+	default:
+		return nil, "unsupported event " + event, nil
+	}
+}
+
+func githubPushTrigger(push githubPush) (*gitTrigger, string, error) {
+	switch {
+	case push.Deleted:
+		return nil, "ref was deleted", nil
+	case push.Ref != "refs/heads/"+push.Repository.DefaultBranch:
+		return nil, "push is not to the default branch", nil
+	}
+
+	trigger := &gitTrigger{
+		repositories: push.Repository.urls(),
+		sha:          push.After,
+		requester:    "github:" + push.Sender.Login,
+	}
+	if len(push.Commits) < githubMaxPushCommits {
+		trigger.changedFiles = make([]string, 0)
+		for _, c := range push.Commits {
+			trigger.changedFiles = append(trigger.changedFiles, c.Added...)
+			trigger.changedFiles = append(trigger.changedFiles, c.Removed...)
+			trigger.changedFiles = append(trigger.changedFiles, c.Modified...)
+		}
+	}
+	return trigger, "", nil
+}
+
+func githubPullRequestTrigger(pr githubPullRequest) (*gitTrigger, string, error) {
+	switch pr.Action {
+	case "opened", "synchronize", "reopened":
+	default:
+		return nil, "pull request action " + pr.Action + " does not change code", nil
+	}
+
+	// Planning runs the pull request's code with the runner's credentials so pull requests from forks are never run
+	head := pr.PullRequest.Head.Repo
+	if head == nil || !strings.EqualFold(head.FullName, pr.Repository.FullName) {
+		return nil, "pull request is from a fork", nil
+	}
+
+	return &gitTrigger{
+		repositories: pr.Repository.urls(),
+		sha:          pr.PullRequest.Head.SHA,
+		planOnly:     true,
+		requester:    "github:" + pr.Sender.Login,
+		pullRequest:  pr.Number,
+	}, "", nil
+}
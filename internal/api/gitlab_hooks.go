@@ -0,0 +1,123 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+)
+
+type gitlabProject struct {
+	ID            int    `json:"id"`
+	GitHTTPURL    string `json:"git_http_url"`
+	GitSSHURL     string `json:"git_ssh_url"`
+	WebURL        string `json:"web_url"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+func (p gitlabProject) urls() []string {
+	return []string{p.GitHTTPURL, p.GitSSHURL, p.WebURL}
+}
+
+type gitlabCommit struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+type gitlabPush struct {
+	Ref               string         `json:"ref"`
+	CheckoutSHA       string         `json:"checkout_sha"`
+	UserUsername      string         `json:"user_username"`
+	Project           gitlabProject  `json:"project"`
+	Commits           []gitlabCommit `json:"commits"`
+	TotalCommitsCount int            `json:"total_commits_count"`
+}
+
+type gitlabMergeRequest struct {
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+	Project          gitlabProject `json:"project"`
+	ObjectAttributes struct {
+		IID             int    `json:"iid"`
+		Action          string `json:"action"`
+		OldRev          string `json:"oldrev"`
+		SourceProjectID int    `json:"source_project_id"`
+		TargetProjectID int    `json:"target_project_id"`
+		LastCommit      struct {
+			ID string `json:"id"`
+		} `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+// parseGitlabHook verifies a GitLab webhook's X-Gitlab-Token header and turns push and merge request events into
+// triggers
+func parseGitlabHook(r *http.Request, body []byte, token string) (*gitTrigger, string, error) {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(r.Header.Get("X-Gitlab-Token"))) != 1 {
+		return nil, "", errHookUnauthorized
+	}
+
+	switch event := r.Header.Get("X-Gitlab-Event"); event {
+	case "Push Hook":
+		var push gitlabPush
+		if err := json.Unmarshal(body, &push); err != nil {
+			return nil, "", err
+		}
+		return gitlabPushTrigger(push)
+	case "Merge Request Hook":
+		var mr gitlabMergeRequest
+		if err := json.Unmarshal(body, &mr); err != nil {
+			return nil, "", err
+		}
+		return gitlabMergeRequestTrigger(mr)
+	default:
+		return nil, "unsupported event " + event, nil
+	}
+}
+
+func gitlabPushTrigger(push gitlabPush) (*gitTrigger, string, error) {
+	switch {
+	case push.CheckoutSHA == "":
+		return nil, "ref was deleted", nil
+	case push.Ref != "refs/heads/"+push.Project.DefaultBranch:
+		return nil, "push is not to the default branch", nil
+	}
+
+	trigger := &gitTrigger{
+		repositories: push.Project.urls(),
+		sha:          push.CheckoutSHA,
+		requester:    "gitlab:" + push.UserUsername,
+	}
+	// GitLab only sends the first 20 commits of a push, the changed files are unknown when some are missing
+	if push.TotalCommitsCount <= len(push.Commits) {
+		trigger.changedFiles = make([]string, 0)
+		for _, c := range push.Commits {
+			trigger.changedFiles = append(trigger.changedFiles, c.Added...)
+			trigger.changedFiles = append(trigger.changedFiles, c.Removed...)
+			trigger.changedFiles = append(trigger.changedFiles, c.Modified...)
+		}
+	}
+	return trigger, "", nil
+}
+
+func gitlabMergeRequestTrigger(mr gitlabMergeRequest) (*gitTrigger, string, error) {
+	attrs := mr.ObjectAttributes
+	switch {
+	case attrs.Action != "open" && attrs.Action != "reopen" && attrs.Action != "update":
+		return nil, "merge request action " + attrs.Action + " does not change code", nil
+	case attrs.Action == "update" && attrs.OldRev == "":
+		// Updates without oldrev only changed the description, labels etc
+		return nil, "merge request update has no new commits", nil
+	case attrs.SourceProjectID != attrs.TargetProjectID:
+		// Planning runs the merge request's code with the runner's credentials so forks are never run
+		return nil, "merge request is from a fork", nil
+	}
+
+	return &gitTrigger{
+		repositories: mr.Project.urls(),
+		sha:          attrs.LastCommit.ID,
+		planOnly:     true,
+		requester:    "gitlab:" + mr.User.Username,
+		pullRequest:  attrs.IID,
+	}, "", nil
+}
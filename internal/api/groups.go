@@ -0,0 +1,55 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type groupRoutes struct {
+	coordinator internal.GroupCoordinator
+	store       internal.GroupStore
+}
+
+func NewGroupRoutes(coordinator internal.GroupCoordinator, store internal.GroupStore) routesOut {
+	return routesOut{Routes: &groupRoutes{coordinator: coordinator, store: store}}
+}
+
+func (a *groupRoutes) Mount(r chi.Router) {
+	r.Post("/groups", a.submit)
+	r.Get("/groups/{groupID}", a.get)
+}
+
+func (a *groupRoutes) submit(w http.ResponseWriter, r *http.Request) {
+	requester, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+	var req internal.DeployGroupRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	req.Requester = requester
+
+	group, err := a.coordinator.SubmitGroup(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidRequest) {
+			writeError(w, http.StatusBadRequest, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	writeJSON(w, http.StatusAccepted, group)
+}
+
+func (a *groupRoutes) get(w http.ResponseWriter, r *http.Request) {
+	group, err := a.store.GetGroup(r.Context(), chi.URLParam(r, "groupID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"strconv"
+)
+
+type pipelineRoutes struct {
+	runner internal.PipelineRunner
+	store  internal.PipelineStore
+}
+
+func NewPipelineRoutes(runner internal.PipelineRunner, store internal.PipelineStore) routesOut {
+	return routesOut{Routes: &pipelineRoutes{runner: runner, store: store}}
+}
+
+func (a *pipelineRoutes) Mount(r chi.Router) {
+	r.Get("/pipelines", a.list)
+	r.Post("/pipelines/{pipeline}/runs", a.start)
+	r.Get("/pipelines/{pipeline}/runs", a.listRuns)
+	r.Get("/pipeline-runs/{runID}", a.getRun)
+	r.Post("/pipeline-runs/{runID}/promote", a.promote)
+}
+
+func (a *pipelineRoutes) list(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.runner.Pipelines())
+}
+
+func (a *pipelineRoutes) start(w http.ResponseWriter, r *http.Request) {
+	requester, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+	var req internal.PipelineRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	req.Requester = requester
+
+	run, err := a.runner.StartPipeline(r.Context(), chi.URLParam(r, "pipeline"), req)
+	switch {
+	case errors.Is(err, internal.ErrPipelineNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeStoreError(w, err)
+	default:
+		writeJSON(w, http.StatusAccepted, run)
+	}
+}
+
+func (a *pipelineRoutes) listRuns(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := internal.PipelineRunFilter{
+		Pipeline: chi.URLParam(r, "pipeline"),
+		Status:   internal.PipelineStatus(q.Get("status")),
+		Cursor:   q.Get("cursor"),
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid limit: "+err.Error())
+			return
+		}
+		filter.Limit = limit
+	}
+
+	page, err := a.store.ListPipelineRuns(r.Context(), filter)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (a *pipelineRoutes) getRun(w http.ResponseWriter, r *http.Request) {
+	run, err := a.store.GetPipelineRun(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (a *pipelineRoutes) promote(w http.ResponseWriter, r *http.Request) {
+	approver, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := a.runner.Promote(r.Context(), chi.URLParam(r, "runID"), approver)
+	switch {
+	case errors.Is(err, internal.ErrNotAwaitingPromotion):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, internal.ErrNotApprover):
+		writeError(w, http.StatusForbidden, err.Error())
+	case err != nil:
+		writeStoreError(w, err)
+	default:
+		writeJSON(w, http.StatusAccepted, run)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func decodeJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// callerOf returns the authenticated caller of the request, requests, approvals and cancellations are recorded under the
+// caller's name so they can not be made on behalf of someone else. It responds with unauthorized when the caller is
+// unknown.
+func callerOf(w http.ResponseWriter, r *http.Request) (string, bool) {
+	caller := internal.Caller(r.Context())
+	if caller == "" {
+		writeError(w, http.StatusUnauthorized, "the caller could not be identified")
+		return "", false
+	}
+	return caller, true
+}
+
+// writeStoreError maps errors returned from stores to a response status
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrRunNotFound), errors.Is(err, internal.ErrDeliveryNotFound),
+		errors.Is(err, internal.ErrGroupNotFound), errors.Is(err, internal.ErrPipelineRunNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, internal.ErrInvalidRequest):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+type submitRunResponse struct {
+	Run   *internal.Run        `json:"run"`
+	Queue *internal.QueueEntry `json:"queue,omitempty"`
+}
+
+type rejectRunRequest struct {
+	Reason string `json:"reason"`
+}
+
+type runRoutes struct {
+	orchestrator internal.Orchestrator
+	queue        internal.RunQueue
+	store        internal.RunStore
+}
+
+func NewRunRoutes(orchestrator internal.Orchestrator, queue internal.RunQueue, store internal.RunStore) routesOut {
+	return routesOut{Routes: &runRoutes{
+		orchestrator: orchestrator,
+		queue:        queue,
+		store:        store,
+	}}
+}
+
+func (a *runRoutes) Mount(r chi.Router) {
+	r.Post("/runs", a.submit)
+	r.Get("/runs", a.list)
+	r.Get("/runs/{runID}", a.get)
+	r.Get("/runs/{runID}/transitions", a.transitions)
+	r.Get("/runs/{runID}/logs", a.logs)
+	r.Post("/runs/{runID}/cancel", a.cancel)
+	r.Post("/runs/{runID}/approve", a.approve)
+	r.Post("/runs/{runID}/reject", a.reject)
+	r.Get("/queue", a.queueStats)
+	r.Get("/queue/{runID}", a.queueEntry)
+}
+
+func (a *runRoutes) submit(w http.ResponseWriter, r *http.Request) {
+	requester, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+	var req internal.DeployRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	req.Requester = requester
+
+	run, err := a.orchestrator.Submit(r.Context(), req)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidRequest) {
+			writeError(w, http.StatusBadRequest, err.Error())
+		} else {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	resp := submitRunResponse{Run: run}
+	if entry, ok := a.queue.Entry(run.ID); ok {
+		resp.Queue = &entry
+	}
+	writeJSON(w, http.StatusAccepted, resp)
+}
+
+func (a *runRoutes) queueStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.queue.Stats())
+}
+
+func (a *runRoutes) queueEntry(w http.ResponseWriter, r *http.Request) {
+	entry, ok := a.queue.Entry(chi.URLParam(r, "runID"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "run is not queued")
+		return
+	}
+	writeJSON(w, http.StatusOK, entry)
+}
+
+func (a *runRoutes) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseRunFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := a.store.List(r.Context(), filter)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (a *runRoutes) get(w http.ResponseWriter, r *http.Request) {
+	run, err := a.store.Get(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (a *runRoutes) transitions(w http.ResponseWriter, r *http.Request) {
+	transitions, err := a.store.Transitions(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, transitions)
+}
+
+func (a *runRoutes) logs(w http.ResponseWriter, r *http.Request) {
+	run, err := a.store.Get(r.Context(), chi.URLParam(r, "runID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	f, err := os.Open(run.LogPath)
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "run has no logs yet")
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
+
+func (a *runRoutes) cancel(w http.ResponseWriter, r *http.Request) {
+	requester, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := a.orchestrator.CancelRun(r.Context(), chi.URLParam(r, "runID"), requester)
+	if errors.Is(err, internal.ErrRunNotCancellable) {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (a *runRoutes) approve(w http.ResponseWriter, r *http.Request) {
+	approver, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+
+	run, err := a.orchestrator.Approve(r.Context(), chi.URLParam(r, "runID"), approver)
+	if err != nil {
+		writeApprovalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run)
+}
+
+func (a *runRoutes) reject(w http.ResponseWriter, r *http.Request) {
+	approver, ok := callerOf(w, r)
+	if !ok {
+		return
+	}
+	// The reason is optional so an empty body is accepted
+	var req rejectRunRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	run, err := a.orchestrator.Reject(r.Context(), chi.URLParam(r, "runID"), approver, req.Reason)
+	if err != nil {
+		writeApprovalError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func writeApprovalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, internal.ErrRunNotAwaitingApproval):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, internal.ErrNotApprover):
+		writeError(w, http.StatusForbidden, err.Error())
+	default:
+		writeStoreError(w, err)
+	}
+}
+
+func parseRunFilter(q url.Values) (internal.RunFilter, error) {
+	filter := internal.RunFilter{
+		Stack:     q.Get("stack"),
+		Status:    internal.RunStatus(q.Get("status")),
+		Requester: q.Get("requester"),
+		Cursor:    q.Get("cursor"),
+	}
+
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("invalid limit: %v", err)
+		}
+	}
+	if v := q.Get("since"); v != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid since: %v", err)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return filter, fmt.Errorf("invalid until: %v", err)
+		}
+	}
+	return filter, nil
+}
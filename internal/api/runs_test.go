@@ -0,0 +1,65 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRuns(t *testing.T) {
+	t.Run("TestRequesterIsCaller", testRequesterIsCaller)
+}
+
+// emptyQueue has no waiting runs
+type emptyQueue struct {
+	internal.RunQueue
+}
+
+func (emptyQueue) Entry(runID string) (internal.QueueEntry, bool) {
+	return internal.QueueEntry{}, false
+}
+
+// cancelRecorder records who runs are cancelled by
+type cancelRecorder struct {
+	submitRecorder
+	cancelledBy []string
+}
+
+func (o *cancelRecorder) CancelRun(ctx context.Context, id, requester string) (*internal.Run, error) {
+	o.cancelledBy = append(o.cancelledBy, requester)
+	return &internal.Run{ID: id, CancelledBy: requester}, nil
+}
+
+func testRequesterIsCaller(t *testing.T) {
+	o := &cancelRecorder{submitRecorder: submitRecorder{failWorkspace: "none"}}
+	rt := chi.NewRouter()
+	NewRunRoutes(o, emptyQueue{}, nil).Routes.Mount(rt)
+	send := func(path, body string, ctx context.Context) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rt.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body)).WithContext(ctx))
+		return w
+	}
+	alice := internal.WithCaller(context.Background(), "alice")
+
+	// A requester in the body is replaced by the caller
+	w := send("/runs", `{"stack":"network","requester":"mallory"}`, alice)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	var resp submitRunResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "alice", resp.Run.Requester)
+	assert.Equal(t, "alice", o.requests[0].Requester)
+
+	w = send("/runs/abc/cancel", "", alice)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, []string{"alice"}, o.cancelledBy)
+
+	w = send("/runs/abc/cancel", "", context.Background())
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Len(t, o.cancelledBy, 1)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+)
+
+type stackRoutes struct {
+	stacks  internal.StackRegistry
+	outputs internal.OutputStore
+}
+
+func NewStackRoutes(stacks internal.StackRegistry, outputs internal.OutputStore) routesOut {
+	return routesOut{Routes: &stackRoutes{stacks: stacks, outputs: outputs}}
+}
+
+func (a *stackRoutes) Mount(r chi.Router) {
+	r.Get("/stacks", a.list)
+	r.Get("/stacks/{stack}", a.get)
+	r.Get("/stacks/{stack}/workspaces/{workspace}/outputs", a.getOutputs)
+}
+
+// list returns every stack, the values of backend config and variables are redacted
+func (a *stackRoutes) list(w http.ResponseWriter, r *http.Request) {
+	stacks := a.stacks.List()
+	redacted := make([]internal.Stack, 0, len(stacks))
+	for _, s := range stacks {
+		redacted = append(redacted, s.Redacted())
+	}
+	writeJSON(w, http.StatusOK, redacted)
+}
+
+// get returns a stack with the values of its backend config and variables redacted
+func (a *stackRoutes) get(w http.ResponseWriter, r *http.Request) {
+	stack, ok := a.stacks.Get(chi.URLParam(r, "stack"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "stack not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, stack.Redacted())
+}
+
+// getOutputs returns the latest captured outputs of a stack workspace, values of sensitive outputs are left out
+func (a *stackRoutes) getOutputs(w http.ResponseWriter, r *http.Request) {
+	outputs, err := a.outputs.LatestOutputs(r.Context(), chi.URLParam(r, "stack"), chi.URLParam(r, "workspace"))
+	if errors.Is(err, internal.ErrOutputsNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, outputs.Redacted())
+}
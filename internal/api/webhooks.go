@@ -0,0 +1,80 @@
+package api
+
+import (
+	"deploy-runner/internal"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type webhookRoutes struct {
+	store      internal.DeliveryStore
+	dispatcher internal.WebhookDispatcher
+}
+
+func NewWebhookRoutes(store internal.DeliveryStore, dispatcher internal.WebhookDispatcher) routesOut {
+	return routesOut{Routes: &webhookRoutes{
+		store:      store,
+		dispatcher: dispatcher,
+	}}
+}
+
+func (a *webhookRoutes) Mount(r chi.Router) {
+	r.Route("/webhooks/deliveries", func(r chi.Router) {
+		r.Get("/", a.list)
+		r.Get("/{deliveryID}", a.get)
+		r.Post("/{deliveryID}/redeliver", a.redeliver)
+	})
+}
+
+func (a *webhookRoutes) list(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDeliveryFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := a.store.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (a *webhookRoutes) get(w http.ResponseWriter, r *http.Request) {
+	delivery, err := a.store.GetDelivery(r.Context(), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, delivery)
+}
+
+func (a *webhookRoutes) redeliver(w http.ResponseWriter, r *http.Request) {
+	delivery, err := a.dispatcher.Redeliver(r.Context(), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, delivery)
+}
+
+func parseDeliveryFilter(q url.Values) (internal.DeliveryFilter, error) {
+	filter := internal.DeliveryFilter{
+		Subscription: q.Get("subscription"),
+		RunID:        q.Get("run_id"),
+		Status:       internal.DeliveryStatus(q.Get("status")),
+		Cursor:       q.Get("cursor"),
+	}
+
+	if v := q.Get("limit"); v != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("invalid limit: %v", err)
+		}
+	}
+	return filter, nil
+}
@@ -5,6 +5,7 @@ import (
 	"deploy-runner/internal"
 	"fmt"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"sort"
@@ -28,22 +29,35 @@ type Command interface {
 	SetParent(parent Command)
 
 	// Adds an integer flag to the command
-	IntFlag(viperKey, flag, usage string)
+	IntFlag(viperKey, flag, usage string, options ...FlagOption)
 
 	// Adds a string flag to the command
-	StringFlag(viperKey, flag, usage string)
+	StringFlag(viperKey, flag, usage string, options ...FlagOption)
 
 	// Adds a bool flag to the command
-	BoolFlag(viperKey, flag, usage string)
+	BoolFlag(viperKey, flag, usage string, options ...FlagOption)
 
 	// Adds a duration flag to the command
-	DurationFlag(viperKey, flag, usage string)
+	DurationFlag(viperKey, flag, usage string, options ...FlagOption)
+
+	// Adds a string slice flag to the command, it can be repeated or take comma separated values
+	StringSliceFlag(viperKey, flag, usage string, options ...FlagOption)
+
+	// Adds a key=value flag to the command, it can be repeated or take comma separated pairs
+	StringToStringFlag(viperKey, flag, usage string, options ...FlagOption)
+
+	// Adds a string flag to the command that only accepts one of values
+	EnumFlag(viperKey, flag, usage string, values []string, options ...FlagOption)
 
 	// Validates the dependencies for the command
 	Validate(cfg *viper.Viper) error
 
-	// Binds single environment variable to command
+	// Binds single environment variable to the command and its sub commands
 	BindEnv(envVar config.EnvVar)
+
+	// Completes the positional arguments of the command in the shell with the ArgCompleter provided by completerFunc,
+	// which can be any fx compatible constructor/provide function that needs the command's dependencies
+	CompleteArgs(completerFunc interface{})
 }
 
 
@@ -52,12 +66,17 @@ type command struct {
 	cobra         *cobra.Command
 	components    []internal.Component
 	flagBindings  map[string]string
+	flagChecks    map[string]flagCheck
 	childCommands []Command
 	parent        *command
 	builder       Builder
 	help          HelpWriter
 	provide       fx.Option
 	extraEnv      []config.EnvVar
+	completer     interface{}
+
+	// run replaces building and running an fx app for commands that only need cobra and the config, like completion
+	run func(cfg *viper.Viper, cmd *cobra.Command, args []string) error
 }
 
 
@@ -75,6 +94,7 @@ func newCommand(use, short, long string, container bool, builder Builder, help H
 			Long:  long,
 		},
 		flagBindings:  make(map[string]string),
+		flagChecks:    make(map[string]flagCheck),
 		childCommands: make([]Command, 0),
 		components:    make([]internal.Component, 0),
 		builder:       builder,
@@ -94,8 +114,9 @@ func (c *command) ToCobra(cfg *viper.Viper) *cobra.Command {
 		}
 	} else {
 		flagBindings := c.mergeFlagBindings()
+		flagChecks := c.mergeFlagChecks()
 
-		c.cobra.PreRunE = func(cmd *cobra.Command, args []string) error {
+		bind := func(cmd *cobra.Command) error {
 			for f, v := range flagBindings {
 				flag := cmd.Flags().Lookup(f)
 				if flag == nil {
@@ -112,6 +133,23 @@ func (c *command) ToCobra(cfg *viper.Viper) *cobra.Command {
 				}
 			}
 
+			// The config file can only be loaded once the flags and env vars are bound since they hold its path
+			return config.LoadFile(cfg)
+		}
+		c.cobra.PreRunE = func(cmd *cobra.Command, args []string) error {
+			// Cobra has parsed the flags and checked the arguments by now, usage is only printed for bad flag values
+			// rather than for every error of the command
+			cmd.SilenceUsage = true
+			if err := bind(cmd); err != nil {
+				return err
+			}
+
+			for f, check := range flagChecks {
+				if err := check.check(cfg, cmd.Flags().Lookup(f), flagBindings[f]); err != nil {
+					cmd.SilenceUsage = false
+					return err
+				}
+			}
 			return nil
 		}
 
@@ -120,11 +158,31 @@ func (c *command) ToCobra(cfg *viper.Viper) *cobra.Command {
 			options = append(options, c.provide)
 		}
 
-		c.cobra.RunE = func(cmd *cobra.Command, args []string) error {
-			if runner, err := c.builder.BuildRunner(cfg, options...); err == nil {
-				return runner.Run(args)
-			} else {
-				return err
+		if c.run != nil {
+			c.cobra.RunE = func(cmd *cobra.Command, args []string) error {
+				return c.run(cfg, cmd, args)
+			}
+		} else {
+			c.cobra.RunE = func(cmd *cobra.Command, args []string) error {
+				if runner, err := c.builder.BuildRunner(cfg, options...); err == nil {
+					return runner.Run(args)
+				} else {
+					return err
+				}
+			}
+		}
+
+		if c.completer != nil {
+			c.cobra.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				// Flags and env vars are bound here too since PreRunE does not run when completing
+				if err := bind(cmd); err != nil {
+					return nil, cobra.ShellCompDirectiveError
+				}
+				completer, err := buildCompleter(cfg, c.completer, options...)
+				if err != nil {
+					return nil, cobra.ShellCompDirectiveError
+				}
+				return completer.Complete(args, toComplete)
 			}
 		}
 	}
@@ -153,48 +211,66 @@ func (c *command) SetParent(parent Command) {
 	c.parent = parent.(*command)
 }
 
-func (c *command) IntFlag(viperKey, flag, usage string) {
-	if c.container {
-		c.cobra.PersistentFlags().Int(flag, 0, usage)
-	} else {
-		c.cobra.Flags().Int(flag, 0, usage)
-	}
+func (c *command) IntFlag(viperKey, flag, usage string, options ...FlagOption) {
+	o := newFlagOptions(0, options)
+	c.flags().IntP(flag, o.shorthand, o.defaultValue.(int), c.bindFlag(viperKey, flag, usage, o, nil))
+}
 
-	c.flagBindings[flag] = viperKey
+func (c *command) StringFlag(viperKey, flag, usage string, options ...FlagOption) {
+	o := newFlagOptions("", options)
+	c.flags().StringP(flag, o.shorthand, o.defaultValue.(string), c.bindFlag(viperKey, flag, usage, o, nil))
 }
 
-func (c *command) StringFlag(viperKey, flag, usage string) {
-	if c.container {
-		c.cobra.PersistentFlags().String(flag, "", usage)
-	} else {
-		c.cobra.Flags().String(flag, "", usage)
-	}
+func (c *command) BoolFlag(viperKey, flag, usage string, options ...FlagOption) {
+	o := newFlagOptions(false, options)
+	c.flags().BoolP(flag, o.shorthand, o.defaultValue.(bool), c.bindFlag(viperKey, flag, usage, o, nil))
+}
 
-	c.flagBindings[flag] = viperKey
+func (c *command) DurationFlag(viperKey, flag, usage string, options ...FlagOption) {
+	o := newFlagOptions(time.Second, options)
+	c.flags().DurationP(flag, o.shorthand, o.defaultValue.(time.Duration), c.bindFlag(viperKey, flag, usage, o, nil))
 }
 
-func (c *command) BoolFlag(viperKey, flag, usage string) {
-	if c.container {
-		c.cobra.PersistentFlags().Bool(flag, false, usage)
-	} else {
-		c.cobra.Flags().Bool(flag, false, usage)
-	}
+func (c *command) StringSliceFlag(viperKey, flag, usage string, options ...FlagOption) {
+	o := newFlagOptions([]string{}, options)
+	c.flags().StringSliceP(flag, o.shorthand, o.defaultValue.([]string), c.bindFlag(viperKey, flag, usage, o, nil))
+}
 
-	c.flagBindings[flag] = viperKey
+func (c *command) StringToStringFlag(viperKey, flag, usage string, options ...FlagOption) {
+	o := newFlagOptions(map[string]string{}, options)
+	c.flags().StringToStringP(flag, o.shorthand, o.defaultValue.(map[string]string), c.bindFlag(viperKey, flag, usage, o, nil))
 }
 
-func (c *command) DurationFlag(viperKey, flag, usage string) {
+func (c *command) EnumFlag(viperKey, flag, usage string, values []string, options ...FlagOption) {
+	o := newFlagOptions("", options)
+	c.flags().StringP(flag, o.shorthand, o.defaultValue.(string), c.bindFlag(viperKey, flag, usage, o, values))
+	_ = c.cobra.RegisterFlagCompletionFunc(flag, completeValues(values))
+}
+
+// flags returns the flag set flags are added to, flags of container commands are inherited by their sub commands
+func (c *command) flags() *pflag.FlagSet {
 	if c.container {
-		c.cobra.PersistentFlags().Duration(flag, time.Second, usage)
-	} else {
-		c.cobra.Flags().Duration(flag, time.Second, usage)
+		return c.cobra.PersistentFlags()
 	}
+	return c.cobra.Flags()
+}
 
+// bindFlag binds the flag to the viper key, records what is checked of its value and returns the usage to show in help
+func (c *command) bindFlag(viperKey, flag, usage string, o *flagOptions, values []string) string {
 	c.flagBindings[flag] = viperKey
 
+	check := flagCheck{required: o.required, values: values}
+	if check.required || len(check.values) > 0 {
+		c.flagChecks[flag] = check
+	}
+	return flagUsage(usage, check)
 }
 
 func (c *command) Validate(cfg *viper.Viper) error {
+	if c.builder == nil {
+		// Container commands and commands with their own run func have no dependencies
+		return nil
+	}
 	components := c.mergeComponents()
 
 	// Set some basic values to ensure logging is okay
@@ -219,6 +295,18 @@ func (c *command) mergeFlagBindings() map[string]string {
 	return merged
 }
 
+func (c *command) mergeFlagChecks() map[string]flagCheck {
+	merged := make(map[string]flagCheck)
+	current := c
+	for current != nil {
+		for k, v := range current.flagChecks {
+			merged[k] = v
+		}
+		current = current.parent
+	}
+	return merged
+}
+
 func (c *command) mergeComponents() []internal.Component {
 	// Dedupe first
 	dedupe := make(map[string]internal.Component)
@@ -239,6 +327,10 @@ func (c *command) mergeComponents() []internal.Component {
 	return merged
 }
 
+func (c *command) CompleteArgs(completerFunc interface{}) {
+	c.completer = completerFunc
+}
+
 func (c *command) BindEnv(envVar config.EnvVar) {
 	c.extraEnv = append(c.extraEnv, envVar)
 }
@@ -252,8 +344,12 @@ func (c *command) mergeEnvVars(components []internal.Component) []config.EnvVar
 		}
 	}
 
-	for _, v := range c.extraEnv {
-		vars[v.Name] = v
+	current := c
+	for current != nil {
+		for _, v := range current.extraEnv {
+			vars[v.Name] = v
+		}
+		current = current.parent
 	}
 
 	// Next get them in a sorted state
@@ -0,0 +1,109 @@
+package app
+
+import (
+	"bytes"
+	"deploy-runner/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	t.Run("TestFlagBindings", testFlagBindings)
+	t.Run("TestFlagChecks", testFlagChecks)
+	t.Run("TestFlagHelp", testFlagHelp)
+	t.Run("TestConfigPrecedence", testConfigPrecedence)
+}
+
+// newFlagCommand returns a command with one of each flag that records the config it was run with
+func newFlagCommand(ran *bool) Command {
+	cmd := newCommand("flags", "", "", false, nil, NewHelpWriter(), nil).(*command)
+	cmd.run = func(cfg *viper.Viper, cmd *cobra.Command, args []string) error {
+		*ran = true
+		return nil
+	}
+	cmd.StringSliceFlag("SLICE", "slice", "Slice flag", Default([]string{"a"}))
+	cmd.StringToStringFlag("VARS", "var", "Vars flag", Shorthand("v"))
+	cmd.EnumFlag("FORMAT", "format", "Format flag", []string{"json", "junit"}, Default("json"))
+	cmd.IntFlag("LIMIT", "limit", "Limit flag", Default(20))
+	cmd.StringFlag("NAME", "name", "Name flag", Required())
+	cmd.StringFlag(config.ConfigPath.String(), "config", "Config flag")
+	cmd.BindEnv(config.EnvConfigPath)
+	cmd.BindEnv(config.EnvVar{Key: "LIMIT", Name: "DR_TEST_LIMIT"})
+	return cmd
+}
+
+func execute(cfg *viper.Viper, args ...string) (bool, error) {
+	var ran bool
+	c := newFlagCommand(&ran).ToCobra(cfg)
+	c.SetArgs(args)
+	c.SetOut(&bytes.Buffer{})
+	c.SetErr(&bytes.Buffer{})
+	err := c.Execute()
+	return ran, err
+}
+
+func testFlagBindings(t *testing.T) {
+	cfg := viper.New()
+	ran, err := execute(cfg, "--name", "x", "-v", "region=eu-west-1", "--var", "size=small")
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	assert.Equal(t, []string{"a"}, cfg.GetStringSlice("SLICE"))
+	assert.Equal(t, map[string]string{"region": "eu-west-1", "size": "small"}, cfg.GetStringMapString("VARS"))
+	assert.Equal(t, "json", cfg.GetString("FORMAT"))
+	assert.Equal(t, 20, cfg.GetInt("LIMIT"))
+
+	cfg = viper.New()
+	_, err = execute(cfg, "--name", "x", "--slice", "b,c", "--format", "junit")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, cfg.GetStringSlice("SLICE"))
+	assert.Equal(t, "junit", cfg.GetString("FORMAT"))
+}
+
+func testFlagChecks(t *testing.T) {
+	ran, err := execute(viper.New())
+	assert.EqualError(t, err, "required flag --name is not set")
+	assert.False(t, ran)
+
+	// Required flags can be set through the config and enum values from the config are checked too
+	cfg := viper.New()
+	cfg.Set("NAME", "x")
+	cfg.Set("FORMAT", "xml")
+	ran, err = execute(cfg)
+	assert.EqualError(t, err, "invalid value xml for --format, expected one of json, junit")
+	assert.False(t, ran)
+}
+
+func testFlagHelp(t *testing.T) {
+	var ran bool
+	c := newFlagCommand(&ran).ToCobra(viper.New())
+	usage := c.UsageString()
+	assert.Contains(t, usage, `Format flag, one of json, junit (default "json")`)
+	assert.Contains(t, usage, "Name flag (required)")
+	assert.Contains(t, usage, "-v, --var stringToString")
+}
+
+func testConfigPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("NAME: file\nLIMIT: 5\nFORMAT: junit\n"), 0o600))
+	t.Setenv("CONFIG_PATH", file)
+	t.Setenv("DR_TEST_LIMIT", "7")
+
+	// defaults < file < env vars < flags
+	cfg := viper.New()
+	cfg.SetConfigType("yaml")
+	assert.NoError(t, cfg.ReadConfig(strings.NewReader("NAME: default\nSLICE: [default]\n")))
+	_, err := execute(cfg, "--format", "json")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"default"}, cfg.GetStringSlice("SLICE"))
+	assert.Equal(t, "file", cfg.GetString("NAME"))
+	assert.Equal(t, 7, cfg.GetInt("LIMIT"))
+	assert.Equal(t, "json", cfg.GetString("FORMAT"))
+
+	_, err = execute(viper.New(), "--config", filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
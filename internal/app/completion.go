@@ -0,0 +1,72 @@
+package app
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal/logging"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+// ArgCompleter completes the positional arguments of a command, args are the arguments already given and toComplete is
+// the partial argument being completed. Completions can carry a description after a tab.
+type ArgCompleter interface {
+	Complete(args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+}
+
+const completionLong = `Generates the shell completion script of the CLI.
+
+Bash:
+  source <(deploy-runner completion bash)
+
+Zsh, if completion is not enabled yet run "autoload -U compinit; compinit" first:
+  deploy-runner completion zsh > "${fpath[1]}/_deploy-runner"
+
+Fish:
+  deploy-runner completion fish > ~/.config/fish/completions/deploy-runner.fish
+
+PowerShell:
+  deploy-runner completion powershell | Out-String | Invoke-Expression
+`
+
+// CompletionCommand creates a new CLI sub command that writes the completion script of the root command for bash, zsh,
+// fish or powershell
+func CompletionCommand() Command {
+	cmd := newCommand("completion [bash|zsh|fish|powershell]", "Generates shell completion scripts", completionLong, false, nil, NewHelpWriter(), []config.EnvVar{}).(*command)
+	cmd.cobra.ValidArgs = []string{"bash", "zsh", "fish", "powershell"}
+	cmd.cobra.Args = cobra.ExactValidArgs(1)
+	cmd.cobra.DisableFlagsInUseLine = true
+	cmd.run = writeCompletion
+	return cmd
+}
+
+func writeCompletion(cfg *viper.Viper, cmd *cobra.Command, args []string) error {
+	root, out := cmd.Root(), cmd.OutOrStdout()
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(out, true)
+	case "zsh":
+		return root.GenZshCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(out)
+	default:
+		return fmt.Errorf("unsupported shell %s", args[0])
+	}
+}
+
+// buildCompleter builds the ArgCompleter of a command with the command's dependencies, config validators are not run
+// and nothing is started since only the completer's dependencies are built
+func buildCompleter(cfg *viper.Viper, completerFunc interface{}, options ...fx.Option) (ArgCompleter, error) {
+	log, err := logging.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize logging %w", err)
+	}
+
+	var completer ArgCompleter
+	app := fx.New(fx.Options(options...), fx.Provide(completerFunc), fx.Provide(logging.NewStartUp), fx.Supply(cfg, log),
+		fx.Populate(&completer), fx.Logger(&nullPrinter{}))
+	return completer, app.Err()
+}
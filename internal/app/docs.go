@@ -0,0 +1,223 @@
+package app
+
+import (
+	"deploy-runner/config"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	docsFormatMarkdown = "markdown"
+	docsFormatMan      = "man"
+)
+
+const docsLong = `Generates a Markdown or man page per command of the CLI into the directory passed as the only argument, each page
+describes the command, its flags and the environment variables it reads. Pages are written without dates so generating
+them again only changes pages of commands that changed.`
+
+// DocsCommand creates a new hidden CLI sub command that walks every command from the root and generates Markdown or
+// man pages describing the commands, their flags and the environment variables of their components
+func DocsCommand() Command {
+	cmd := newCommand("docs <dir>", "Generates Markdown or man pages of the CLI", docsLong, false, nil, NewHelpWriter(), []config.EnvVar{}).(*command)
+	cmd.cobra.Hidden = true
+	cmd.cobra.Args = cobra.ExactArgs(1)
+	cmd.EnumFlag(config.DocsFormat.String(), "format", "Format of the generated pages", []string{docsFormatMarkdown, docsFormatMan}, Default(docsFormatMarkdown))
+	cmd.run = func(cfg *viper.Viper, _ *cobra.Command, args []string) error {
+		return writeDocs(cmd.root(), cfg.GetString(config.DocsFormat.String()), args[0])
+	}
+	return cmd
+}
+
+// docPage is the data a page is generated from
+type docPage struct {
+	Command  *cobra.Command
+	EnvVars  []config.EnvVar
+	Commands []*cobra.Command
+}
+
+var markdownTemplate = `## {{.Command.CommandPath}}
+
+{{.Command.Short}}
+{{with .Command.Long}}
+### Synopsis
+
+{{.}}
+{{end}}{{if .Command.Runnable}}
+` + "```" + `
+{{.Command.UseLine}}
+` + "```" + `
+{{end}}{{if .Command.HasAvailableLocalFlags}}
+### Options
+
+` + "```" + `
+{{.Command.LocalFlags.FlagUsages}}` + "```" + `
+{{end}}{{if .Command.HasAvailableInheritedFlags}}
+### Options inherited from parent commands
+
+` + "```" + `
+{{.Command.InheritedFlags.FlagUsages}}` + "```" + `
+{{end}}{{if .EnvVars}}
+### Environment variables
+
+| Name | Description |
+|------|-------------|
+{{range .EnvVars}}| ` + "`{{.Name}}`" + ` | {{.Description}} |
+{{end}}{{end}}{{if or .Command.HasParent .Commands}}
+### See also
+
+{{if .Command.HasParent}}* [{{.Command.Parent.CommandPath}}]({{.Command.Parent | markdownFile}}) - {{.Command.Parent.Short}}
+{{end}}{{range .Commands}}* [{{.CommandPath}}]({{. | markdownFile}}) - {{.Short}}
+{{end}}{{end}}`
+
+var manTemplate = `.TH "{{.Command | manName | upper}}" "1" "" "{{.Command.Root.Name}}" ""
+.SH NAME
+{{.Command | manName}} \- {{.Command.Short | roff}}
+.SH SYNOPSIS
+\fB{{.Command.UseLine | roff}}\fP
+.SH DESCRIPTION
+{{or .Command.Long .Command.Short | roff}}
+{{if .Command.HasAvailableLocalFlags}}.SH OPTIONS
+{{range (.Command.LocalFlags | manFlags)}}{{.}}{{end}}{{end}}{{if .Command.HasAvailableInheritedFlags}}.SH OPTIONS INHERITED FROM PARENT COMMANDS
+{{range (.Command.InheritedFlags | manFlags)}}{{.}}{{end}}{{end}}{{if .EnvVars}}.SH ENVIRONMENT
+{{range .EnvVars}}.TP
+\fB{{.Name}}\fP
+{{.Description | roff}}
+{{end}}{{end}}{{if or .Command.HasParent .Commands}}.SH SEE ALSO
+{{if .Command.HasParent}}\fB{{.Command.Parent | manName}}(1)\fP
+{{end}}{{range .Commands}}\fB{{. | manName}}(1)\fP
+{{end}}{{end}}`
+
+var docsFuncs = template.FuncMap{
+	"markdownFile": markdownFile,
+	"manName":      manName,
+	"manFlags":     manFlags,
+	"roff":         roff,
+	"upper":        strings.ToUpper,
+}
+
+// writeDocs writes a page per available command starting from root into dir
+func writeDocs(root *command, format, dir string) error {
+	t := template.New("docs").Funcs(docsFuncs)
+	file := markdownFile
+	switch format {
+	case docsFormatMarkdown:
+		template.Must(t.Parse(markdownTemplate))
+	case docsFormatMan:
+		template.Must(t.Parse(manTemplate))
+		file = func(cmd *cobra.Command) string {
+			return manName(cmd) + ".1"
+		}
+	default:
+		return fmt.Errorf("unsupported docs format %s", format)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("unable to create docs directory: %w", err)
+	}
+	return root.walk(func(c *command) error {
+		c.cobra.InitDefaultHelpFlag()
+
+		page := docPage{Command: c.cobra, EnvVars: c.mergeEnvVars(c.mergeComponents())}
+		for _, child := range c.cobra.Commands() {
+			if child.IsAvailableCommand() {
+				page.Commands = append(page.Commands, child)
+			}
+		}
+
+		f, err := os.Create(filepath.Join(dir, file(c.cobra)))
+		if err != nil {
+			return fmt.Errorf("unable to create docs page: %w", err)
+		}
+		defer f.Close()
+		if err := t.Execute(f, page); err != nil {
+			return fmt.Errorf("unable to write docs page of %s: %w", c.cobra.CommandPath(), err)
+		}
+		return nil
+	})
+}
+
+// root returns the top most parent of the command
+func (c *command) root() *command {
+	current := c
+	for current.parent != nil {
+		current = current.parent
+	}
+	return current
+}
+
+// walk calls f with the command and every available sub command under it, hidden commands are skipped
+func (c *command) walk(f func(c *command) error) error {
+	if c.cobra.Hidden {
+		return nil
+	}
+	if err := f(c); err != nil {
+		return err
+	}
+	for _, child := range c.childCommands {
+		if err := child.(*command).walk(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func markdownFile(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "_") + ".md"
+}
+
+func manName(cmd *cobra.Command) string {
+	return strings.ReplaceAll(cmd.CommandPath(), " ", "-")
+}
+
+// manFlags returns a tagged paragraph per flag with its usage and default
+func manFlags(flags *pflag.FlagSet) []string {
+	paragraphs := make([]string, 0)
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden {
+			return
+		}
+		name, usage := pflag.UnquoteUsage(flag)
+		p := ".TP\n"
+		if flag.Shorthand != "" {
+			p += `\fB\-` + flag.Shorthand + `\fP, `
+		}
+		p += `\fB\-\-` + roff(flag.Name) + `\fP`
+		if name != "" {
+			p += " " + name
+		}
+		p += "\n" + roff(usage)
+		if !zeroDefault(flag) {
+			p += " (default " + roff(flag.DefValue) + ")"
+		}
+		paragraphs = append(paragraphs, p+"\n")
+	})
+	return paragraphs
+}
+
+// zeroDefault returns true when the default of the flag is the zero value of its type so it is left out like in help
+func zeroDefault(flag *pflag.Flag) bool {
+	switch flag.DefValue {
+	case "", "0", "false", "[]", "0s":
+		return true
+	}
+	return false
+}
+
+// roff escapes text so it is shown as is in a man page
+func roff(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\e`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"deploy-runner/config"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocs(t *testing.T) {
+	t.Run("TestMarkdown", testMarkdown)
+	t.Run("TestMan", testMan)
+}
+
+var envDocs = config.EnvVar{Key: "DOCS_VAR", Name: "DOCS_VAR", Description: "Var of the docs test"}
+
+// generateDocs builds a root with a container and an action and runs the docs command with args
+func generateDocs(t *testing.T, args ...string) string {
+	root := ContainerCommand("root", "Root of the docs test", "", NewHelpWriter())
+	parent := ContainerCommand("parent", "Parent of the docs test", "", NewHelpWriter())
+	parent.StringFlag("PARENT", "parent-flag", "Parent flag")
+	action := ActionCommand("action", "Action of the docs test", "", nil, envDocs)
+	action.EnumFlag("FORMAT", "format", "Format flag", []string{"json", "junit"}, Default("json"))
+	parent.AddCommand(action)
+	root.AddCommand(parent, DocsCommand())
+
+	dir := t.TempDir()
+	c := root.ToCobra(viper.New())
+	c.SetArgs(append([]string{"docs", dir}, args...))
+	assert.NoError(t, c.Execute())
+	return dir
+}
+
+func readDoc(t *testing.T, dir, name string) string {
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func testMarkdown(t *testing.T) {
+	dir := generateDocs(t)
+
+	page := readDoc(t, dir, "root_parent_action.md")
+	assert.Contains(t, page, "## root parent action\n")
+	assert.Contains(t, page, `--format string   Format flag, one of json, junit (default "json")`)
+	assert.Contains(t, page, "--parent-flag string   Parent flag")
+	assert.Contains(t, page, "| `DOCS_VAR` | Var of the docs test |")
+	assert.Contains(t, page, "* [root parent](root_parent.md) - Parent of the docs test")
+
+	assert.Contains(t, readDoc(t, dir, "root.md"), "* [root parent](root_parent.md) - Parent of the docs test")
+	// The docs command is hidden so it has no page
+	assert.NoFileExists(t, filepath.Join(dir, "root_docs.md"))
+}
+
+func testMan(t *testing.T) {
+	dir := generateDocs(t, "--format", "man")
+
+	page := readDoc(t, dir, "root-parent-action.1")
+	assert.Contains(t, page, `.TH "ROOT-PARENT-ACTION" "1" "" "root" ""`)
+	assert.Contains(t, page, ".TP\n\\fB\\-\\-format\\fP string\nFormat flag, one of json, junit (default json)\n")
+	assert.Contains(t, page, ".SH OPTIONS INHERITED FROM PARENT COMMANDS\n.TP\n\\fB\\-\\-parent\\-flag\\fP string\n")
+	assert.Contains(t, page, ".SH ENVIRONMENT\n.TP\n\\fBDOCS_VAR\\fP\nVar of the docs test\n")
+	assert.Contains(t, page, ".SH SEE ALSO\n\\fBroot-parent(1)\\fP\n")
+}
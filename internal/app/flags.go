@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// FlagOption changes how a flag is added to a command
+type FlagOption func(o *flagOptions)
+
+type flagOptions struct {
+	shorthand    string
+	defaultValue interface{}
+	required     bool
+}
+
+// Default sets the default value of a flag, it has to be of the flag's type, e.g. []string for a StringSliceFlag or
+// map[string]string for a StringToStringFlag. The default is only used when the viper key has no value from the config
+// or environment
+func Default(value interface{}) FlagOption {
+	return func(o *flagOptions) {
+		o.defaultValue = value
+	}
+}
+
+// Required fails the command before it runs when the flag is not passed and its viper key has no value from the config
+// or environment
+func Required() FlagOption {
+	return func(o *flagOptions) {
+		o.required = true
+	}
+}
+
+// Shorthand sets the one letter shorthand of a flag, e.g. -v for --var
+func Shorthand(shorthand string) FlagOption {
+	return func(o *flagOptions) {
+		o.shorthand = shorthand
+	}
+}
+
+func newFlagOptions(zero interface{}, options []FlagOption) *flagOptions {
+	o := &flagOptions{defaultValue: zero}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// flagCheck holds what is checked of a flag's value once flags, env vars and config are bound
+type flagCheck struct {
+	required bool
+	// values are the allowed values of an enum flag
+	values []string
+}
+
+// check checks the value of the viper key the flag is bound to, so values from env vars and the config are checked as
+// well as the flag itself
+func (f flagCheck) check(cfg *viper.Viper, flag *pflag.Flag, viperKey string) error {
+	if f.required && !flag.Changed && !cfg.IsSet(viperKey) {
+		return fmt.Errorf("required flag --%s is not set", flag.Name)
+	}
+
+	if len(f.values) > 0 {
+		value := cfg.GetString(viperKey)
+		if value == "" {
+			return nil
+		}
+		for _, v := range f.values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid value %s for --%s, expected one of %s", value, flag.Name, strings.Join(f.values, ", "))
+	}
+	return nil
+}
+
+// flagUsage adds the allowed values and if the flag is required to its usage so they show up in the help output
+func flagUsage(usage string, check flagCheck) string {
+	if len(check.values) > 0 {
+		usage += ", one of " + strings.Join(check.values, ", ")
+	}
+	if check.required {
+		usage += " (required)"
+	}
+	return usage
+}
+
+// completeValues completes an enum flag with its allowed values
+func completeValues(values []string) func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values, cobra.ShellCompDirectiveNoFileComp
+	}
+}
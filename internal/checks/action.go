@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// formatJUnit is the value of the format flag writing the report as JUnit XML rather than json
+const formatJUnit = "junit"
+
+// ErrChecksFailed is returned by the test command when the module failed one of its checks so the process exits non
+// zero and CI can gate on it
+var ErrChecksFailed = errors.New("module failed its checks")
+
+type testAction struct {
+	cfg     *viper.Viper
+	checker internal.ModuleChecker
+	out     io.Writer
+	tfOut   io.Writer
+}
+
+// NewTestAction checks the module of the stack passed as the only argument, or of the repository and path flags when
+// no stack is given, and writes the report as json or JUnit XML
+func NewTestAction(cfg *viper.Viper, checker internal.ModuleChecker) app.ActionAdapter {
+	return &testAction{cfg: cfg, checker: checker, out: os.Stdout, tfOut: os.Stderr}
+}
+
+func (a *testAction) Execute(args []string) error {
+	if len(args) > 1 {
+		return errors.New("expected at most the stack to test as an argument")
+	}
+	req := internal.ModuleCheckRequest{
+		Repository: a.cfg.GetString(config.TestRepository.String()),
+		Path:       a.cfg.GetString(config.TestPath.String()),
+		Ref:        a.cfg.GetString(config.ClientRef.String()),
+		RunTests:   a.cfg.GetBool(config.TestTerraformTest.String()),
+	}
+	if len(args) == 1 {
+		req.Stack = args[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := a.checker.Check(ctx, req, a.tfOut)
+	if err != nil {
+		return err
+	}
+
+	if err := a.write(report); err != nil {
+		return err
+	}
+	if !report.Passed {
+		return ErrChecksFailed
+	}
+	return nil
+}
+
+// write writes the report to the report file, or stdout when no file is set
+func (a *testAction) write(report *internal.ModuleReport) error {
+	path := a.cfg.GetString(config.TestReportFile.String())
+	if path == "" {
+		return a.encode(a.out, report)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create report file: %w", err)
+	}
+	if err := a.encode(f, report); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write report file: %w", err)
+	}
+	return nil
+}
+
+// encode writes the report in the format of the format flag, which only accepts json and junit
+func (a *testAction) encode(w io.Writer, report *internal.ModuleReport) error {
+	if a.cfg.GetString(config.TestFormat.String()) == formatJUnit {
+		return WriteJUnit(w, report)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
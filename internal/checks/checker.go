@@ -0,0 +1,178 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/internal"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	checkFmt      = "fmt"
+	checkInit     = "init"
+	checkValidate = "validate"
+	checkTest     = "test"
+)
+
+type checker struct {
+	log    internal.BackgroundLog
+	git    internal.GitClient
+	tf     internal.TerraformFactory
+	stacks internal.StackRegistry
+}
+
+func NewChecker(log internal.BackgroundLog, git internal.GitClient, tf internal.TerraformFactory, stacks internal.StackRegistry) internal.ModuleChecker {
+	return &checker{
+		log:    log.ChildLog("checks"),
+		git:    git,
+		tf:     tf,
+		stacks: stacks,
+	}
+}
+
+func (c *checker) Check(ctx context.Context, req internal.ModuleCheckRequest, output io.Writer) (*internal.ModuleReport, error) {
+	report := &internal.ModuleReport{Stack: req.Stack, Repository: req.Repository, Path: req.Path, Ref: req.Ref}
+	var opts internal.TerraformOptions
+	if req.Stack != "" {
+		stack, ok := c.stacks.Get(req.Stack)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown stack %s", internal.ErrInvalidRequest, req.Stack)
+		}
+		report.Repository, report.Path = stack.Repository, stack.Path
+		opts.Version = stack.TerraformVersion
+	}
+	if report.Repository == "" {
+		return nil, fmt.Errorf("%w: a stack or repository is required", internal.ErrInvalidRequest)
+	}
+	// The module is checked without its backend so no state or backend credentials are needed
+	opts.NoBackend = true
+
+	dir, err := os.MkdirTemp("", "deploy-runner-test-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create checkout directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if report.CommitSHA, err = c.git.Clone(ctx, report.Repository, req.Ref, dir); err != nil {
+		return nil, err
+	}
+	moduleDir := filepath.Join(dir, report.Path)
+	if _, err := os.Stat(moduleDir); err != nil {
+		return nil, fmt.Errorf("%w: module path %s not found in %s", internal.ErrInvalidRequest, report.Path, report.Repository)
+	}
+
+	m := &module{dir: moduleDir, opts: opts, tf: c.tf, output: output}
+	report.Checks = append(report.Checks, m.check(ctx, checkFmt, m.format))
+
+	initResult := m.check(ctx, checkInit, m.init)
+	report.Checks = append(report.Checks, initResult)
+	if initResult.Status == internal.CheckStatusFailed {
+		report.Checks = append(report.Checks, skipped(checkValidate, "terraform init failed"))
+		if req.RunTests {
+			report.Checks = append(report.Checks, skipped(checkTest, "terraform init failed"))
+		}
+	} else {
+		report.Checks = append(report.Checks, m.check(ctx, checkValidate, m.validate))
+		if req.RunTests {
+			report.Checks = append(report.Checks, m.check(ctx, checkTest, m.test))
+		}
+	}
+
+	report.Passed = true
+	for _, check := range report.Checks {
+		if check.Status == internal.CheckStatusFailed {
+			report.Passed = false
+		}
+	}
+	c.log.Infow("Module checked", "repository", report.Repository, "path", report.Path, "commit", report.CommitSHA, "passed", report.Passed)
+	return report, nil
+}
+
+// module runs the checks against a checked out module
+type module struct {
+	dir    string
+	opts   internal.TerraformOptions
+	tf     internal.TerraformFactory
+	output io.Writer
+}
+
+// errDiagnostics fails a check whose problems are described by its diagnostics
+var errDiagnostics = errors.New("module has errors")
+
+// check runs a single check, f returns the diagnostics the check found and fails it with an error
+func (m *module) check(ctx context.Context, name string, f func(ctx context.Context, tf internal.TerraformClient) ([]internal.TerraformDiagnostic, error)) internal.CheckResult {
+	result := internal.CheckResult{Name: name, StartedAt: time.Now().UTC()}
+
+	// Each check gets its own client so its output can be kept with its result
+	var out bytes.Buffer
+	w := io.Writer(&out)
+	if m.output != nil {
+		w = io.MultiWriter(m.output, &out)
+	}
+
+	tf, err := m.tf.NewClient(m.dir, w, m.opts)
+	if err == nil {
+		result.Diagnostics, err = f(ctx, tf)
+	}
+
+	result.FinishedAt = time.Now().UTC()
+	result.Output = out.String()
+	result.Status = internal.CheckStatusPassed
+	if err != nil {
+		result.Status = internal.CheckStatusFailed
+		if !errors.Is(err, errDiagnostics) {
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
+
+func (m *module) format(ctx context.Context, tf internal.TerraformClient) ([]internal.TerraformDiagnostic, error) {
+	files, err := tf.FormatCheck(ctx)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+
+	diagnostics := make([]internal.TerraformDiagnostic, 0, len(files))
+	for _, f := range files {
+		diagnostics = append(diagnostics, internal.TerraformDiagnostic{
+			Severity: "error",
+			Summary:  "File is not formatted, run terraform fmt",
+			Filename: f,
+		})
+	}
+	return diagnostics, errDiagnostics
+}
+
+func (m *module) init(ctx context.Context, tf internal.TerraformClient) ([]internal.TerraformDiagnostic, error) {
+	return nil, tf.Init(ctx)
+}
+
+func (m *module) validate(ctx context.Context, tf internal.TerraformClient) ([]internal.TerraformDiagnostic, error) {
+	diagnostics, err := tf.Validate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Warnings are reported but only errors fail the check
+	for _, d := range diagnostics {
+		if d.Severity == "error" {
+			return diagnostics, errDiagnostics
+		}
+	}
+	return diagnostics, nil
+}
+
+func (m *module) test(ctx context.Context, tf internal.TerraformClient) ([]internal.TerraformDiagnostic, error) {
+	return nil, tf.Test(ctx)
+}
+
+func skipped(name, reason string) internal.CheckResult {
+	now := time.Now().UTC()
+	return internal.CheckResult{Name: name, Status: internal.CheckStatusSkipped, StartedAt: now, FinishedAt: now, Error: reason}
+}
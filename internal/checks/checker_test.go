@@ -0,0 +1,141 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"deploy-runner/internal/stacks"
+	"encoding/xml"
+	"errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("TestPassed", testPassed)
+	t.Run("TestInitFailureSkips", testInitFailureSkips)
+	t.Run("TestJUnit", testJUnit)
+}
+
+type fakeGit struct{}
+
+func (g *fakeGit) Clone(ctx context.Context, repo, ref, dir string) (string, error) {
+	return "0123456789abcdef0123456789abcdef01234567", os.MkdirAll(filepath.Join(dir, "network"), 0o755)
+}
+
+// fakeTerraform embeds internal.TerraformClient so only the methods used by the checks have to be implemented
+type fakeTerraform struct {
+	internal.TerraformClient
+	output      io.Writer
+	opts        internal.TerraformOptions
+	unformatted []string
+	initErr     error
+	diagnostics []internal.TerraformDiagnostic
+}
+
+func (f *fakeTerraform) NewClient(workDir string, output io.Writer, opts internal.TerraformOptions) (internal.TerraformClient, error) {
+	f.output = output
+	f.opts = opts
+	return f, nil
+}
+
+func (f *fakeTerraform) FormatCheck(ctx context.Context) ([]string, error) {
+	return f.unformatted, nil
+}
+
+func (f *fakeTerraform) Init(ctx context.Context) error {
+	_, _ = f.output.Write([]byte("Initializing modules...\n"))
+	return f.initErr
+}
+
+func (f *fakeTerraform) Validate(ctx context.Context) ([]internal.TerraformDiagnostic, error) {
+	return f.diagnostics, nil
+}
+
+func (f *fakeTerraform) Test(ctx context.Context) error {
+	return nil
+}
+
+func check(t *testing.T, tf *fakeTerraform, req internal.ModuleCheckRequest) *internal.ModuleReport {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://example.com/infra.git", "path": "network", "terraform_version": "1.6.0"},
+	})
+
+	registry, err := stacks.NewRegistry(cfg)
+	assert.NoError(t, err)
+	c := NewChecker(logging.NewBackgroundLog(cfg), &fakeGit{}, tf, registry)
+	report, err := c.Check(context.Background(), req, nil)
+	assert.NoError(t, err)
+	return report
+}
+
+func statuses(report *internal.ModuleReport) map[string]internal.CheckStatus {
+	s := make(map[string]internal.CheckStatus)
+	for _, c := range report.Checks {
+		s[c.Name] = c.Status
+	}
+	return s
+}
+
+func testPassed(t *testing.T) {
+	tf := &fakeTerraform{diagnostics: []internal.TerraformDiagnostic{{Severity: "warning", Summary: "Deprecated attribute"}}}
+	report := check(t, tf, internal.ModuleCheckRequest{Stack: "network", RunTests: true})
+
+	assert.True(t, report.Passed)
+	assert.Equal(t, "https://example.com/infra.git", report.Repository)
+	assert.Equal(t, internal.TerraformOptions{Version: "1.6.0", NoBackend: true}, tf.opts)
+	assert.Equal(t, map[string]internal.CheckStatus{
+		checkFmt:      internal.CheckStatusPassed,
+		checkInit:     internal.CheckStatusPassed,
+		checkValidate: internal.CheckStatusPassed,
+		checkTest:     internal.CheckStatusPassed,
+	}, statuses(report))
+	assert.Equal(t, "Initializing modules...\n", report.Checks[1].Output)
+}
+
+func testInitFailureSkips(t *testing.T) {
+	tf := &fakeTerraform{unformatted: []string{"main.tf"}, initErr: errors.New("terraform init failed: exit status 1")}
+	report := check(t, tf, internal.ModuleCheckRequest{Repository: "https://example.com/infra.git", Path: "network"})
+
+	assert.False(t, report.Passed)
+	assert.Equal(t, map[string]internal.CheckStatus{
+		checkFmt:      internal.CheckStatusFailed,
+		checkInit:     internal.CheckStatusFailed,
+		checkValidate: internal.CheckStatusSkipped,
+	}, statuses(report))
+	assert.Equal(t, "main.tf", report.Checks[0].Diagnostics[0].Filename)
+}
+
+func testJUnit(t *testing.T) {
+	tf := &fakeTerraform{diagnostics: []internal.TerraformDiagnostic{
+		{Severity: "error", Summary: "Unsupported argument", Detail: "An argument named \"cidr\" is not expected here.", Filename: "main.tf", Line: 3, Column: 5},
+	}}
+	report := check(t, tf, internal.ModuleCheckRequest{Stack: "network"})
+	assert.False(t, report.Passed)
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteJUnit(&buf, report))
+
+	var suites junitTestSuites
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	if !assert.Len(t, suites.Suites, 1) || !assert.Len(t, suites.Suites[0].Cases, 3) {
+		return
+	}
+	validate := suites.Suites[0].Cases[2]
+	assert.Equal(t, "network", validate.ClassName)
+	if assert.NotNil(t, validate.Failure) {
+		assert.Equal(t, "1 problem found", validate.Failure.Message)
+		assert.Equal(t, "main.tf:3:5: error: Unsupported argument\nAn argument named \"cidr\" is not expected here.", validate.Failure.Text)
+	}
+}
@@ -0,0 +1,8 @@
+package checks
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("checks", []config.EnvVar{}, NewChecker)
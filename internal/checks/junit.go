@@ -0,0 +1,147 @@
+package checks
+
+import (
+	"deploy-runner/internal"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes a report as JUnit XML with one test suite for the module and one test case per check
+func WriteJUnit(w io.Writer, report *internal.ModuleReport) error {
+	suite := junitTestSuite{Name: suiteName(report)}
+	if len(report.Checks) > 0 {
+		suite.Timestamp = report.Checks[0].StartedAt.Format("2006-01-02T15:04:05")
+		suite.Time = seconds(report.Checks[0], report.Checks[len(report.Checks)-1])
+	}
+
+	for _, check := range report.Checks {
+		tc := junitTestCase{
+			Name:      check.Name,
+			ClassName: suite.Name,
+			Time:      seconds(check, check),
+			SystemOut: check.Output,
+		}
+		switch check.Status {
+		case internal.CheckStatusFailed:
+			suite.Failures++
+			tc.Failure = &junitMessage{Message: failureMessage(check), Type: check.Name, Text: describe(check)}
+		case internal.CheckStatusSkipped:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: check.Error}
+		default:
+			// Warnings of passing checks are kept with the check's output
+			if text := describe(check); text != "" {
+				tc.SystemOut = strings.TrimLeft(tc.SystemOut+"\n"+text, "\n")
+			}
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	suites := junitTestSuites{
+		Name:     "deploy-runner",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Skipped:  suite.Skipped,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func suiteName(report *internal.ModuleReport) string {
+	if report.Stack != "" {
+		return report.Stack
+	}
+	if report.Path == "" {
+		return report.Repository
+	}
+	return report.Repository + "//" + report.Path
+}
+
+func seconds(first, last internal.CheckResult) string {
+	return fmt.Sprintf("%.3f", last.FinishedAt.Sub(first.StartedAt).Seconds())
+}
+
+func failureMessage(check internal.CheckResult) string {
+	if check.Error != "" {
+		// Terraform errors carry stderr after the first line
+		return strings.SplitN(check.Error, "\n", 2)[0]
+	}
+	if len(check.Diagnostics) == 1 {
+		return "1 problem found"
+	}
+	return fmt.Sprintf("%d problems found", len(check.Diagnostics))
+}
+
+// describe lists the diagnostics of a check one per line as file:line:column: severity: summary followed by the
+// detail, the error of the check comes first when it has one
+func describe(check internal.CheckResult) string {
+	var b strings.Builder
+	if check.Error != "" {
+		b.WriteString(check.Error)
+		b.WriteString("\n")
+	}
+	for _, d := range check.Diagnostics {
+		if d.Filename != "" {
+			b.WriteString(d.Filename)
+			if d.Line > 0 {
+				fmt.Fprintf(&b, ":%d:%d", d.Line, d.Column)
+			}
+			b.WriteString(": ")
+		}
+		fmt.Fprintf(&b, "%s: %s\n", d.Severity, d.Summary)
+		if d.Detail != "" {
+			b.WriteString(d.Detail)
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,22 @@
+package internal
+
+import "context"
+
+// ServerClient calls the http api of a running deploy-runner server
+type ServerClient interface {
+	Submit(ctx context.Context, req DeployRequest) (*Run, error)
+	List(ctx context.Context, filter RunFilter) (RunPage, error)
+	Get(ctx context.Context, id string) (*Run, error)
+
+	// Logs returns the terraform output of a run from byte offset onwards, it is empty when the run has not written
+	// anything past offset yet or does not exist
+	Logs(ctx context.Context, id string, offset int64) ([]byte, error)
+
+	// Approve approves the plan of a run as the caller the server token belongs to
+	Approve(ctx context.Context, id string) (*Run, error)
+	// Cancel cancels a run as the caller the server token belongs to
+	Cancel(ctx context.Context, id string) (*Run, error)
+
+	Stacks(ctx context.Context) ([]Stack, error)
+	Stack(ctx context.Context, name string) (*Stack, error)
+}
@@ -0,0 +1,202 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type submitRunResponse struct {
+	Run *internal.Run `json:"run"`
+}
+
+type httpClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func NewClient(cfg *viper.Viper) internal.ServerClient {
+	return &httpClient{
+		client:  &http.Client{Timeout: requestTimeout},
+		baseURL: strings.TrimSuffix(cfg.GetString(config.ServerURL.String()), "/"),
+		token:   cfg.GetString(config.ServerToken.String()),
+	}
+}
+
+func (c *httpClient) Submit(ctx context.Context, req internal.DeployRequest) (*internal.Run, error) {
+	var resp submitRunResponse
+	if err := c.do(ctx, http.MethodPost, "/runs", nil, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Run, nil
+}
+
+func (c *httpClient) List(ctx context.Context, filter internal.RunFilter) (internal.RunPage, error) {
+	q := url.Values{}
+	setQuery(q, "stack", filter.Stack)
+	setQuery(q, "status", string(filter.Status))
+	setQuery(q, "requester", filter.Requester)
+	setQuery(q, "cursor", filter.Cursor)
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	if !filter.Since.IsZero() {
+		q.Set("since", filter.Since.Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		q.Set("until", filter.Until.Format(time.RFC3339))
+	}
+
+	var page internal.RunPage
+	err := c.do(ctx, http.MethodGet, "/runs", q, nil, &page)
+	return page, err
+}
+
+func (c *httpClient) Get(ctx context.Context, id string) (*internal.Run, error) {
+	var run internal.Run
+	if err := c.do(ctx, http.MethodGet, "/runs/"+url.PathEscape(id), nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (c *httpClient) Logs(ctx context.Context, id string, offset int64) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/runs/"+url.PathEscape(id)+"/logs", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusPartialContent:
+		return ioutil.ReadAll(resp.Body)
+	case http.StatusRequestedRangeNotSatisfiable, http.StatusNotFound:
+		// Nothing has been written past offset yet, the server also responds with not found for unknown runs so
+		// callers check the run exists with Get
+		return nil, nil
+	default:
+		return nil, readError(req, resp)
+	}
+}
+
+func (c *httpClient) Approve(ctx context.Context, id string) (*internal.Run, error) {
+	var run internal.Run
+	if err := c.do(ctx, http.MethodPost, "/runs/"+url.PathEscape(id)+"/approve", nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (c *httpClient) Cancel(ctx context.Context, id string) (*internal.Run, error) {
+	var run internal.Run
+	if err := c.do(ctx, http.MethodPost, "/runs/"+url.PathEscape(id)+"/cancel", nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (c *httpClient) Stacks(ctx context.Context) ([]internal.Stack, error) {
+	var stacks []internal.Stack
+	err := c.do(ctx, http.MethodGet, "/stacks", nil, nil, &stacks)
+	return stacks, err
+}
+
+func (c *httpClient) Stack(ctx context.Context, name string) (*internal.Stack, error) {
+	var stack internal.Stack
+	if err := c.do(ctx, http.MethodGet, "/stacks/"+url.PathEscape(name), nil, nil, &stack); err != nil {
+		return nil, err
+	}
+	return &stack, nil
+}
+
+// do sends body as json and decodes the json response into out, any non 2xx response is returned as an error
+func (c *httpClient) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return readError(req, resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("unable to decode response of %s %s: %w", method, req.URL.Path, err)
+	}
+	return nil
+}
+
+func (c *httpClient) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// readError turns the error response of the server into an error, falling back to the raw body when it is not json
+func readError(req *http.Request, resp *http.Response) error {
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e errorResponse
+	if err := json.Unmarshal(msg, &e); err == nil && e.Error != "" {
+		msg = []byte(e.Error)
+	}
+	return fmt.Errorf("%s %s responded with %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(msg))
+}
+
+func setQuery(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
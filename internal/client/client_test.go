@@ -0,0 +1,133 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/output"
+	"encoding/json"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	t.Run("TestSubmit", testSubmit)
+	t.Run("TestServerError", testServerError)
+	t.Run("TestFollowLogs", testFollowLogs)
+	t.Run("TestCompletePendingRuns", testCompletePendingRuns)
+}
+
+func newTestClient(t *testing.T, handler http.Handler) (*viper.Viper, internal.ServerClient) {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	cfg := viper.New()
+	cfg.Set(config.ServerURL.String(), srv.URL+"/")
+	cfg.Set(config.ServerToken.String(), "secret")
+	return cfg, NewClient(cfg)
+}
+
+func testSubmit(t *testing.T) {
+	var got internal.DeployRequest
+	var auth string
+	cfg, c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(submitRunResponse{Run: internal.NewRun(got, internal.Stack{})})
+	}))
+	cfg.Set(config.ClientRef.String(), "main")
+	cfg.Set(config.ClientPlanOnly.String(), true)
+
+	out := &bytes.Buffer{}
+	w, err := output.New("json", out)
+	assert.NoError(t, err)
+	a := &submitAction{cfg: cfg, client: c, output: w}
+	assert.NoError(t, a.Execute([]string{"network"}))
+
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, internal.DeployRequest{Stack: "network", Ref: "main", PlanOnly: true}, got)
+	var run internal.Run
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &run))
+	assert.Equal(t, "network", run.Stack)
+	assert.Equal(t, internal.RunStatusQueued, run.Status)
+}
+
+func testServerError(t *testing.T) {
+	_, c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(`{"error":"run is not awaiting approval"}`))
+	}))
+
+	_, err := c.Approve(context.Background(), "abc")
+	assert.EqualError(t, err, "POST /runs/abc/approve responded with 409 Conflict: run is not awaiting approval")
+}
+
+func testFollowLogs(t *testing.T) {
+	var mu sync.Mutex
+	status := internal.RunStatusPlanning
+	var logs []byte
+	write := func(s internal.RunStatus, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = s
+		logs = append(logs, line...)
+	}
+
+	_, c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/logs") {
+			if len(logs) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(logs))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(internal.Run{ID: "abc", Status: status})
+	}))
+
+	cfg := viper.New()
+	cfg.Set(config.ClientFollow.String(), true)
+	out := &bytes.Buffer{}
+	a := &logsAction{cfg: cfg, client: c, out: out, interval: time.Millisecond}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		write(internal.RunStatusPlanning, "init\n")
+		time.Sleep(5 * time.Millisecond)
+		write(internal.RunStatusApplying, "plan\n")
+		time.Sleep(5 * time.Millisecond)
+		write(internal.RunStatusApplied, "apply\n")
+	}()
+	assert.NoError(t, a.Execute([]string{"abc"}))
+	assert.Equal(t, "init\nplan\napply\n", out.String())
+}
+
+func testCompletePendingRuns(t *testing.T) {
+	var query string
+	_, c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		_ = json.NewEncoder(w).Encode(internal.RunPage{Runs: []*internal.Run{
+			{ID: "a1", Stack: "network", Workspace: "prod", Status: internal.RunStatusAwaitingApproval},
+			{ID: "b2", Stack: "dns", Workspace: "dev", Status: internal.RunStatusAwaitingApproval},
+		}})
+	}))
+
+	completions, directive := NewPendingRunCompleter(c).Complete(nil, "a")
+	assert.Equal(t, "limit=50&status=awaiting_approval", query)
+	assert.Equal(t, []string{"a1\tnetwork/prod awaiting_approval"}, completions)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	completions, _ = NewPendingRunCompleter(c).Complete([]string{"a1"}, "")
+	assert.Empty(t, completions)
+}
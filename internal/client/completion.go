@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"github.com/spf13/cobra"
+	"strings"
+	"time"
+)
+
+const (
+	completionTimeout = 5 * time.Second
+
+	// completionRunLimit is how many of the newest runs are offered when completing a run id
+	completionRunLimit = 50
+)
+
+type stackCompleter struct {
+	client internal.ServerClient
+}
+
+// NewStackCompleter completes the first argument with the names of the stacks registered with the server
+func NewStackCompleter(client internal.ServerClient) app.ArgCompleter {
+	return &stackCompleter{client: client}
+}
+
+func (c *stackCompleter) Complete(args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	stacks, err := c.client.Stacks(ctx)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []string
+	for _, stack := range stacks {
+		if strings.HasPrefix(stack.Name, toComplete) {
+			completions = append(completions, stack.Name+"\t"+stack.Repository)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+type runCompleter struct {
+	client internal.ServerClient
+	status internal.RunStatus
+}
+
+// NewRunCompleter completes the first argument with the ids of the newest runs on the server
+func NewRunCompleter(client internal.ServerClient) app.ArgCompleter {
+	return &runCompleter{client: client}
+}
+
+// NewPendingRunCompleter completes the first argument with the ids of the runs waiting for approval
+func NewPendingRunCompleter(client internal.ServerClient) app.ArgCompleter {
+	return &runCompleter{client: client, status: internal.RunStatusAwaitingApproval}
+}
+
+func (c *runCompleter) Complete(args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+	page, err := c.client.List(ctx, internal.RunFilter{Status: c.status, Limit: completionRunLimit})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []string
+	for _, run := range page.Runs {
+		if strings.HasPrefix(run.ID, toComplete) {
+			completions = append(completions, run.ID+"\t"+run.Stack+"/"+run.Workspace+" "+string(run.Status))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
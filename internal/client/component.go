@@ -0,0 +1,8 @@
+package client
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("client", []config.EnvVar{}, NewClient)
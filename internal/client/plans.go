@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"errors"
+	"github.com/spf13/viper"
+)
+
+// plan is the plan of a run as printed by the plans commands
+type plan struct {
+	RunID      string                `json:"run_id"`
+	Stack      string                `json:"stack"`
+	Workspace  string                `json:"workspace"`
+	Status     internal.RunStatus    `json:"status"`
+	CommitSHA  string                `json:"commit_sha"`
+	HasChanges bool                  `json:"has_changes"`
+	Summary    *internal.PlanSummary `json:"summary,omitempty"`
+}
+
+func newPlan(run *internal.Run) plan {
+	return plan{
+		RunID:      run.ID,
+		Stack:      run.Stack,
+		Workspace:  run.Workspace,
+		Status:     run.Status,
+		CommitSHA:  run.CommitSHA,
+		HasChanges: run.HasChanges,
+		Summary:    run.Plan,
+	}
+}
+
+type plansShowAction struct {
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewPlansShowAction prints the plan of the run with the id passed as the only argument
+func NewPlansShowAction(client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &plansShowAction{client: client, output: output}
+}
+
+func (a *plansShowAction) Execute(args []string) error {
+	id, err := runID(args)
+	if err != nil {
+		return err
+	}
+
+	run, err := a.client.Get(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	p := newPlan(run)
+	return a.output.Write(p, plansTable(p))
+}
+
+type plansPendingAction struct {
+	cfg    *viper.Viper
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewPlansPendingAction lists the plans of runs waiting for approval newest first
+func NewPlansPendingAction(cfg *viper.Viper, client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &plansPendingAction{cfg: cfg, client: client, output: output}
+}
+
+func (a *plansPendingAction) Execute(args []string) error {
+	if len(args) != 0 {
+		return errors.New("pending does not take any arguments")
+	}
+
+	page, err := a.client.List(context.Background(), internal.RunFilter{
+		Stack:  a.cfg.GetString(config.ClientStack.String()),
+		Status: internal.RunStatusAwaitingApproval,
+		Limit:  a.cfg.GetInt(config.ClientLimit.String()),
+	})
+	if err != nil {
+		return err
+	}
+
+	plans := make([]plan, 0, len(page.Runs))
+	for _, run := range page.Runs {
+		plans = append(plans, newPlan(run))
+	}
+	return a.output.Write(plans, plansTable(plans...))
+}
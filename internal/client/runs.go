@@ -0,0 +1,205 @@
+package client
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"os/user"
+	"time"
+)
+
+const followInterval = 2 * time.Second
+
+type submitAction struct {
+	cfg    *viper.Viper
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewSubmitAction submits a run of the stack passed as the only argument
+func NewSubmitAction(cfg *viper.Viper, client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &submitAction{cfg: cfg, client: client, output: output}
+}
+
+func (a *submitAction) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected the stack to deploy as the only argument")
+	}
+
+	// The server records the caller its token belongs to as the requester
+	run, err := a.client.Submit(context.Background(), internal.DeployRequest{
+		Stack:     args[0],
+		Workspace: a.cfg.GetString(config.ClientWorkspace.String()),
+		Ref:       a.cfg.GetString(config.ClientRef.String()),
+		PlanOnly:  a.cfg.GetBool(config.ClientPlanOnly.String()),
+	})
+	if err != nil {
+		return err
+	}
+	return a.output.Write(run, runsTable(run))
+}
+
+type listAction struct {
+	cfg    *viper.Viper
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewListAction lists runs newest first, filtered by stack and status
+func NewListAction(cfg *viper.Viper, client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &listAction{cfg: cfg, client: client, output: output}
+}
+
+func (a *listAction) Execute(args []string) error {
+	if len(args) != 0 {
+		return errors.New("list does not take any arguments")
+	}
+
+	page, err := a.client.List(context.Background(), internal.RunFilter{
+		Stack:  a.cfg.GetString(config.ClientStack.String()),
+		Status: internal.RunStatus(a.cfg.GetString(config.ClientStatus.String())),
+		Limit:  a.cfg.GetInt(config.ClientLimit.String()),
+	})
+	if err != nil {
+		return err
+	}
+	return a.output.Write(page, runsTable(page.Runs...))
+}
+
+type getAction struct {
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewGetAction prints the run with the id passed as the only argument
+func NewGetAction(client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &getAction{client: client, output: output}
+}
+
+func (a *getAction) Execute(args []string) error {
+	id, err := runID(args)
+	if err != nil {
+		return err
+	}
+
+	run, err := a.client.Get(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return a.output.Write(run, runsTable(run))
+}
+
+type logsAction struct {
+	cfg      *viper.Viper
+	client   internal.ServerClient
+	out      io.Writer
+	interval time.Duration
+}
+
+// NewLogsAction prints the terraform output of a run, with follow it keeps printing new output until the run is done.
+// The output is printed as is whatever the output format.
+func NewLogsAction(cfg *viper.Viper, client internal.ServerClient) app.ActionAdapter {
+	return &logsAction{cfg: cfg, client: client, out: os.Stdout, interval: followInterval}
+}
+
+func (a *logsAction) Execute(args []string) error {
+	id, err := runID(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	follow := a.cfg.GetBool(config.ClientFollow.String())
+	var offset int64
+	for {
+		// The status is read before the logs so everything a finished run wrote is printed before returning
+		run, err := a.client.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		data, err := a.client.Logs(ctx, id, offset)
+		if err != nil {
+			return err
+		}
+		if _, err := a.out.Write(data); err != nil {
+			return err
+		}
+		offset += int64(len(data))
+
+		if !follow || run.Status.Terminal() {
+			return nil
+		}
+		time.Sleep(a.interval)
+	}
+}
+
+type approveAction struct {
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewApproveAction approves the plan of the run with the id passed as the only argument, the server records the caller
+// its token belongs to as the approver
+func NewApproveAction(client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &approveAction{client: client, output: output}
+}
+
+func (a *approveAction) Execute(args []string) error {
+	id, err := runID(args)
+	if err != nil {
+		return err
+	}
+	run, err := a.client.Approve(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return a.output.Write(run, runsTable(run))
+}
+
+type cancelAction struct {
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewCancelAction cancels the run with the id passed as the only argument, the server records the caller its token
+// belongs to as the canceller
+func NewCancelAction(client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &cancelAction{client: client, output: output}
+}
+
+func (a *cancelAction) Execute(args []string) error {
+	id, err := runID(args)
+	if err != nil {
+		return err
+	}
+	run, err := a.client.Cancel(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	return a.output.Write(run, runsTable(run))
+}
+
+func runID(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("expected a run id as the only argument")
+	}
+	return args[0], nil
+}
+
+// Requester returns the configured requester falling back to the name of the current user
+func Requester(cfg *viper.Viper) (string, error) {
+	if r := cfg.GetString(config.ClientRequester.String()); r != "" {
+		return r, nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("unable to look up the current user, set %s: %w", config.EnvClientRequester.Name, err)
+	}
+	return u.Username, nil
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"errors"
+)
+
+type stacksListAction struct {
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewStacksListAction lists the stacks registered with the server
+func NewStacksListAction(client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &stacksListAction{client: client, output: output}
+}
+
+func (a *stacksListAction) Execute(args []string) error {
+	if len(args) != 0 {
+		return errors.New("list does not take any arguments")
+	}
+
+	stacks, err := a.client.Stacks(context.Background())
+	if err != nil {
+		return err
+	}
+	return a.output.Write(stacks, stacksTable(stacks...))
+}
+
+type stacksGetAction struct {
+	client internal.ServerClient
+	output internal.OutputWriter
+}
+
+// NewStacksGetAction prints the stack named by the only argument
+func NewStacksGetAction(client internal.ServerClient, output internal.OutputWriter) app.ActionAdapter {
+	return &stacksGetAction{client: client, output: output}
+}
+
+func (a *stacksGetAction) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected a stack name as the only argument")
+	}
+
+	stack, err := a.client.Stack(context.Background(), args[0])
+	if err != nil {
+		return err
+	}
+	return a.output.Write(stack, stacksTable(*stack))
+}
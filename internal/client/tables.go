@@ -0,0 +1,58 @@
+package client
+
+import (
+	"deploy-runner/internal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func runsTable(runs ...*internal.Run) internal.Table {
+	table := internal.Table{Columns: []string{"ID", "STACK", "WORKSPACE", "STATUS", "REF", "REQUESTER", "CREATED"}}
+	for _, run := range runs {
+		table.Rows = append(table.Rows, []string{
+			run.ID,
+			run.Stack,
+			run.Workspace,
+			string(run.Status),
+			orNone(run.Ref),
+			run.Requester,
+			run.CreatedAt.Local().Format(time.RFC3339),
+		})
+	}
+	return table
+}
+
+func stacksTable(stacks ...internal.Stack) internal.Table {
+	table := internal.Table{Columns: []string{"NAME", "REPOSITORY", "PATH", "WORKSPACES", "DEPENDS ON", "APPROVERS"}}
+	for _, stack := range stacks {
+		table.Rows = append(table.Rows, []string{
+			stack.Name,
+			stack.Repository,
+			orNone(stack.Path),
+			strings.Join(stack.Workspaces, ","),
+			orNone(strings.Join(stack.DependsOn, ",")),
+			orNone(strings.Join(stack.Approvers, ",")),
+		})
+	}
+	return table
+}
+
+func plansTable(plans ...plan) internal.Table {
+	table := internal.Table{Columns: []string{"RUN", "STACK", "WORKSPACE", "STATUS", "ADD", "CHANGE", "DESTROY"}}
+	for _, p := range plans {
+		add, change, destroy := "-", "-", "-"
+		if p.Summary != nil {
+			add, change, destroy = strconv.Itoa(p.Summary.Add), strconv.Itoa(p.Summary.Change), strconv.Itoa(p.Summary.Destroy)
+		}
+		table.Rows = append(table.Rows, []string{p.RunID, p.Stack, p.Workspace, string(p.Status), add, change, destroy})
+	}
+	return table
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,8 @@
+package commitstatus
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("commitstatus", []config.EnvVar{}, NewSubscriber, NewConfigValidator)
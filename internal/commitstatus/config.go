@@ -0,0 +1,60 @@
+package commitstatus
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"fmt"
+	"github.com/spf13/viper"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const requestTimeout = 10 * time.Second
+
+type repositoryConfig struct {
+	Repository string `mapstructure:"repository"`
+	Provider   string `mapstructure:"provider"`
+	ApiURL     string `mapstructure:"api_url"`
+	Token      string `mapstructure:"token"`
+}
+
+// project returns the path of the repository on its git host, e.g. org/repo
+func (c repositoryConfig) project() string {
+	key := internal.NormalizeRepository(c.Repository)
+	if i := strings.Index(key, "/"); i >= 0 {
+		return key[i+1:]
+	}
+	return ""
+}
+
+// provider creates the reporter of a git host for a repository
+type provider func(c repositoryConfig, client *http.Client) internal.CommitStatusReporter
+
+var providers = map[string]provider{
+	"github": newGithubReporter,
+	"gitlab": newGitlabReporter,
+}
+
+// loadReporters creates a reporter for each configured repository keyed by the normalized repository url
+func loadReporters(cfg *viper.Viper, client *http.Client) (map[string]internal.CommitStatusReporter, error) {
+	var repos []repositoryConfig
+	if err := cfg.UnmarshalKey(config.CommitStatusRepositories.String(), &repos); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", config.CommitStatusRepositories, err)
+	}
+
+	reporters := make(map[string]internal.CommitStatusReporter)
+	for _, c := range repos {
+		newReporter, ok := providers[c.Provider]
+		switch {
+		case c.Repository == "" || c.project() == "":
+			return nil, fmt.Errorf("invalid %s: repository %q is not a repository url", config.CommitStatusRepositories, c.Repository)
+		case !ok:
+			return nil, fmt.Errorf("invalid %s: repository %s has unknown provider %q", config.CommitStatusRepositories, c.Repository, c.Provider)
+		case c.Token == "":
+			return nil, fmt.Errorf("invalid %s: repository %s needs a token", config.CommitStatusRepositories, c.Repository)
+		}
+		reporters[internal.NormalizeRepository(c.Repository)] = newReporter(c, client)
+	}
+	return reporters, nil
+}
@@ -0,0 +1,60 @@
+package commitstatus
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const githubApiURL = "https://api.github.com"
+
+type githubReporter struct {
+	client  *http.Client
+	repoURL string
+	headers map[string]string
+}
+
+func newGithubReporter(c repositoryConfig, client *http.Client) internal.CommitStatusReporter {
+	apiURL := c.ApiURL
+	if apiURL == "" {
+		apiURL = githubApiURL
+	}
+	return &githubReporter{
+		client:  client,
+		repoURL: fmt.Sprintf("%s/repos/%s", strings.TrimSuffix(apiURL, "/"), c.project()),
+		headers: map[string]string{
+			"Authorization": "token " + c.Token,
+			"Accept":        "application/vnd.github.v3+json",
+		},
+	}
+}
+
+type githubStatus struct {
+	State       string `json:"state"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+}
+
+type githubComment struct {
+	Body string `json:"body"`
+}
+
+func (r *githubReporter) SetStatus(ctx context.Context, status internal.CommitStatus) error {
+	state := string(status.State)
+	if status.State == internal.CommitStateRunning {
+		// GitHub has no running state
+		state = string(internal.CommitStatePending)
+	}
+	return postJSON(ctx, r.client, fmt.Sprintf("%s/statuses/%s", r.repoURL, status.SHA), r.headers, githubStatus{
+		State:       state,
+		Context:     status.Context,
+		Description: status.Description,
+	})
+}
+
+func (r *githubReporter) Comment(ctx context.Context, pullRequest int, body string) error {
+	// Pull request comments are issue comments in the GitHub api
+	return postJSON(ctx, r.client, fmt.Sprintf("%s/issues/%d/comments", r.repoURL, pullRequest), r.headers, githubComment{Body: body})
+}
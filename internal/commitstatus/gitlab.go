@@ -0,0 +1,93 @@
+package commitstatus
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const gitlabApiURL = "https://gitlab.com/api/v4"
+
+type gitlabReporter struct {
+	client     *http.Client
+	projectURL string
+	headers    map[string]string
+
+	mu sync.Mutex
+	// states holds the last state set for each sha and context, GitLab rejects a status that does not change the
+	// state. order is used to forget the oldest state once maxReported states are held.
+	states map[string]string
+	order  []string
+}
+
+func newGitlabReporter(c repositoryConfig, client *http.Client) internal.CommitStatusReporter {
+	apiURL := c.ApiURL
+	if apiURL == "" {
+		apiURL = gitlabApiURL
+	}
+	return &gitlabReporter{
+		client: client,
+		// Projects are addressed by their url encoded path so subgroups work without looking up the project id
+		projectURL: fmt.Sprintf("%s/projects/%s", strings.TrimSuffix(apiURL, "/"), url.PathEscape(c.project())),
+		headers:    map[string]string{"PRIVATE-TOKEN": c.Token},
+		states:     make(map[string]string),
+	}
+}
+
+type gitlabStatus struct {
+	State       string `json:"state"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type gitlabNote struct {
+	Body string `json:"body"`
+}
+
+func (r *gitlabReporter) SetStatus(ctx context.Context, status internal.CommitStatus) error {
+	state := string(status.State)
+	if status.State == internal.CommitStateFailure {
+		state = "failed"
+	}
+
+	key := status.SHA + "/" + status.Context
+	r.mu.Lock()
+	last := r.states[key]
+	r.mu.Unlock()
+	// A running status can't go back to pending either, a run awaiting approval keeps showing as running
+	if state == last || (state == string(internal.CommitStatePending) && last == string(internal.CommitStateRunning)) {
+		return nil
+	}
+
+	err := postJSON(ctx, r.client, fmt.Sprintf("%s/statuses/%s", r.projectURL, status.SHA), r.headers, gitlabStatus{
+		State:       state,
+		Name:        status.Context,
+		Description: status.Description,
+	})
+	if err != nil {
+		return err
+	}
+	r.remember(key, state)
+	return nil
+}
+
+func (r *gitlabReporter) remember(key, state string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.states[key]; !ok {
+		r.order = append(r.order, key)
+	}
+	r.states[key] = state
+	if len(r.order) > maxReported {
+		delete(r.states, r.order[0])
+		r.order = r.order[1:]
+	}
+}
+
+func (r *gitlabReporter) Comment(ctx context.Context, pullRequest int, body string) error {
+	return postJSON(ctx, r.client, fmt.Sprintf("%s/merge_requests/%d/notes", r.projectURL, pullRequest), r.headers, gitlabNote{Body: body})
+}
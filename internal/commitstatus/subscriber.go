@@ -0,0 +1,282 @@
+package commitstatus
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	contextPrefix = "deploy-runner"
+
+	// GitHub rejects descriptions longer than 140 characters
+	maxDescription = 140
+
+	// queueSize is the number of reports that can wait to be sent before Handle fails
+	queueSize = 256
+
+	// maxReported is the number of reported event ids kept to skip events handed over again
+	maxReported = 4096
+)
+
+var (
+	fullSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+	errQueueFull = errors.New("commit status queue is full")
+)
+
+type subscriberOut struct {
+	fx.Out
+	Subscriber internal.EventSubscriber `group:"eventSubscribers"`
+	Service    app.Service              `group:"services"`
+}
+
+// report is a commit status and optional pull request comment waiting to be sent to a git host
+type report struct {
+	eventID     string
+	reporter    internal.CommitStatusReporter
+	runID       string
+	status      internal.CommitStatus
+	pullRequest int
+	comment     string
+}
+
+// subscriber reports run lifecycle events of configured repositories to their git host. Handle only queues a report
+// which is sent in the background so a slow git host never holds up publishing events. Reporting is best effort,
+// failures are logged rather than retried.
+type subscriber struct {
+	log       internal.BackgroundLog
+	reporters map[string]internal.CommitStatusReporter
+	reports   chan report
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu sync.Mutex
+	// reported holds the ids of the last queued events so an event handed over again is not reported twice, order
+	// is used to forget the oldest id once maxReported ids are held
+	reported map[string]bool
+	order    []string
+}
+
+func NewSubscriber(cfg *viper.Viper, log internal.BackgroundLog) (subscriberOut, error) {
+	reporters, err := loadReporters(cfg, &http.Client{Timeout: requestTimeout})
+	if err != nil {
+		return subscriberOut{}, err
+	}
+	s := newSubscriber(log, reporters)
+	return subscriberOut{Subscriber: s, Service: s}, nil
+}
+
+func newSubscriber(log internal.BackgroundLog, reporters map[string]internal.CommitStatusReporter) *subscriber {
+	return &subscriber{
+		log:       log.ChildLog("commit-status"),
+		reporters: reporters,
+		reports:   make(chan report, queueSize),
+		reported:  make(map[string]bool),
+	}
+}
+
+func (s *subscriber) Name() string {
+	return "commit-status"
+}
+
+func (s *subscriber) Start(ctx context.Context) error {
+	sendCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go s.send(sendCtx)
+	return nil
+}
+
+func (s *subscriber) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *subscriber) Disabled() bool {
+	return len(s.reporters) == 0
+}
+
+func (s *subscriber) Handle(ctx context.Context, event internal.Event) error {
+	run := event.Run
+	if run == nil || !statusChange(event) {
+		return nil
+	}
+	reporter, ok := s.reporters[internal.NormalizeRepository(run.Repository)]
+	if !ok {
+		return nil
+	}
+	sha := commitSHA(run)
+	if sha == "" {
+		return nil
+	}
+
+	r := report{
+		eventID:  event.ID,
+		reporter: reporter,
+		runID:    run.ID,
+		status: internal.CommitStatus{
+			SHA:         sha,
+			Context:     fmt.Sprintf("%s/%s/%s", contextPrefix, run.Stack, run.Workspace),
+			State:       commitState(run.Status),
+			Description: truncate(describe(run), maxDescription),
+		},
+	}
+	if run.PullRequest > 0 && (run.Status.Terminal() || run.Status == internal.RunStatusAwaitingApproval) {
+		r.pullRequest = run.PullRequest
+		r.comment = comment(run)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reported[event.ID] {
+		return nil
+	}
+	select {
+	case s.reports <- r:
+	default:
+		// The event is handed over again later, by then the git host may have caught up
+		return errQueueFull
+	}
+	s.remember(event.ID)
+	return nil
+}
+
+// remember records an event as reported, the caller holds mu
+func (s *subscriber) remember(eventID string) {
+	s.reported[eventID] = true
+	s.order = append(s.order, eventID)
+	if len(s.order) > maxReported {
+		delete(s.reported, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// send sends queued reports in the order their events were published
+func (s *subscriber) send(ctx context.Context) {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case r := <-s.reports:
+			s.sendReport(ctx, r)
+		}
+	}
+}
+
+func (s *subscriber) sendReport(ctx context.Context, r report) {
+	if err := r.reporter.SetStatus(ctx, r.status); err != nil {
+		s.log.Errw(err, "Unable to set commit status", "run_id", r.runID, "event_id", r.eventID, "sha", r.status.SHA, "state", r.status.State)
+	}
+
+	if r.pullRequest > 0 {
+		if err := r.reporter.Comment(ctx, r.pullRequest, r.comment); err != nil {
+			s.log.Errw(err, "Unable to comment on pull request", "run_id", r.runID, "event_id", r.eventID, "pull_request", r.pullRequest)
+		}
+	}
+}
+
+// statusChange reports if an event is recorded for a run entering its status, a run settled on startup only has the
+// recovery event
+func statusChange(event internal.Event) bool {
+	switch event.Type {
+	case internal.RunStatusEventType(event.Run.Status), internal.EventRunInterrupted, internal.EventRunNeedsAttention:
+		return true
+	}
+	return false
+}
+
+// commitSHA returns the commit a run is for, runs only know their commit once it has been checked out unless they
+// were requested for a commit
+func commitSHA(run *internal.Run) string {
+	if run.CommitSHA != "" {
+		return run.CommitSHA
+	}
+	if fullSHA.MatchString(run.Ref) {
+		return run.Ref
+	}
+	return ""
+}
+
+func commitState(status internal.RunStatus) internal.CommitState {
+	switch status {
+	case internal.RunStatusPlanned, internal.RunStatusApplied:
+		return internal.CommitStateSuccess
+	case internal.RunStatusQueued, internal.RunStatusAwaitingApproval:
+		return internal.CommitStatePending
+	case internal.RunStatusPlanning, internal.RunStatusApplying:
+		return internal.CommitStateRunning
+	default:
+		return internal.CommitStateFailure
+	}
+}
+
+func describe(run *internal.Run) string {
+	switch run.Status {
+	case internal.RunStatusQueued:
+		return "Queued"
+	case internal.RunStatusPlanning:
+		return "Planning"
+	case internal.RunStatusPlanned:
+		return "Plan: " + planSummary(run)
+	case internal.RunStatusAwaitingApproval:
+		return "Waiting for approval, plan: " + planSummary(run)
+	case internal.RunStatusApplying:
+		return "Applying"
+	case internal.RunStatusApplied:
+		return "Applied: " + planSummary(run)
+	case internal.RunStatusCancelled, internal.RunStatusCancelledDuringApply:
+		return fmt.Sprintf("%s by %s", strings.ReplaceAll(string(run.Status), "_", " "), run.CancelledBy)
+	default:
+		return fmt.Sprintf("%s: %s", strings.ReplaceAll(string(run.Status), "_", " "), run.Error)
+	}
+}
+
+func planSummary(run *internal.Run) string {
+	if !run.HasChanges || run.Plan == nil {
+		return "no changes"
+	}
+	return fmt.Sprintf("%d to add, %d to change, %d to destroy", run.Plan.Add, run.Plan.Change, run.Plan.Destroy)
+}
+
+func comment(run *internal.Run) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### deploy-runner: `%s` (`%s`) %s\n\n", run.Stack, run.Workspace, strings.ReplaceAll(string(run.Status), "_", " "))
+	fmt.Fprintf(&b, "Commit: `%s`\nRun: `%s`\n\n", commitSHA(run), run.ID)
+
+	switch run.Status {
+	case internal.RunStatusPlanned, internal.RunStatusAwaitingApproval, internal.RunStatusApplied:
+		fmt.Fprintf(&b, "**Plan:** %s\n", planSummary(run))
+	default:
+		if run.Error != "" {
+			fmt.Fprintf(&b, "**Error:** %s\n", run.Error)
+		}
+		if run.OutputTail != "" {
+			fmt.Fprintf(&b, "\n<details><summary>Output</summary>\n\n```\n%s\n```\n</details>\n", strings.TrimSpace(run.OutputTail))
+		}
+	}
+	return b.String()
+}
+
+// truncate shortens s to max characters, git hosts count characters rather than bytes
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max-3]) + "..."
+}
@@ -0,0 +1,203 @@
+package commitstatus
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"encoding/json"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testSHA = "0123456789abcdef0123456789abcdef01234567"
+
+func TestSubscriber(t *testing.T) {
+	t.Run("TestGithubPlan", testGithubPlan)
+	t.Run("TestGitlabFailure", testGitlabFailure)
+	t.Run("TestUnknownRepository", testUnknownRepository)
+	t.Run("TestReportsEventOnce", testReportsEventOnce)
+	t.Run("TestGitlabStates", testGitlabStates)
+	t.Run("TestTruncate", testTruncate)
+}
+
+type hostRequest struct {
+	Path   string
+	Header http.Header
+	Body   map[string]interface{}
+}
+
+// gitHost is a stand-in of a git host api recording the requests made to it
+type gitHost struct {
+	mu       sync.Mutex
+	requests []hostRequest
+}
+
+func (h *gitHost) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := ioutil.ReadAll(r.Body)
+	req := hostRequest{Path: r.URL.EscapedPath(), Header: r.Header}
+	_ = json.Unmarshal(data, &req.Body)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests = append(h.requests, req)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// received waits for the subscriber to send count requests and returns them
+func (h *gitHost) received(t *testing.T, count int) []hostRequest {
+	var requests []hostRequest
+	assert.Eventually(t, func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		requests = append([]hostRequest{}, h.requests...)
+		return len(requests) >= count
+	}, time.Second, time.Millisecond)
+	return requests
+}
+
+func newTestSubscriber(t *testing.T, repos []map[string]interface{}) *subscriber {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.CommitStatusRepositories.String(), repos)
+
+	reporters, err := loadReporters(cfg, http.DefaultClient)
+	assert.NoError(t, err)
+	s := newSubscriber(logging.NewBackgroundLog(cfg), reporters)
+	assert.NoError(t, s.Start(context.Background()))
+	t.Cleanup(func() {
+		assert.NoError(t, s.Stop(context.Background()))
+	})
+	return s
+}
+
+func handle(t *testing.T, s *subscriber, run *internal.Run) {
+	assert.NoError(t, s.Handle(context.Background(), internal.NewEvent(internal.RunStatusEventType(run.Status), run, "")))
+}
+
+func testGithubPlan(t *testing.T) {
+	host := &gitHost{}
+	server := httptest.NewServer(host)
+	defer server.Close()
+	s := newTestSubscriber(t, []map[string]interface{}{{"repository": "git@github.com:example/infra.git", "provider": "github", "api_url": server.URL, "token": "gh-token"}})
+
+	run := internal.NewRun(internal.DeployRequest{Stack: "network", Workspace: "dev", Ref: testSHA, Requester: "github:alice", PlanOnly: true, PullRequest: 7}, internal.Stack{Repository: "https://github.com/example/infra.git"})
+	handle(t, s, run)
+	run.Status = internal.RunStatusPlanned
+	run.HasChanges = true
+	run.Plan = &internal.PlanSummary{Add: 1, Change: 2}
+	handle(t, s, run)
+
+	requests := host.received(t, 3)
+	if !assert.Len(t, requests, 3) {
+		return
+	}
+	assert.Equal(t, "/repos/example/infra/statuses/"+testSHA, requests[0].Path)
+	assert.Equal(t, "token gh-token", requests[0].Header.Get("Authorization"))
+	assert.Equal(t, "pending", requests[0].Body["state"])
+	assert.Equal(t, "deploy-runner/network/dev", requests[0].Body["context"])
+
+	assert.Equal(t, "success", requests[1].Body["state"])
+	assert.Equal(t, "Plan: 1 to add, 2 to change, 0 to destroy", requests[1].Body["description"])
+
+	assert.Equal(t, "/repos/example/infra/issues/7/comments", requests[2].Path)
+	assert.Contains(t, requests[2].Body["body"], "1 to add, 2 to change, 0 to destroy")
+}
+
+func testGitlabFailure(t *testing.T) {
+	host := &gitHost{}
+	server := httptest.NewServer(host)
+	defer server.Close()
+	s := newTestSubscriber(t, []map[string]interface{}{{"repository": "https://gitlab.com/example/platform/infra.git", "provider": "gitlab", "api_url": server.URL + "/api/v4", "token": "gl-token"}})
+
+	run := internal.NewRun(internal.DeployRequest{Stack: "dns", Ref: "main", Requester: "gitlab:carol", PullRequest: 3}, internal.Stack{Repository: "https://gitlab.com/example/platform/infra.git"})
+	// The commit is not known until the run has been checked out
+	handle(t, s, run)
+	assert.Empty(t, host.received(t, 0))
+
+	run.CommitSHA = testSHA
+	run.Status = internal.RunStatusFailed
+	run.Error = "plan phase failed: exit status 1"
+	run.OutputTail = "Error: Unsupported argument"
+	handle(t, s, run)
+
+	requests := host.received(t, 2)
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	assert.Equal(t, "/api/v4/projects/example%2Fplatform%2Finfra/statuses/"+testSHA, requests[0].Path)
+	assert.Equal(t, "gl-token", requests[0].Header.Get("PRIVATE-TOKEN"))
+	assert.Equal(t, "failed", requests[0].Body["state"])
+	assert.Equal(t, "deploy-runner/dns/default", requests[0].Body["name"])
+	assert.Equal(t, "failed: plan phase failed: exit status 1", requests[0].Body["description"])
+
+	assert.Equal(t, "/api/v4/projects/example%2Fplatform%2Finfra/merge_requests/3/notes", requests[1].Path)
+	assert.Contains(t, requests[1].Body["body"], "Error: Unsupported argument")
+}
+
+func testUnknownRepository(t *testing.T) {
+	s := newTestSubscriber(t, nil)
+	run := internal.NewRun(internal.DeployRequest{Stack: "dns", Ref: testSHA, Requester: "ci"}, internal.Stack{Repository: "https://gitlab.com/example/infra.git"})
+	handle(t, s, run)
+
+	cfg := viper.New()
+	cfg.Set(config.CommitStatusRepositories.String(), []map[string]interface{}{{"repository": "https://gitlab.com/example/infra.git", "provider": "bitbucket", "token": "x"}})
+	_, err := loadReporters(cfg, http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func testReportsEventOnce(t *testing.T) {
+	host := &gitHost{}
+	server := httptest.NewServer(host)
+	defer server.Close()
+	s := newTestSubscriber(t, []map[string]interface{}{{"repository": "https://github.com/example/infra.git", "provider": "github", "api_url": server.URL, "token": "gh-token"}})
+
+	run := internal.NewRun(internal.DeployRequest{Stack: "network", Ref: testSHA, Requester: "ci"}, internal.Stack{Repository: "https://github.com/example/infra.git"})
+	event := internal.NewEvent(internal.RunStatusEventType(run.Status), run, "")
+	// The relay hands an event over again when another subscriber failed to handle it
+	assert.NoError(t, s.Handle(context.Background(), event))
+	assert.NoError(t, s.Handle(context.Background(), event))
+
+	run.Status = internal.RunStatusPlanning
+	handle(t, s, run)
+
+	requests := host.received(t, 2)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "pending", requests[1].Body["state"])
+	assert.Equal(t, "Planning", requests[1].Body["description"])
+}
+
+func testGitlabStates(t *testing.T) {
+	host := &gitHost{}
+	server := httptest.NewServer(host)
+	defer server.Close()
+	s := newTestSubscriber(t, []map[string]interface{}{{"repository": "https://gitlab.com/example/infra.git", "provider": "gitlab", "api_url": server.URL, "token": "gl-token"}})
+
+	run := internal.NewRun(internal.DeployRequest{Stack: "dns", Ref: testSHA, Requester: "ci"}, internal.Stack{Repository: "https://gitlab.com/example/infra.git"})
+	for _, status := range []internal.RunStatus{internal.RunStatusQueued, internal.RunStatusPlanning, internal.RunStatusAwaitingApproval, internal.RunStatusApplying, internal.RunStatusApplied} {
+		run.Status = status
+		handle(t, s, run)
+	}
+
+	// GitLab rejects a status that does not change the state, which includes going from running back to pending
+	requests := host.received(t, 3)
+	if !assert.Len(t, requests, 3) {
+		return
+	}
+	assert.Equal(t, "pending", requests[0].Body["state"])
+	assert.Equal(t, "running", requests[1].Body["state"])
+	assert.Equal(t, "Planning", requests[1].Body["description"])
+	assert.Equal(t, "success", requests[2].Body["state"])
+}
+
+func testTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 5))
+	assert.Equal(t, "äöü...", truncate("äöüäöüäöü", 6))
+}
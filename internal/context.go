@@ -1 +1,36 @@
 package internal
+
+import (
+	"context"
+	"io"
+)
+
+type contextKey int
+
+const (
+	runOutputKey contextKey = iota
+	callerKey
+)
+
+// WithRunOutput returns a context that has the terraform output of a run executed with it written to w as well as to
+// the run's log
+func WithRunOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, runOutputKey, w)
+}
+
+// RunOutput returns the writer set with WithRunOutput, nil when none was set
+func RunOutput(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(runOutputKey).(io.Writer)
+	return w
+}
+
+// WithCaller returns a context that has the name of the authenticated api caller set
+func WithCaller(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, callerKey, name)
+}
+
+// Caller returns the name set with WithCaller, empty when the caller was not authenticated
+func Caller(ctx context.Context) string {
+	name, _ := ctx.Value(callerKey).(string)
+	return name
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies what happened in an Event
+type EventType string
+
+// Run lifecycle events, one is recorded for every status change of a run
+const (
+	EventRunQueued           EventType = "run.queued"
+	EventRunPlanning         EventType = "run.planning"
+	EventRunPlanned          EventType = "run.planned"
+	EventRunAwaitingApproval EventType = "run.awaiting_approval"
+	EventRunApplying         EventType = "run.applying"
+	EventRunApplied          EventType = "run.applied"
+	EventRunFailed           EventType = "run.failed"
+	EventRunCancelled        EventType = "run.cancelled"
+)
+
+const (
+	// EventRunRequeued is emitted on startup for a run that was still queued when the runner stopped
+	EventRunRequeued EventType = "run.recovered.requeued"
+
+	// EventRunInterrupted is emitted on startup for a run that was planning when the runner stopped
+	EventRunInterrupted EventType = "run.recovered.interrupted"
+
+	// EventRunNeedsAttention is emitted on startup for a run that was applying when the runner stopped
+	EventRunNeedsAttention EventType = "run.recovered.needs_attention"
+)
+
+// Event describes something that happened to a run
+type Event struct {
+	ID      string    `json:"id"`
+	Type    EventType `json:"type"`
+	Time    time.Time `json:"time"`
+	Run     *Run      `json:"run,omitempty"`
+	Message string    `json:"message,omitempty"`
+
+	// PreviousStatus is the status the run was in before a lifecycle event
+	PreviousStatus RunStatus `json:"previous_status,omitempty"`
+}
+
+// RunStatusEventType returns the lifecycle event type of a run entering status. Every kind of failure is reported as
+// EventRunFailed and both kinds of cancellation as EventRunCancelled, the run in the event has the exact status.
+func RunStatusEventType(status RunStatus) EventType {
+	switch status {
+	case RunStatusQueued:
+		return EventRunQueued
+	case RunStatusPlanning:
+		return EventRunPlanning
+	case RunStatusPlanned:
+		return EventRunPlanned
+	case RunStatusAwaitingApproval:
+		return EventRunAwaitingApproval
+	case RunStatusApplying:
+		return EventRunApplying
+	case RunStatusApplied:
+		return EventRunApplied
+	case RunStatusCancelled, RunStatusCancelledDuringApply:
+		return EventRunCancelled
+	default:
+		return EventRunFailed
+	}
+}
+
+// NewEvent creates an event for a run, the run is copied so later changes to it are not reflected in the event
+func NewEvent(eventType EventType, run *Run, message string) Event {
+	e := Event{
+		ID:      NewRunID(),
+		Type:    eventType,
+		Time:    time.Now().UTC(),
+		Message: message,
+	}
+	if run != nil {
+		copied := *run
+		e.Run = &copied
+	}
+	return e
+}
+
+// EventSubscriber receives published events. Implementations are provided to fx in the "eventSubscribers" group.
+type EventSubscriber interface {
+	Name() string
+	Handle(ctx context.Context, event Event) error
+}
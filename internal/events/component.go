@@ -0,0 +1,8 @@
+package events
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("events", []config.EnvVar{}, NewLogSubscriber, NewOutboxRelay)
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"go.uber.org/fx"
+)
+
+type subscriberOut struct {
+	fx.Out
+	Subscriber internal.EventSubscriber `group:"eventSubscribers"`
+}
+
+// logSubscriber writes every event to the background log so there is always a record of them
+type logSubscriber struct {
+	log internal.BackgroundLog
+}
+
+func NewLogSubscriber(log internal.BackgroundLog) subscriberOut {
+	return subscriberOut{Subscriber: &logSubscriber{log: log.ChildLog("events")}}
+}
+
+func (s *logSubscriber) Name() string {
+	return "log"
+}
+
+func (s *logSubscriber) Handle(ctx context.Context, event internal.Event) error {
+	fields := []interface{}{"event_id", event.ID, "event_type", event.Type, "message", event.Message}
+	if event.Run != nil {
+		fields = append(fields, "run_id", event.Run.ID, "stack", event.Run.Stack, "workspace", event.Run.Workspace, "status", event.Run.Status)
+	}
+	s.log.Infow("Event published", fields...)
+	return nil
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"go.uber.org/fx"
+	"sync"
+	"time"
+)
+
+const (
+	relayInterval  = time.Second
+	relayBatchSize = 100
+)
+
+type serviceOut struct {
+	fx.Out
+	Service app.Service `group:"services"`
+}
+
+type subscribersContainer struct {
+	fx.In
+	Subscribers []internal.EventSubscriber `group:"eventSubscribers"`
+}
+
+// relay hands the events recorded in the outbox by the run store to every subscriber. Each subscriber that handled an
+// event is recorded in the outbox so a failing subscriber only gets the event again itself, the event is removed from
+// the outbox once every subscriber has handled it.
+type relay struct {
+	log         internal.BackgroundLog
+	outbox      internal.EventOutbox
+	subscribers []internal.EventSubscriber
+	interval    time.Duration
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func NewOutboxRelay(log internal.BackgroundLog, outbox internal.EventOutbox, c subscribersContainer) serviceOut {
+	return serviceOut{Service: newRelay(log, outbox, c.Subscribers)}
+}
+
+func newRelay(log internal.BackgroundLog, outbox internal.EventOutbox, subscribers []internal.EventSubscriber) *relay {
+	return &relay{
+		log:         log.ChildLog("outbox"),
+		outbox:      outbox,
+		subscribers: subscribers,
+		interval:    relayInterval,
+	}
+}
+
+func (r *relay) Start(ctx context.Context) error {
+	relayCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go r.run(relayCtx)
+	return nil
+}
+
+func (r *relay) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	r.wg.Wait()
+	return nil
+}
+
+func (r *relay) Disabled() bool {
+	return false
+}
+
+func (r *relay) run(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		r.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// drain hands pending events to the subscribers in order. A subscriber that fails is skipped for the rest of the drain
+// so it never sees events out of order, the other subscribers carry on with the later events.
+func (r *relay) drain(ctx context.Context) {
+	failed := make(map[string]bool)
+	var after uint64
+	for ctx.Err() == nil {
+		entries, err := r.outbox.Pending(ctx, after, relayBatchSize)
+		if err != nil {
+			r.log.Err(err, "Unable to read outbox")
+			return
+		}
+
+		for _, entry := range entries {
+			after = entry.Sequence
+			if !r.deliver(ctx, entry, failed) {
+				continue
+			}
+			if err := r.outbox.Ack(ctx, entry.Sequence); err != nil {
+				r.log.Errw(err, "Unable to remove published event from outbox", "event_id", entry.Event.ID)
+			}
+		}
+
+		if len(entries) < relayBatchSize || (len(r.subscribers) > 0 && len(failed) == len(r.subscribers)) {
+			return
+		}
+	}
+}
+
+// deliver hands the event to every subscriber that has not handled it yet and returns true when all of them have
+func (r *relay) deliver(ctx context.Context, entry internal.OutboxEntry, failed map[string]bool) bool {
+	delivered := make(map[string]bool, len(entry.Delivered))
+	for _, name := range entry.Delivered {
+		delivered[name] = true
+	}
+
+	done := true
+	for _, s := range r.subscribers {
+		name := s.Name()
+		if delivered[name] {
+			continue
+		}
+		if failed[name] {
+			done = false
+			continue
+		}
+
+		if err := s.Handle(ctx, entry.Event); err != nil {
+			r.log.Errw(err, "Event subscriber failed, the event will be retried", "subscriber", name, "event_id", entry.Event.ID, "event_type", entry.Event.Type)
+			failed[name] = true
+			done = false
+			continue
+		}
+		if err := r.outbox.MarkDelivered(ctx, entry.Sequence, name); err != nil {
+			// The subscriber gets the event again on the next drain, it is skipped until then to keep events in order
+			r.log.Errw(err, "Unable to record event delivery", "subscriber", name, "event_id", entry.Event.ID)
+			failed[name] = true
+			done = false
+		}
+	}
+	return done
+}
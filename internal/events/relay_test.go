@@ -0,0 +1,158 @@
+package events
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutboxRelay(t *testing.T) {
+	t.Run("TestPublishesInOrder", testPublishesInOrder)
+	t.Run("TestRetriesFailedEvent", testRetriesFailedEvent)
+	t.Run("TestRetriesOnlyFailedSubscriber", testRetriesOnlyFailedSubscriber)
+}
+
+type memOutbox struct {
+	mu        sync.Mutex
+	next      uint64
+	entries   []internal.OutboxEntry
+	delivered map[uint64][]string
+}
+
+func (o *memOutbox) add(eventType internal.EventType) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.next++
+	o.entries = append(o.entries, internal.OutboxEntry{Sequence: o.next, Event: internal.NewEvent(eventType, nil, "")})
+}
+
+func (o *memOutbox) Pending(ctx context.Context, after uint64, limit int) ([]internal.OutboxEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	entries := make([]internal.OutboxEntry, 0)
+	for _, e := range o.entries {
+		if e.Sequence > after && len(entries) < limit {
+			e.Delivered = append([]string{}, o.delivered[e.Sequence]...)
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (o *memOutbox) MarkDelivered(ctx context.Context, sequence uint64, subscriber string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.delivered == nil {
+		o.delivered = make(map[uint64][]string)
+	}
+	o.delivered[sequence] = append(o.delivered[sequence], subscriber)
+	return nil
+}
+
+func (o *memOutbox) Ack(ctx context.Context, sequence uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i, e := range o.entries {
+		if e.Sequence == sequence {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (o *memOutbox) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+type recordingSubscriber struct {
+	mu       sync.Mutex
+	name     string
+	failures int
+	handled  []internal.EventType
+}
+
+func (s *recordingSubscriber) Name() string {
+	if s.name == "" {
+		return "recording"
+	}
+	return s.name
+}
+
+func (s *recordingSubscriber) Handle(ctx context.Context, event internal.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("unavailable")
+	}
+	s.handled = append(s.handled, event.Type)
+	return nil
+}
+
+func (s *recordingSubscriber) events() []internal.EventType {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]internal.EventType{}, s.handled...)
+}
+
+func startRelay(t *testing.T, outbox internal.EventOutbox, subscribers ...internal.EventSubscriber) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	log := logging.NewBackgroundLog(cfg)
+
+	r := newRelay(log, outbox, subscribers)
+	r.interval = time.Millisecond
+	assert.NoError(t, r.Start(context.Background()))
+	t.Cleanup(func() {
+		assert.NoError(t, r.Stop(context.Background()))
+	})
+}
+
+func testPublishesInOrder(t *testing.T) {
+	outbox := &memOutbox{}
+	outbox.add(internal.EventRunQueued)
+	outbox.add(internal.EventRunPlanning)
+	subscriber := &recordingSubscriber{}
+	startRelay(t, outbox, subscriber)
+
+	outbox.add(internal.EventRunPlanned)
+	assert.Eventually(t, func() bool { return outbox.len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, []internal.EventType{internal.EventRunQueued, internal.EventRunPlanning, internal.EventRunPlanned}, subscriber.events())
+}
+
+func testRetriesFailedEvent(t *testing.T) {
+	outbox := &memOutbox{}
+	outbox.add(internal.EventRunQueued)
+	outbox.add(internal.EventRunPlanning)
+	subscriber := &recordingSubscriber{failures: 2}
+	startRelay(t, outbox, subscriber)
+
+	assert.Eventually(t, func() bool { return outbox.len() == 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, []internal.EventType{internal.EventRunQueued, internal.EventRunPlanning}, subscriber.events())
+}
+
+func testRetriesOnlyFailedSubscriber(t *testing.T) {
+	outbox := &memOutbox{}
+	outbox.add(internal.EventRunQueued)
+	outbox.add(internal.EventRunPlanning)
+	healthy := &recordingSubscriber{name: "healthy"}
+	failing := &recordingSubscriber{name: "failing", failures: 5}
+	startRelay(t, outbox, healthy, failing)
+
+	// The healthy subscriber is not held up by the failing one and never sees an event twice
+	assert.Eventually(t, func() bool { return outbox.len() == 0 }, time.Second, time.Millisecond)
+	expected := []internal.EventType{internal.EventRunQueued, internal.EventRunPlanning}
+	assert.Equal(t, expected, healthy.events())
+	assert.Equal(t, expected, failing.events())
+}
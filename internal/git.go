@@ -1,5 +1,27 @@
 package internal
 
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
 type GitClient interface {
-	Clone(repo string) error
-}
\ No newline at end of file
+	// Clone checks out ref of repo into dir and returns the commit SHA that was checked out. ref can be a branch, tag
+	// or full commit SHA, an empty ref checks out the default branch.
+	Clone(ctx context.Context, repo, ref, dir string) (string, error)
+}
+
+// NormalizeRepository reduces the different urls of a repository to host/path so https, ssh and scp style urls
+// compare equal, e.g. https://github.com/org/repo.git and git@github.com:org/repo both become github.com/org/repo
+func NormalizeRepository(repository string) string {
+	repository = strings.TrimSpace(repository)
+	if u, err := url.Parse(repository); err == nil && u.Scheme != "" && u.Host != "" {
+		repository = u.Hostname() + u.Path
+	} else if at := strings.Index(repository, "@"); at >= 0 {
+		// scp style user@host:path
+		repository = strings.Replace(repository[at+1:], ":", "/", 1)
+	}
+	repository = strings.TrimSuffix(strings.TrimSuffix(repository, "/"), ".git")
+	return strings.ToLower(repository)
+}
@@ -1,34 +1,72 @@
 package git
 
 import (
+	"context"
+	"deploy-runner/internal"
 	"fmt"
-	"github.com/go-git/go-git/v5" // with go modules enabled (GO111MODULE=on or outside GOPATH)
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"regexp"
 )
 
+var commitShaRegexp = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
 type client struct {
+	log internal.BackgroundLog
+}
+
+func NewClient(log internal.BackgroundLog) internal.GitClient {
+	return &client{log: log.ChildLog("git")}
 }
 
-func (c *client) Clone(url, dir string) error {
-	// Clone the given repository to the given directory
-	Info("git clone %s %s", url, dir)
-	r, err := git.PlainClone(dir, false, &git.CloneOptions{
-		URL: url,
-	})
+func (c *client) Clone(ctx context.Context, url, ref, dir string) (string, error) {
+	c.log.Debugw("Cloning repository", "repository", url, "ref", ref, "dir", dir)
+
+	if commitShaRegexp.MatchString(ref) {
+		return c.cloneCommit(ctx, url, ref, dir)
+	}
 
-	CheckIfError(err)
+	opts := &git.CloneOptions{
+		URL:          url,
+		Depth:        1,
+		SingleBranch: true,
+	}
+	if ref != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(ref)
+	}
 
-	// ... retrieving the commit being pointed by HEAD
-	Info("git show-ref --head HEAD")
-	ref, err := r.Head()
-	CheckIfError(err)
-	fmt.Println(ref.Hash())
+	r, err := git.PlainCloneContext(ctx, dir, false, opts)
+	if err != nil && ref != "" {
+		// Not a branch, try it as a tag before giving up
+		opts.ReferenceName = plumbing.NewTagReferenceName(ref)
+		r, err = git.PlainCloneContext(ctx, dir, false, opts)
+	}
+	if err != nil {
+		return "", fmt.Errorf("unable to clone %s at %s: %w", url, ref, err)
+	}
 
-	_, err = r.Worktree()
-	CheckIfError(err)
-	return err
+	head, err := r.Head()
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve HEAD of %s: %w", url, err)
+	}
+
+	return head.Hash().String(), nil
 }
 
-func (c *client) RemoveClone(dir string) error {
+func (c *client) cloneCommit(ctx context.Context, url, sha, dir string) (string, error) {
+	r, err := git.PlainCloneContext(ctx, dir, false, &git.CloneOptions{URL: url})
+	if err != nil {
+		return "", fmt.Errorf("unable to clone %s: %w", url, err)
+	}
+
+	wt, err := r.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("unable to open worktree of %s: %w", url, err)
+	}
+
+	if err := wt.Checkout(&git.CheckoutOptions{Hash: plumbing.NewHash(sha)}); err != nil {
+		return "", fmt.Errorf("unable to checkout %s of %s: %w", sha, url, err)
+	}
 
-	return nil
+	return sha, nil
 }
@@ -1 +1,8 @@
 package git
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("git", []config.EnvVar{}, NewClient)
@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrHookDeliveryNotFound is returned by a GitHookStore when a webhook delivery was never recorded
+var ErrHookDeliveryNotFound = errors.New("git webhook delivery not found")
+
+// GitHookDelivery is a webhook from a git host that submitted runs
+type GitHookDelivery struct {
+	// ID is the id the git host sent the webhook with, a redelivered webhook has the same id
+	ID         string    `json:"id"`
+	ReceivedAt time.Time `json:"received_at"`
+	RunIDs     []string  `json:"run_ids"`
+}
+
+// GitHookStore remembers git host webhook deliveries so a redelivered webhook does not submit its runs again
+type GitHookStore interface {
+	// SaveHookDelivery records a delivery
+	SaveHookDelivery(ctx context.Context, delivery *GitHookDelivery) error
+
+	// GetHookDelivery loads a delivery by the id the git host sent it with
+	GetHookDelivery(ctx context.Context, id string) (*GitHookDelivery, error)
+}
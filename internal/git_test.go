@@ -0,0 +1,18 @@
+package internal
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNormalizeRepository(t *testing.T) {
+	for _, u := range []string{
+		"https://github.com/Example/Infra.git",
+		"https://token@github.com/example/infra",
+		"ssh://git@github.com/example/infra.git",
+		"git@github.com:example/infra.git",
+		"github.com/example/infra/",
+	} {
+		assert.Equal(t, "github.com/example/infra", NormalizeRepository(u), u)
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrGroupNotFound is returned by a GroupStore when a deploy group does not exist
+var ErrGroupNotFound = errors.New("deploy group not found")
+
+// GroupStatus is the state of a deploy group
+type GroupStatus string
+
+const (
+	GroupStatusRunning   GroupStatus = "running"
+	GroupStatusSucceeded GroupStatus = "succeeded"
+
+	// GroupStatusFailed means at least one stack of the group did not succeed, stacks depending on it were skipped
+	GroupStatusFailed GroupStatus = "failed"
+)
+
+// MemberStatus is the state of a stack in a deploy group
+type MemberStatus string
+
+const (
+	// MemberStatusPending means the stack is waiting for the stacks it depends on
+	MemberStatusPending   MemberStatus = "pending"
+	MemberStatusRunning   MemberStatus = "running"
+	MemberStatusSucceeded MemberStatus = "succeeded"
+	MemberStatusFailed    MemberStatus = "failed"
+
+	// MemberStatusSkipped means a stack the member depends on did not succeed so the member was never run
+	MemberStatusSkipped MemberStatus = "skipped"
+)
+
+// Settled returns true when a member in this status will not make any further progress
+func (s MemberStatus) Settled() bool {
+	return s == MemberStatusSucceeded || s == MemberStatusFailed || s == MemberStatusSkipped
+}
+
+// DeployGroupRequest is a request to run several stacks in the order of their dependencies
+type DeployGroupRequest struct {
+	// Stacks are the stacks of the group, dependencies between them decide the order they run in. Dependencies on
+	// stacks outside of the group are assumed to be deployed already.
+	Stacks []string `json:"stacks"`
+
+	// Workspace is run for every stack, each stack's first workspace is used when it is empty
+	Workspace string `json:"workspace"`
+	Ref       string `json:"ref"`
+	Requester string `json:"requester"`
+	PlanOnly  bool   `json:"plan_only"`
+}
+
+func (r DeployGroupRequest) Validate() error {
+	switch {
+	case len(r.Stacks) == 0:
+		return errors.New("stacks are required")
+	case r.Requester == "":
+		return errors.New("requester is required")
+	}
+	return nil
+}
+
+// GroupMember is a stack of a deploy group and the run deploying it
+type GroupMember struct {
+	Stack     string       `json:"stack"`
+	DependsOn []string     `json:"depends_on,omitempty"`
+	Status    MemberStatus `json:"status"`
+	RunID     string       `json:"run_id,omitempty"`
+}
+
+// DeployGroup is a set of runs across stacks executed in dependency order
+type DeployGroup struct {
+	ID        string         `json:"id"`
+	Workspace string         `json:"workspace,omitempty"`
+	Ref       string         `json:"ref,omitempty"`
+	Requester string         `json:"requester"`
+	PlanOnly  bool           `json:"plan_only"`
+	Status    GroupStatus    `json:"status"`
+	Members   []*GroupMember `json:"members"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Member returns the member of the group for a stack
+func (g *DeployGroup) Member(stack string) (*GroupMember, bool) {
+	for _, m := range g.Members {
+		if m.Stack == stack {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+// GroupStore persists deploy groups
+type GroupStore interface {
+	// CreateGroup saves a new group
+	CreateGroup(ctx context.Context, group *DeployGroup) error
+
+	// UpdateGroup saves an existing group
+	UpdateGroup(ctx context.Context, group *DeployGroup) error
+
+	// GetGroup loads a group by id
+	GetGroup(ctx context.Context, id string) (*DeployGroup, error)
+}
+
+// GroupCoordinator runs deploy groups
+type GroupCoordinator interface {
+	// SubmitGroup validates a deploy group request and queues the runs of the stacks that do not depend on any other
+	// stack of the group, the rest are queued as the stacks they depend on succeed
+	SubmitGroup(ctx context.Context, req DeployGroupRequest) (*DeployGroup, error)
+}
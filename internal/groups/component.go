@@ -0,0 +1,8 @@
+package groups
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("groups", []config.EnvVar{}, NewCoordinator)
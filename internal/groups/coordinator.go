@@ -0,0 +1,230 @@
+package groups
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"fmt"
+	"go.uber.org/fx"
+	"sort"
+	"sync"
+	"time"
+)
+
+type coordinatorOut struct {
+	fx.Out
+	Coordinator internal.GroupCoordinator
+	Subscriber  internal.EventSubscriber `group:"eventSubscribers"`
+}
+
+// coordinator runs deploy groups. Runs of stacks that do not depend on each other are queued together so the workers
+// run them in parallel, a stack is queued once every stack it depends on has succeeded and is skipped when any of
+// them did not. Run events drive the group forward.
+type coordinator struct {
+	log          internal.BackgroundLog
+	stacks       internal.StackRegistry
+	orchestrator internal.Orchestrator
+	store        internal.GroupStore
+
+	// mu serializes changes to groups so a run finishing while its group is being submitted is not lost
+	mu sync.Mutex
+}
+
+func NewCoordinator(log internal.BackgroundLog, stacks internal.StackRegistry, orchestrator internal.Orchestrator, store internal.GroupStore) coordinatorOut {
+	c := newCoordinator(log, stacks, orchestrator, store)
+	return coordinatorOut{Coordinator: c, Subscriber: c}
+}
+
+func newCoordinator(log internal.BackgroundLog, stacks internal.StackRegistry, orchestrator internal.Orchestrator, store internal.GroupStore) *coordinator {
+	return &coordinator{
+		log:          log.ChildLog("deploy-groups"),
+		stacks:       stacks,
+		orchestrator: orchestrator,
+		store:        store,
+	}
+}
+
+func (c *coordinator) SubmitGroup(ctx context.Context, req internal.DeployGroupRequest) (*internal.DeployGroup, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrInvalidRequest, err)
+	}
+
+	now := time.Now().UTC()
+	group := &internal.DeployGroup{
+		ID:        internal.NewRunID(),
+		Workspace: req.Workspace,
+		Ref:       req.Ref,
+		Requester: req.Requester,
+		PlanOnly:  req.PlanOnly,
+		Status:    internal.GroupStatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	inGroup := make(map[string]bool)
+	for _, name := range req.Stacks {
+		if inGroup[name] {
+			return nil, fmt.Errorf("%w: stack %s is in the group more than once", internal.ErrInvalidRequest, name)
+		}
+		inGroup[name] = true
+	}
+	for _, name := range req.Stacks {
+		stack, ok := c.stacks.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown stack %s", internal.ErrInvalidRequest, name)
+		}
+		if req.Workspace != "" && !stack.HasWorkspace(req.Workspace) {
+			return nil, fmt.Errorf("%w: stack %s has no workspace %s", internal.ErrInvalidRequest, name, req.Workspace)
+		}
+
+		member := &internal.GroupMember{Stack: name, Status: internal.MemberStatusPending, DependsOn: c.groupDependencies(stack, inGroup)}
+		group.Members = append(group.Members, member)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.store.CreateGroup(ctx, group); err != nil {
+		return nil, fmt.Errorf("unable to save deploy group: %w", err)
+	}
+	c.log.InfowCtx(ctx, "Deploy group submitted", "group_id", group.ID, "stacks", req.Stacks, "requester", req.Requester)
+
+	if err := c.advance(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// groupDependencies returns the members of a group that stack depends on directly or through stacks left out of the
+// group, so a stack still waits for a member it only depends on through a stack that is not deployed with it
+func (c *coordinator) groupDependencies(stack internal.Stack, inGroup map[string]bool) []string {
+	var deps []string
+	seen := make(map[string]bool)
+	var visit func(s internal.Stack)
+	visit = func(s internal.Stack) {
+		for _, name := range s.DependsOn {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if inGroup[name] {
+				deps = append(deps, name)
+			}
+			// Dependencies are checked to be known stacks without cycles when the config is loaded
+			if dep, ok := c.stacks.Get(name); ok {
+				visit(dep)
+			}
+		}
+	}
+	visit(stack)
+	sort.Strings(deps)
+	return deps
+}
+
+func (c *coordinator) Name() string {
+	return "deploy-groups"
+}
+
+func (c *coordinator) Handle(ctx context.Context, event internal.Event) error {
+	run := event.Run
+	if run == nil || run.Group == "" || !run.Status.Terminal() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	group, err := c.store.GetGroup(ctx, run.Group)
+	if err != nil {
+		return fmt.Errorf("unable to load deploy group %s: %w", run.Group, err)
+	}
+
+	// Events can be delivered more than once, only the first one for the member's run moves the group on
+	member, ok := group.Member(run.Stack)
+	if !ok || member.RunID != run.ID || member.Status != internal.MemberStatusRunning {
+		return nil
+	}
+
+	member.Status = internal.MemberStatusFailed
+	if run.Status == internal.RunStatusApplied || run.Status == internal.RunStatusPlanned {
+		member.Status = internal.MemberStatusSucceeded
+	}
+	c.log.Infow("Deploy group stack finished", "group_id", group.ID, "stack", member.Stack, "run_id", run.ID, "status", member.Status)
+
+	return c.advance(ctx, group)
+}
+
+// advance queues the members whose dependencies have all succeeded, skips the members with a dependency that did not
+// succeed and settles the group once every member has settled
+func (c *coordinator) advance(ctx context.Context, group *internal.DeployGroup) error {
+	for changed := true; changed; {
+		changed = false
+		for _, member := range group.Members {
+			if member.Status != internal.MemberStatusPending {
+				continue
+			}
+
+			switch dependencies(group, member) {
+			case internal.MemberStatusFailed:
+				member.Status = internal.MemberStatusSkipped
+				changed = true
+			case internal.MemberStatusSucceeded:
+				c.submit(ctx, group, member)
+				changed = true
+			}
+		}
+	}
+
+	settled, failed := true, false
+	for _, member := range group.Members {
+		settled = settled && member.Status.Settled()
+		failed = failed || member.Status == internal.MemberStatusFailed || member.Status == internal.MemberStatusSkipped
+	}
+	if settled {
+		group.Status = internal.GroupStatusSucceeded
+		if failed {
+			group.Status = internal.GroupStatusFailed
+		}
+		c.log.Infow("Deploy group finished", "group_id", group.ID, "status", group.Status)
+	}
+
+	group.UpdatedAt = time.Now().UTC()
+	if err := c.store.UpdateGroup(ctx, group); err != nil {
+		return fmt.Errorf("unable to save deploy group %s: %w", group.ID, err)
+	}
+	return nil
+}
+
+func (c *coordinator) submit(ctx context.Context, group *internal.DeployGroup, member *internal.GroupMember) {
+	run, err := c.orchestrator.Submit(ctx, internal.DeployRequest{
+		Stack:     member.Stack,
+		Workspace: group.Workspace,
+		Ref:       group.Ref,
+		Requester: group.Requester,
+		PlanOnly:  group.PlanOnly,
+		Group:     group.ID,
+	})
+	if err != nil {
+		c.log.Errw(err, "Unable to submit deploy group run", "group_id", group.ID, "stack", member.Stack)
+		member.Status = internal.MemberStatusFailed
+		return
+	}
+	member.RunID = run.ID
+	member.Status = internal.MemberStatusRunning
+}
+
+// dependencies returns succeeded when every dependency of member has succeeded, failed when any of them failed or was
+// skipped and pending otherwise
+func dependencies(group *internal.DeployGroup, member *internal.GroupMember) internal.MemberStatus {
+	status := internal.MemberStatusSucceeded
+	for _, name := range member.DependsOn {
+		dep, _ := group.Member(name)
+		switch dep.Status {
+		case internal.MemberStatusFailed, internal.MemberStatusSkipped:
+			return internal.MemberStatusFailed
+		case internal.MemberStatusSucceeded:
+		default:
+			status = internal.MemberStatusPending
+		}
+	}
+	return status
+}
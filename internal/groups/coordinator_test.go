@@ -0,0 +1,159 @@
+package groups
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"deploy-runner/internal/stacks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestCoordinator(t *testing.T) {
+	t.Run("TestTopologicalOrder", testTopologicalOrder)
+	t.Run("TestHaltDependents", testHaltDependents)
+	t.Run("TestTransitiveDependencies", testTransitiveDependencies)
+}
+
+type fakeOrchestrator struct {
+	internal.Orchestrator
+	runs map[string]*internal.Run
+}
+
+func (o *fakeOrchestrator) Submit(ctx context.Context, req internal.DeployRequest) (*internal.Run, error) {
+	stack := internal.Stack{Repository: "https://example.com/infra.git", Workspaces: []string{"default"}}
+	run := internal.NewRun(req, stack)
+	o.runs[req.Stack] = run
+	return run, nil
+}
+
+// submitted returns the stacks that have been submitted so far
+func (o *fakeOrchestrator) submitted() map[string]bool {
+	stacks := make(map[string]bool)
+	for name := range o.runs {
+		stacks[name] = true
+	}
+	return stacks
+}
+
+type memGroupStore struct {
+	mu     sync.Mutex
+	groups map[string]internal.DeployGroup
+}
+
+func (s *memGroupStore) CreateGroup(ctx context.Context, group *internal.DeployGroup) error {
+	return s.UpdateGroup(ctx, group)
+}
+
+func (s *memGroupStore) UpdateGroup(ctx context.Context, group *internal.DeployGroup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *group
+	copied.Members = make([]*internal.GroupMember, 0, len(group.Members))
+	for _, m := range group.Members {
+		member := *m
+		copied.Members = append(copied.Members, &member)
+	}
+	s.groups[group.ID] = copied
+	return nil
+}
+
+func (s *memGroupStore) GetGroup(ctx context.Context, id string) (*internal.DeployGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	group, ok := s.groups[id]
+	if !ok {
+		return nil, internal.ErrGroupNotFound
+	}
+	return &group, nil
+}
+
+func newTestCoordinator(t *testing.T) (*coordinator, *fakeOrchestrator, *memGroupStore) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"accounts": map[string]interface{}{"repository": "https://example.com/infra.git"},
+		"network":  map[string]interface{}{"repository": "https://example.com/infra.git", "depends_on": []string{"accounts"}},
+		"dns":      map[string]interface{}{"repository": "https://example.com/infra.git", "depends_on": []string{"accounts"}},
+		"cluster":  map[string]interface{}{"repository": "https://example.com/infra.git", "depends_on": []string{"network", "dns"}},
+	})
+
+	registry, err := stacks.NewRegistry(cfg)
+	assert.NoError(t, err)
+	o := &fakeOrchestrator{runs: make(map[string]*internal.Run)}
+	store := &memGroupStore{groups: make(map[string]internal.DeployGroup)}
+	return newCoordinator(logging.NewBackgroundLog(cfg), registry, o, store), o, store
+}
+
+// finish settles the run of a stack and hands its event to the coordinator
+func finish(t *testing.T, c *coordinator, o *fakeOrchestrator, stack string, status internal.RunStatus) {
+	run := o.runs[stack]
+	run.Status = status
+	assert.NoError(t, c.Handle(context.Background(), internal.NewEvent(internal.RunStatusEventType(status), run, "")))
+}
+
+func testTopologicalOrder(t *testing.T) {
+	c, o, store := newTestCoordinator(t)
+	group, err := c.SubmitGroup(context.Background(), internal.DeployGroupRequest{Stacks: []string{"cluster", "network", "dns", "accounts"}, Requester: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"accounts": true}, o.submitted())
+
+	finish(t, c, o, "accounts", internal.RunStatusApplied)
+	assert.Equal(t, map[string]bool{"accounts": true, "network": true, "dns": true}, o.submitted())
+
+	finish(t, c, o, "network", internal.RunStatusApplied)
+	assert.Len(t, o.submitted(), 3)
+
+	// A duplicate event does not move the group on twice
+	finish(t, c, o, "network", internal.RunStatusApplied)
+	finish(t, c, o, "dns", internal.RunStatusPlanned)
+	assert.Len(t, o.submitted(), 4)
+	assert.Equal(t, group.ID, o.runs["cluster"].Group)
+
+	finish(t, c, o, "cluster", internal.RunStatusApplied)
+	saved, err := store.GetGroup(context.Background(), group.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.GroupStatusSucceeded, saved.Status)
+}
+
+func testHaltDependents(t *testing.T) {
+	c, o, store := newTestCoordinator(t)
+	group, err := c.SubmitGroup(context.Background(), internal.DeployGroupRequest{Stacks: []string{"accounts", "network", "dns", "cluster"}, Requester: "alice"})
+	assert.NoError(t, err)
+
+	finish(t, c, o, "accounts", internal.RunStatusApplied)
+	finish(t, c, o, "network", internal.RunStatusFailed)
+
+	saved, _ := store.GetGroup(context.Background(), group.ID)
+	cluster, _ := saved.Member("cluster")
+	assert.Equal(t, internal.MemberStatusSkipped, cluster.Status)
+	assert.Equal(t, internal.GroupStatusRunning, saved.Status)
+
+	// The independent dns branch still finishes before the group fails
+	finish(t, c, o, "dns", internal.RunStatusApplied)
+	saved, _ = store.GetGroup(context.Background(), group.ID)
+	assert.Equal(t, internal.GroupStatusFailed, saved.Status)
+	assert.NotContains(t, o.submitted(), "cluster")
+
+	_, err = c.SubmitGroup(context.Background(), internal.DeployGroupRequest{Stacks: []string{"network", "network"}, Requester: "alice"})
+	assert.ErrorIs(t, err, internal.ErrInvalidRequest)
+}
+
+func testTransitiveDependencies(t *testing.T) {
+	c, o, store := newTestCoordinator(t)
+	// cluster depends on accounts through network and dns which are not in the group
+	group, err := c.SubmitGroup(context.Background(), internal.DeployGroupRequest{Stacks: []string{"cluster", "accounts"}, Requester: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"accounts": true}, o.submitted())
+
+	saved, _ := store.GetGroup(context.Background(), group.ID)
+	cluster, _ := saved.Member("cluster")
+	assert.Equal(t, []string{"accounts"}, cluster.DependsOn)
+
+	finish(t, c, o, "accounts", internal.RunStatusApplied)
+	assert.Equal(t, map[string]bool{"accounts": true, "cluster": true}, o.submitted())
+}
@@ -0,0 +1,137 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"sync"
+	"time"
+)
+
+// fakeBroker is an in-process stand in for a kafka cluster with single partition topics and one consumer group
+type fakeBroker struct {
+	mu        sync.Mutex
+	topics    map[string][]kafka.Message
+	committed map[string]int64
+	// writeErr makes writes fail while it is set
+	writeErr error
+	changed  chan struct{}
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		topics:    make(map[string][]kafka.Message),
+		committed: make(map[string]int64),
+		changed:   make(chan struct{}),
+	}
+}
+
+func (b *fakeBroker) produce(topic string, msgs ...kafka.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, msg := range msgs {
+		msg.Topic = topic
+		msg.Offset = int64(len(b.topics[topic]))
+		msg.Time = time.Now()
+		b.topics[topic] = append(b.topics[topic], msg)
+	}
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func (b *fakeBroker) messages(topic string) []kafka.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]kafka.Message{}, b.topics[topic]...)
+}
+
+// committedOffset returns the offset the consumer group will resume the topic from
+func (b *fakeBroker) committedOffset(topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.committed[topic]
+}
+
+func (b *fakeBroker) setWriteErr(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writeErr = err
+}
+
+func (b *fakeBroker) reader(topic string) *fakeReader {
+	return &fakeReader{broker: b, topic: topic, next: b.committedOffset(topic)}
+}
+
+func (b *fakeBroker) writer(topic string) *fakeWriter {
+	return &fakeWriter{broker: b, topic: topic}
+}
+
+type fakeReader struct {
+	broker *fakeBroker
+	topic  string
+	next   int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.broker.mu.Lock()
+		msgs, changed := r.broker.topics[r.topic], r.broker.changed
+		r.broker.mu.Unlock()
+
+		if r.next < int64(len(msgs)) {
+			r.next++
+			return msgs[r.next-1], nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (r *fakeReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.broker.mu.Lock()
+	defer r.broker.mu.Unlock()
+	for _, msg := range msgs {
+		if msg.Topic != r.topic {
+			return errors.New("message is not from the reader's topic")
+		}
+		if msg.Offset+1 > r.broker.committed[r.topic] {
+			r.broker.committed[r.topic] = msg.Offset + 1
+		}
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error {
+	return nil
+}
+
+type fakeWriter struct {
+	broker *fakeBroker
+	topic  string
+}
+
+func (w *fakeWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.broker.mu.Lock()
+	err := w.broker.writeErr
+	w.broker.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		topic := w.topic
+		if topic == "" {
+			topic = msg.Topic
+		}
+		w.broker.produce(topic, msg)
+	}
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package kafka
+
+import (
+	"context"
+	"deploy-runner/config"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// messageReader is the part of kafka.Reader used by the runner, it is an interface so tests can use an in-process
+// broker instead of a real one
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// messageWriter is the part of kafka.Writer used by the runner
+type messageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// brokers returns the configured bootstrap servers, kafka is disabled when there are none
+func brokers(cfg *viper.Viper) []string {
+	var addrs []string
+	for _, addr := range strings.Split(cfg.GetString(config.KafkaBootstrapServerAddress.String()), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+func newWriter(addrs []string, topic string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:         kafka.TCP(addrs...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+}
@@ -0,0 +1,8 @@
+package kafka
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("kafka", []config.EnvVar{config.EnvKafkaBootstrapServerAddress, config.EnvKafkaDeployRequestsTopic, config.EnvKafkaConsumerGroup, config.EnvKafkaDeadLetterTopic, config.EnvKafkaRunEventsTopic}, NewConsumer, NewEventProducer)
@@ -0,0 +1,193 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/multierr"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	minRetryBackoff = time.Second
+	maxRetryBackoff = 30 * time.Second
+
+	headerError     = "x-dead-letter-error"
+	headerTopic     = "x-dead-letter-topic"
+	headerPartition = "x-dead-letter-partition"
+	headerOffset    = "x-dead-letter-offset"
+)
+
+type serviceOut struct {
+	fx.Out
+	Service app.Service `group:"services"`
+}
+
+// consumer reads deploy requests from kafka and submits them as runs. A message's offset is only committed once the
+// run has been saved to the run store or the message has been sent to the dead letter topic, so delivery is at least
+// once and a crash between the two can submit the same request twice.
+type consumer struct {
+	log          internal.BackgroundLog
+	orchestrator internal.Orchestrator
+	reader       messageReader
+	deadLetter   messageWriter
+	disabled     bool
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+func NewConsumer(cfg *viper.Viper, log internal.BackgroundLog, orchestrator internal.Orchestrator) serviceOut {
+	addrs := brokers(cfg)
+	if len(addrs) == 0 {
+		return serviceOut{Service: &consumer{disabled: true}}
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: addrs,
+		GroupID: cfg.GetString(config.KafkaConsumerGroup.String()),
+		Topic:   cfg.GetString(config.KafkaDeployRequestsTopic.String()),
+	})
+	deadLetter := newWriter(addrs, cfg.GetString(config.KafkaDeadLetterTopic.String()))
+	return serviceOut{Service: newConsumer(log, orchestrator, reader, deadLetter)}
+}
+
+func newConsumer(log internal.BackgroundLog, orchestrator internal.Orchestrator, reader messageReader, deadLetter messageWriter) *consumer {
+	return &consumer{
+		log:          log.ChildLog("kafka-consumer"),
+		orchestrator: orchestrator,
+		reader:       reader,
+		deadLetter:   deadLetter,
+		minBackoff:   minRetryBackoff,
+		maxBackoff:   maxRetryBackoff,
+	}
+}
+
+func (c *consumer) Start(ctx context.Context) error {
+	consumeCtx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.log.Info("Starting deploy request consumer")
+	c.wg.Add(1)
+	go c.consume(consumeCtx)
+	return nil
+}
+
+func (c *consumer) Stop(ctx context.Context) error {
+	if c.cancel == nil {
+		return nil
+	}
+	c.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return multierr.Combine(c.reader.Close(), c.deadLetter.Close())
+}
+
+func (c *consumer) Disabled() bool {
+	return c.disabled
+}
+
+func (c *consumer) consume(ctx context.Context) {
+	defer c.wg.Done()
+	for {
+		var msg kafka.Message
+		err := c.retry(ctx, "fetch message", func() (err error) {
+			msg, err = c.reader.FetchMessage(ctx)
+			return err
+		})
+		if err != nil {
+			return
+		}
+
+		// A message is retried until it is handled rather than skipped, committing a later offset would lose it
+		if err := c.retry(ctx, "handle message", func() error { return c.handle(ctx, msg) }); err != nil {
+			return
+		}
+		if err := c.retry(ctx, "commit message", func() error { return c.reader.CommitMessages(ctx, msg) }); err != nil {
+			return
+		}
+	}
+}
+
+// handle submits the deploy request in a message, malformed or invalid requests are sent to the dead letter topic.
+// An error is only returned when the message should be retried.
+func (c *consumer) handle(ctx context.Context, msg kafka.Message) error {
+	var req internal.DeployRequest
+	dec := json.NewDecoder(bytes.NewReader(msg.Value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return c.sendToDeadLetter(ctx, msg, fmt.Errorf("unable to decode deploy request: %w", err))
+	}
+
+	run, err := c.orchestrator.Submit(ctx, req)
+	if errors.Is(err, internal.ErrInvalidRequest) {
+		return c.sendToDeadLetter(ctx, msg, err)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.log.Infow("Deploy request consumed", "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace, "requester", run.Requester, "partition", msg.Partition, "offset", msg.Offset)
+	return nil
+}
+
+func (c *consumer) sendToDeadLetter(ctx context.Context, msg kafka.Message, reason error) error {
+	c.log.Warnw("Sending deploy request to dead letter topic", "error", reason.Error(), "partition", msg.Partition, "offset", msg.Offset)
+
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: headerError, Value: []byte(reason.Error())},
+		kafka.Header{Key: headerTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: headerPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: headerOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+	)
+	if err := c.deadLetter.WriteMessages(ctx, kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}); err != nil {
+		return fmt.Errorf("unable to write to dead letter topic: %w", err)
+	}
+	return nil
+}
+
+// retry calls f until it succeeds backing off between failures, it only gives up when ctx is done
+func (c *consumer) retry(ctx context.Context, action string, f func() error) error {
+	backoff := c.minBackoff
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		c.log.Errw(err, "Kafka consumer failed, retrying", "action", action, "backoff", backoff.String())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package kafka
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	requestsTopic   = "deploy-requests"
+	deadLetterTopic = "deploy-requests-dlq"
+)
+
+func TestConsumer(t *testing.T) {
+	t.Run("TestSubmitsRequest", testSubmitsRequest)
+	t.Run("TestMalformedToDeadLetter", testMalformedToDeadLetter)
+	t.Run("TestInvalidToDeadLetter", testInvalidToDeadLetter)
+	t.Run("TestRetriesUntilRecorded", testRetriesUntilRecorded)
+	t.Run("TestRetriesDeadLetterWrite", testRetriesDeadLetterWrite)
+}
+
+// fakeOrchestrator records submitted requests along with the offset committed at the time they were submitted
+type fakeOrchestrator struct {
+	internal.Orchestrator
+	broker *fakeBroker
+
+	mu        sync.Mutex
+	submitted []internal.DeployRequest
+	committed []int64
+	failures  int
+}
+
+func (o *fakeOrchestrator) Submit(ctx context.Context, req internal.DeployRequest) (*internal.Run, error) {
+	if err := req.Validate(); err != nil {
+		return nil, internal.ErrInvalidRequest
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.failures > 0 {
+		o.failures--
+		return nil, errors.New("store unavailable")
+	}
+	o.submitted = append(o.submitted, req)
+	o.committed = append(o.committed, o.broker.committedOffset(requestsTopic))
+	return internal.NewRun(req, internal.Stack{}), nil
+}
+
+func (o *fakeOrchestrator) requests() []internal.DeployRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]internal.DeployRequest{}, o.submitted...)
+}
+
+func startConsumer(t *testing.T, broker *fakeBroker, orchestrator internal.Orchestrator) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+
+	c := newConsumer(logging.NewBackgroundLog(cfg), orchestrator, broker.reader(requestsTopic), broker.writer(deadLetterTopic))
+	c.minBackoff, c.maxBackoff = time.Millisecond, 5*time.Millisecond
+	assert.NoError(t, c.Start(context.Background()))
+	t.Cleanup(func() {
+		assert.NoError(t, c.Stop(context.Background()))
+	})
+}
+
+func waitForCommit(t *testing.T, broker *fakeBroker, offset int64) {
+	assert.Eventually(t, func() bool {
+		return broker.committedOffset(requestsTopic) == offset
+	}, time.Second, time.Millisecond)
+}
+
+func testSubmitsRequest(t *testing.T) {
+	broker := newFakeBroker()
+	o := &fakeOrchestrator{broker: broker}
+	startConsumer(t, broker, o)
+
+	broker.produce(requestsTopic, kafka.Message{Key: []byte("network"), Value: []byte(`{"stack":"network","ref":"main","requester":"ci"}`)})
+	waitForCommit(t, broker, 1)
+
+	assert.Equal(t, []internal.DeployRequest{{Stack: "network", Ref: "main", Requester: "ci"}}, o.requests())
+	assert.Equal(t, []int64{0}, o.committed)
+	assert.Empty(t, broker.messages(deadLetterTopic))
+}
+
+func testMalformedToDeadLetter(t *testing.T) {
+	broker := newFakeBroker()
+	o := &fakeOrchestrator{broker: broker}
+	startConsumer(t, broker, o)
+
+	broker.produce(requestsTopic, kafka.Message{Key: []byte("network"), Value: []byte(`{"stack":`), Headers: []kafka.Header{{Key: "trace", Value: []byte("abc")}}})
+	waitForCommit(t, broker, 1)
+
+	assert.Empty(t, o.requests())
+	dead := broker.messages(deadLetterTopic)
+	if !assert.Len(t, dead, 1) {
+		return
+	}
+	assert.Equal(t, []byte("network"), dead[0].Key)
+	assert.Equal(t, []byte(`{"stack":`), dead[0].Value)
+
+	headers := make(map[string]string)
+	for _, h := range dead[0].Headers {
+		headers[h.Key] = string(h.Value)
+	}
+	assert.Equal(t, "abc", headers["trace"])
+	assert.Equal(t, requestsTopic, headers[headerTopic])
+	assert.Equal(t, "0", headers[headerOffset])
+	assert.Contains(t, headers[headerError], "unable to decode deploy request")
+}
+
+func testInvalidToDeadLetter(t *testing.T) {
+	broker := newFakeBroker()
+	o := &fakeOrchestrator{broker: broker}
+	startConsumer(t, broker, o)
+
+	broker.produce(requestsTopic,
+		kafka.Message{Value: []byte(`{"stack":"network"}`)},
+		kafka.Message{Value: []byte(`{"stack":"network","unknown":true}`)},
+		kafka.Message{Value: []byte(`{"stack":"dns","requester":"ci"}`)},
+	)
+	waitForCommit(t, broker, 3)
+
+	assert.Len(t, broker.messages(deadLetterTopic), 2)
+	requests := o.requests()
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+	assert.Equal(t, "dns", requests[0].Stack)
+}
+
+func testRetriesUntilRecorded(t *testing.T) {
+	broker := newFakeBroker()
+	o := &fakeOrchestrator{broker: broker, failures: 3}
+	startConsumer(t, broker, o)
+
+	broker.produce(requestsTopic,
+		kafka.Message{Value: []byte(`{"stack":"network","requester":"ci"}`)},
+		kafka.Message{Value: []byte(`{"stack":"dns","requester":"ci"}`)},
+	)
+	waitForCommit(t, broker, 2)
+
+	requests := o.requests()
+	if !assert.Len(t, requests, 2) {
+		return
+	}
+	assert.Equal(t, "network", requests[0].Stack)
+	assert.Equal(t, "dns", requests[1].Stack)
+	// Nothing is committed until the run has been recorded
+	assert.Equal(t, []int64{0, 1}, o.committed)
+}
+
+func testRetriesDeadLetterWrite(t *testing.T) {
+	broker := newFakeBroker()
+	broker.setWriteErr(errors.New("leader not available"))
+	o := &fakeOrchestrator{broker: broker}
+	startConsumer(t, broker, o)
+
+	broker.produce(requestsTopic, kafka.Message{Value: []byte(`not json`)})
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int64(0), broker.committedOffset(requestsTopic))
+
+	broker.setWriteErr(nil)
+	waitForCommit(t, broker, 1)
+	assert.Len(t, broker.messages(deadLetterTopic), 1)
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"encoding/json"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"time"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsSource      = "deploy-runner"
+	cloudEventsTypePrefix  = "deploy-runner."
+	cloudEventsContentType = "application/cloudevents+json"
+)
+
+type subscriberOut struct {
+	fx.Out
+	Subscriber internal.EventSubscriber `group:"eventSubscribers"`
+}
+
+// cloudEvent is the structured mode CloudEvents JSON envelope events are published in
+type cloudEvent struct {
+	SpecVersion     string       `json:"specversion"`
+	ID              string       `json:"id"`
+	Source          string       `json:"source"`
+	Type            string       `json:"type"`
+	Subject         string       `json:"subject,omitempty"`
+	Time            time.Time    `json:"time"`
+	DataContentType string       `json:"datacontenttype"`
+	Data            runEventData `json:"data"`
+}
+
+type runEventData struct {
+	RunID          string                `json:"run_id,omitempty"`
+	Stack          string                `json:"stack,omitempty"`
+	Workspace      string                `json:"workspace,omitempty"`
+	Status         internal.RunStatus    `json:"status,omitempty"`
+	PreviousStatus internal.RunStatus    `json:"previous_status,omitempty"`
+	Repository     string                `json:"repository,omitempty"`
+	Ref            string                `json:"ref,omitempty"`
+	CommitSHA      string                `json:"commit_sha,omitempty"`
+	Requester      string                `json:"requester,omitempty"`
+	PlanOnly       bool                  `json:"plan_only"`
+	Plan           *internal.PlanSummary `json:"plan,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	Message        string                `json:"message,omitempty"`
+}
+
+// eventProducer publishes events to kafka, it does nothing when kafka is not configured
+type eventProducer struct {
+	writer messageWriter
+}
+
+func NewEventProducer(cfg *viper.Viper, lc fx.Lifecycle) subscriberOut {
+	addrs := brokers(cfg)
+	if len(addrs) == 0 {
+		return subscriberOut{Subscriber: &eventProducer{}}
+	}
+
+	writer := newWriter(addrs, cfg.GetString(config.KafkaRunEventsTopic.String()))
+	lc.Append(fx.Hook{OnStop: func(ctx context.Context) error {
+		return writer.Close()
+	}})
+	return subscriberOut{Subscriber: &eventProducer{writer: writer}}
+}
+
+func (p *eventProducer) Name() string {
+	return "kafka"
+}
+
+func (p *eventProducer) Handle(ctx context.Context, event internal.Event) error {
+	if p.writer == nil || event.Run == nil {
+		return nil
+	}
+
+	value, err := json.Marshal(newCloudEvent(event))
+	if err != nil {
+		return fmt.Errorf("unable to encode event: %w", err)
+	}
+
+	// Keying by stack keeps the events of a stack in order since its runs are executed one at a time
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(event.Run.Stack),
+		Value:   value,
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte(cloudEventsContentType)}},
+	})
+}
+
+func newCloudEvent(event internal.Event) cloudEvent {
+	run := event.Run
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          cloudEventsSource,
+		Type:            cloudEventsTypePrefix + string(event.Type),
+		Subject:         run.ID,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data: runEventData{
+			RunID:          run.ID,
+			Stack:          run.Stack,
+			Workspace:      run.Workspace,
+			Status:         run.Status,
+			PreviousStatus: event.PreviousStatus,
+			Repository:     run.Repository,
+			Ref:            run.Ref,
+			CommitSHA:      run.CommitSHA,
+			Requester:      run.Requester,
+			PlanOnly:       run.PlanOnly,
+			Plan:           run.Plan,
+			Error:          run.Error,
+			Message:        event.Message,
+		},
+	}
+}
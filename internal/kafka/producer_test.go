@@ -0,0 +1,56 @@
+package kafka
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const runEventsTopic = "deploy-run-events"
+
+func TestEventProducer(t *testing.T) {
+	t.Run("TestPublishesCloudEvent", testPublishesCloudEvent)
+	t.Run("TestDisabled", testProducerDisabled)
+}
+
+func testPublishesCloudEvent(t *testing.T) {
+	broker := newFakeBroker()
+	p := &eventProducer{writer: broker.writer(runEventsTopic)}
+
+	run := internal.NewRun(internal.DeployRequest{Stack: "network", Ref: "main", Requester: "ci"}, internal.Stack{Repository: "https://example.com/infra.git"})
+	run.Status = internal.RunStatusPlanned
+	run.CommitSHA = "0123456789abcdef0123456789abcdef01234567"
+	run.Plan = &internal.PlanSummary{Add: 1, Change: 2}
+	event := internal.NewEvent(internal.RunStatusEventType(run.Status), run, "")
+	event.PreviousStatus = internal.RunStatusPlanning
+	assert.NoError(t, p.Handle(context.Background(), event))
+
+	msgs := broker.messages(runEventsTopic)
+	if !assert.Len(t, msgs, 1) {
+		return
+	}
+	assert.Equal(t, []byte("network"), msgs[0].Key)
+	assert.Equal(t, "content-type", msgs[0].Headers[0].Key)
+
+	var ce map[string]interface{}
+	assert.NoError(t, json.Unmarshal(msgs[0].Value, &ce))
+	assert.Equal(t, "1.0", ce["specversion"])
+	assert.Equal(t, event.ID, ce["id"])
+	assert.Equal(t, "deploy-runner.run.planned", ce["type"])
+	assert.Equal(t, run.ID, ce["subject"])
+
+	data := ce["data"].(map[string]interface{})
+	assert.Equal(t, "network", data["stack"])
+	assert.Equal(t, "planned", data["status"])
+	assert.Equal(t, "planning", data["previous_status"])
+	assert.Equal(t, run.CommitSHA, data["commit_sha"])
+	assert.Equal(t, "ci", data["requester"])
+	assert.Equal(t, map[string]interface{}{"add": 1.0, "change": 2.0, "destroy": 0.0}, data["plan"])
+}
+
+func testProducerDisabled(t *testing.T) {
+	p := &eventProducer{}
+	assert.NoError(t, p.Handle(context.Background(), internal.NewEvent(internal.EventRunQueued, &internal.Run{ID: "1"}, "")))
+}
@@ -0,0 +1,94 @@
+package local
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"deploy-runner/internal/client"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type runAction struct {
+	cfg          *viper.Viper
+	orchestrator internal.Orchestrator
+	queue        internal.RunQueue
+	out          io.Writer
+	status       io.Writer
+}
+
+// NewRunAction executes a run of the stack passed as the only argument in this process, the run goes through the same
+// orchestrator as runs of the server with the terraform output also written to the terminal
+func NewRunAction(cfg *viper.Viper, orchestrator internal.Orchestrator, queue internal.RunQueue) app.ActionAdapter {
+	return &runAction{cfg: cfg, orchestrator: orchestrator, queue: queue, out: os.Stdout, status: os.Stderr}
+}
+
+func (a *runAction) Execute(args []string) error {
+	if len(args) != 1 {
+		return errors.New("expected the stack to run as the only argument")
+	}
+	requester, err := client.Requester(a.cfg)
+	if err != nil {
+		return err
+	}
+
+	// Interrupting the command cancels the run so terraform can release its state lock before it stops
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = internal.WithRunOutput(ctx, a.out)
+
+	submitted, err := a.orchestrator.Submit(ctx, internal.DeployRequest{
+		Stack:     args[0],
+		Workspace: a.cfg.GetString(config.ClientWorkspace.String()),
+		Ref:       a.cfg.GetString(config.ClientRef.String()),
+		Requester: requester,
+		PlanOnly:  a.cfg.GetBool(config.ClientPlanOnly.String()),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.status, "Run %s of %s/%s queued\n", submitted.ID, submitted.Stack, submitted.Workspace)
+
+	run, err := a.next(ctx)
+	if err != nil {
+		return err
+	}
+	if run.Status == internal.RunStatusAwaitingApproval && a.cfg.GetBool(config.ClientApprove.String()) {
+		if _, err := a.orchestrator.Approve(ctx, run.ID, requester); err != nil {
+			return fmt.Errorf("unable to approve run %s: %w", run.ID, err)
+		}
+		if run, err = a.next(ctx); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(a.status, "Run %s finished %s\n", run.ID, run.Status)
+	switch run.Status {
+	case internal.RunStatusPlanned, internal.RunStatusApplied:
+		return nil
+	case internal.RunStatusAwaitingApproval:
+		fmt.Fprintf(a.status, "The plan has changes that need approval, run again with --approve as an approver of %s to apply them\n", run.Stack)
+		return nil
+	default:
+		return fmt.Errorf("run %s finished %s: %s", run.ID, run.Status, run.Error)
+	}
+}
+
+// next executes the next queued run the same way a worker of the server does
+func (a *runAction) next(ctx context.Context) (*internal.Run, error) {
+	run, err := a.queue.Dequeue(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer a.queue.Done(run)
+
+	// The error is recorded on the run so its final status decides the result
+	_ = a.orchestrator.Execute(ctx, run)
+	return run, nil
+}
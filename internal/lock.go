@@ -0,0 +1,13 @@
+package internal
+
+import "context"
+
+// StackLocker serializes work against the same stack workspace inside this runner so it never races itself for a
+// terraform state lock
+type StackLocker interface {
+	// Lock blocks until the stack workspace is free or ctx is done and returns a func to release it
+	Lock(ctx context.Context, stack, workspace string) (func(), error)
+
+	// TryLock acquires the stack workspace only if it is free, ok is false when it is already held
+	TryLock(stack, workspace string) (unlock func(), ok bool)
+}
@@ -0,0 +1,8 @@
+package locking
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("locking", []config.EnvVar{}, NewStackLocker)
@@ -0,0 +1,56 @@
+package locking
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"sync"
+)
+
+type stackLocker struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+func NewStackLocker() internal.StackLocker {
+	return &stackLocker{locks: make(map[string]chan struct{})}
+}
+
+func (l *stackLocker) Lock(ctx context.Context, stack, workspace string) (func(), error) {
+	sem := l.semaphore(stack, workspace)
+	select {
+	case sem <- struct{}{}:
+		return releaser(sem), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *stackLocker) TryLock(stack, workspace string) (func(), bool) {
+	sem := l.semaphore(stack, workspace)
+	select {
+	case sem <- struct{}{}:
+		return releaser(sem), true
+	default:
+		return nil, false
+	}
+}
+
+func (l *stackLocker) semaphore(stack, workspace string) chan struct{} {
+	key := stack + "/" + workspace
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.locks[key]
+	if !ok {
+		sem = make(chan struct{}, 1)
+		l.locks[key] = sem
+	}
+	return sem
+}
+
+func releaser(sem chan struct{}) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-sem })
+	}
+}
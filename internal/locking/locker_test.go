@@ -0,0 +1,59 @@
+package locking
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestStackLocker(t *testing.T) {
+	t.Run("TestLockBlocksSameWorkspace", testLockBlocksSameWorkspace)
+	t.Run("TestLockDifferentWorkspaces", testLockDifferentWorkspaces)
+	t.Run("TestTryLock", testTryLock)
+}
+
+func testLockBlocksSameWorkspace(t *testing.T) {
+	l := NewStackLocker()
+	unlock, err := l.Lock(context.Background(), "network", "prod")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "network", "prod")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlock()
+	unlock2, err := l.Lock(context.Background(), "network", "prod")
+	assert.NoError(t, err)
+	unlock2()
+}
+
+func testLockDifferentWorkspaces(t *testing.T) {
+	l := NewStackLocker()
+	unlock, err := l.Lock(context.Background(), "network", "prod")
+	assert.NoError(t, err)
+	defer unlock()
+
+	unlock2, err := l.Lock(context.Background(), "network", "staging")
+	assert.NoError(t, err)
+	unlock2()
+}
+
+func testTryLock(t *testing.T) {
+	l := NewStackLocker()
+	unlock, ok := l.TryLock("network", "prod")
+	assert.True(t, ok)
+
+	_, ok = l.TryLock("network", "prod")
+	assert.False(t, ok)
+
+	// Releasing twice must not free a lock taken by someone else
+	unlock()
+	unlock()
+	unlock2, ok := l.TryLock("network", "prod")
+	assert.True(t, ok)
+	_, ok = l.TryLock("network", "prod")
+	assert.False(t, ok)
+	unlock2()
+}
@@ -3,7 +3,7 @@ package logging
 import (
 	"context"
 	"go.uber.org/zap"
-	"tracks-grpc/internal"
+	"deploy-runner/internal"
 )
 
 type background struct {
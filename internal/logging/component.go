@@ -1,8 +1,8 @@
 package logging
 
 import (
-	"tracks-grpc/config"
-	"tracks-grpc/internal"
+	"deploy-runner/config"
+	"deploy-runner/internal"
 )
 
 var Component = internal.NewComponent(
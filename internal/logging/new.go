@@ -3,7 +3,7 @@ package logging
 import (
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
-	"tracks-grpc/internal"
+	"deploy-runner/internal"
 )
 
 func New(cfg *viper.Viper) (*zap.Logger, error) {
@@ -2,7 +2,7 @@ package logging
 
 import (
 	"context"
-	"tracks-grpc/internal"
+	"deploy-runner/internal"
 )
 
 type request struct {
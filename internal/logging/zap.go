@@ -8,7 +8,7 @@ import (
 	"go.uber.org/zap/zapcore"
 	"os"
 	"strings"
-	"tracks-grpc/config"
+	"deploy-runner/config"
 )
 
 var samplingRate int
@@ -196,6 +196,7 @@ func (w *zapLog) ErrfCtx(ctx context.Context, err error, template string, args .
 func (w *zapLog) ErrwCtx(ctx context.Context, err error, message string, keyValues ...interface{}) {
 	fields := make([]interface{}, 0, len(keyValues)+6)
 	fields = append(fields, FieldError.String(), err.Error(), FieldRequestID.String(), FieldRequestID.GetFromContext(ctx))
+	fields = append(fields, keyValues...)
 	w.sugared.Errorw(message, fields...)
 }
 
@@ -5,7 +5,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"testing"
-	"tracks-grpc/config"
+	"deploy-runner/config"
 )
 
 func TestZapLog(t *testing.T) {
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// CheckStatus is the result of a single check of a terraform module
+type CheckStatus string
+
+const (
+	CheckStatusPassed  CheckStatus = "passed"
+	CheckStatusFailed  CheckStatus = "failed"
+	CheckStatusSkipped CheckStatus = "skipped"
+)
+
+// ModuleCheckRequest selects the terraform module a ModuleChecker checks, either the module of a registered stack or
+// a path in a repository
+type ModuleCheckRequest struct {
+	Stack      string
+	Repository string
+	Path       string
+
+	// Ref is a branch, tag or commit SHA of the repository, the default branch is used when it is empty
+	Ref string
+
+	// RunTests also runs terraform test, which needs terraform 1.6 or newer
+	RunTests bool
+}
+
+// CheckResult is the result of one check of a module
+type CheckResult struct {
+	Name        string                `json:"name"`
+	Status      CheckStatus           `json:"status"`
+	StartedAt   time.Time             `json:"started_at"`
+	FinishedAt  time.Time             `json:"finished_at"`
+	Diagnostics []TerraformDiagnostic `json:"diagnostics,omitempty"`
+	Error       string                `json:"error,omitempty"`
+
+	// Output is what terraform printed while running the check
+	Output string `json:"output,omitempty"`
+}
+
+// ModuleReport holds the results of checking a module, it passed when none of its checks failed
+type ModuleReport struct {
+	Stack      string        `json:"stack,omitempty"`
+	Repository string        `json:"repository"`
+	Path       string        `json:"path"`
+	Ref        string        `json:"ref"`
+	CommitSHA  string        `json:"commit_sha"`
+	Passed     bool          `json:"passed"`
+	Checks     []CheckResult `json:"checks"`
+}
+
+// ModuleChecker checks out a terraform module and runs fmt, validate and optionally test against it without touching
+// its state
+type ModuleChecker interface {
+	// Check checks the module writing terraform output to output as it runs, the error is only set when the module
+	// could not be checked at all
+	Check(ctx context.Context, req ModuleCheckRequest, output io.Writer) (*ModuleReport, error)
+}
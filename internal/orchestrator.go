@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStackBusy is returned when an operation needs exclusive access to a stack workspace that a run is using
+var ErrStackBusy = errors.New("stack workspace is in use by a run")
+
+// ErrWorkspaceNotFound is returned when a stack workspace has never been checked out by this runner
+var ErrWorkspaceNotFound = errors.New("stack workspace has not been initialized by this runner")
+
+// ErrInvalidRequest is wrapped by errors caused by a malformed or invalid request
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrRunNotCancellable is returned when cancelling a run that has already finished
+var ErrRunNotCancellable = errors.New("run has already finished and cannot be cancelled")
+
+// ErrRunNotAwaitingApproval is returned when approving or rejecting a run that is not waiting for approval
+var ErrRunNotAwaitingApproval = errors.New("run is not awaiting approval")
+
+// ErrNotApprover is returned when someone who is not an approver of a stack approves or rejects one of its runs
+var ErrNotApprover = errors.New("not an approver of the stack")
+
+// Orchestrator drives runs through the terraform pipeline
+type Orchestrator interface {
+	// Submit validates a deploy request and queues a run for it
+	Submit(ctx context.Context, req DeployRequest) (*Run, error)
+
+	// Execute runs the clone -> init -> plan -> (optional) approval -> (optional) apply pipeline for a run updating
+	// its status as it goes. An approved run resumes from its saved plan.
+	Execute(ctx context.Context, run *Run) error
+
+	// CancelRun cancels a run. Queued runs are removed from the queue, running runs have their context cancelled which
+	// interrupts terraform so it can release its state lock before it is stopped.
+	CancelRun(ctx context.Context, id, requester string) (*Run, error)
+
+	// Approve queues a run that is awaiting approval to apply the plan it already made
+	Approve(ctx context.Context, id, approver string) (*Run, error)
+
+	// Reject cancels a run that is awaiting approval without applying it
+	Reject(ctx context.Context, id, approver, reason string) (*Run, error)
+
+	// ForceUnlock releases a terraform state lock for a stack workspace, the workspace must not be in use by a run
+	ForceUnlock(ctx context.Context, stack, workspace, lockID string) error
+}
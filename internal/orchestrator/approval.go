@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"fmt"
+)
+
+func (o *orchestrator) Approve(ctx context.Context, id, approver string) (*internal.Run, error) {
+	// Held until the run has left awaiting approval so a run approved twice at the same time is only queued once
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	run, err := o.awaitingApproval(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	run.ApprovedBy = approver
+	o.transition(ctx, run, internal.RunStatusQueued)
+
+	approved := *run
+	position := o.queue.Enqueue(run)
+	o.log.InfowCtx(ctx, "Run approved", "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace, "approver", approver, "position", position)
+	return &approved, nil
+}
+
+func (o *orchestrator) Reject(ctx context.Context, id, approver, reason string) (*internal.Run, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	run, err := o.awaitingApproval(ctx, id, approver)
+	if err != nil {
+		return nil, err
+	}
+
+	run.CancelledBy = approver
+	run.Error = fmt.Sprintf("plan rejected by %s", approver)
+	if reason != "" {
+		run.Error += ": " + reason
+	}
+	o.transition(ctx, run, internal.RunStatusCancelled)
+	o.log.InfowCtx(ctx, "Run rejected", "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace, "approver", approver)
+	return run, nil
+}
+
+// awaitingApproval loads a run that is waiting for approval checking approver can approve it
+func (o *orchestrator) awaitingApproval(ctx context.Context, id, approver string) (*internal.Run, error) {
+	run, err := o.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != internal.RunStatusAwaitingApproval {
+		return nil, internal.ErrRunNotAwaitingApproval
+	}
+
+	stack, ok := o.stacks.Get(run.Stack)
+	if !ok || !stack.CanApprove(approver) {
+		return nil, internal.ErrNotApprover
+	}
+	return run, nil
+}
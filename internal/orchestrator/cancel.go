@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"fmt"
+)
+
+func (o *orchestrator) CancelRun(ctx context.Context, id, requester string) (*internal.Run, error) {
+	if run, ok := o.queue.Remove(id); ok {
+		run.CancelledBy = requester
+		run.Error = fmt.Sprintf("cancelled by %s before it started", requester)
+		o.transition(ctx, run, internal.RunStatusCancelled)
+		cancelled := *run
+		return &cancelled, nil
+	}
+
+	o.mu.Lock()
+	run, err := o.store.Get(ctx, id)
+	if err != nil {
+		o.mu.Unlock()
+		return nil, err
+	}
+	if run.Status.Terminal() {
+		o.mu.Unlock()
+		return nil, internal.ErrRunNotCancellable
+	}
+
+	// A run awaiting approval is not executing, it is settled here rather than by a worker
+	if run.Status == internal.RunStatusAwaitingApproval {
+		defer o.mu.Unlock()
+		run.CancelledBy = requester
+		run.Error = fmt.Sprintf("cancelled by %s while awaiting approval", requester)
+		o.transition(ctx, run, internal.RunStatusCancelled)
+		return run, nil
+	}
+
+	o.cancelRequests[id] = requester
+	cancel, running := o.active[id]
+	o.mu.Unlock()
+
+	// A run that is not active yet has been handed to a worker and will be cancelled as soon as it registers
+	if running {
+		cancel()
+	}
+
+	o.log.InfowCtx(ctx, "Run cancellation requested", "run_id", id, "requester", requester, "status", run.Status, "running", running)
+	return run, nil
+}
+
+// register records the cancel func of a run that is about to execute, it returns false when the run was cancelled
+// before it got this far
+func (o *orchestrator) register(id string, cancel context.CancelFunc) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.cancelRequests[id]; ok {
+		return false
+	}
+	o.active[id] = cancel
+	return true
+}
+
+func (o *orchestrator) unregister(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	delete(o.active, id)
+	delete(o.cancelRequests, id)
+}
+
+func (o *orchestrator) cancelRequester(id string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.cancelRequests[id]
+}
@@ -0,0 +1,125 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+func terraformOptions(stack internal.Stack) internal.TerraformOptions {
+	return internal.TerraformOptions{
+		Version:       stack.TerraformVersion,
+		BackendConfig: stack.BackendConfig,
+	}
+}
+
+// variables returns the stack's variables together with its inputs read from the latest outputs of upstream stacks,
+// recording where each input came from on the run
+func (o *orchestrator) variables(ctx context.Context, e *execution) (map[string]interface{}, error) {
+	variables := make(map[string]interface{}, len(e.stack.Variables)+len(e.stack.Inputs))
+	for name, v := range e.stack.Variables {
+		variables[name] = v
+	}
+
+	e.run.Inputs = nil
+	for name, input := range e.stack.Inputs {
+		workspace := input.Workspace
+		if workspace == "" {
+			workspace = e.run.Workspace
+		}
+
+		outputs, err := o.outputs.LatestOutputs(ctx, input.Stack, workspace)
+		if errors.Is(err, internal.ErrOutputsNotFound) {
+			return nil, fmt.Errorf("input %s: stack %s workspace %s has not been applied by this runner", name, input.Stack, workspace)
+		} else if err != nil {
+			return nil, fmt.Errorf("input %s: unable to load outputs of stack %s: %w", name, input.Stack, err)
+		}
+		value, ok := outputs.Outputs[input.Output]
+		if !ok {
+			return nil, fmt.Errorf("input %s: stack %s workspace %s has no output %s", name, input.Stack, workspace, input.Output)
+		}
+
+		variables[name] = value.Value
+		e.run.Inputs = append(e.run.Inputs, internal.InputProvenance{
+			Variable:   name,
+			Stack:      input.Stack,
+			Workspace:  workspace,
+			Output:     input.Output,
+			RunID:      outputs.RunID,
+			CommitSHA:  outputs.CommitSHA,
+			CapturedAt: outputs.CapturedAt,
+		})
+	}
+
+	// Ordered so the provenance reads the same on every run
+	sort.Slice(e.run.Inputs, func(i, j int) bool {
+		return e.run.Inputs[i].Variable < e.run.Inputs[j].Variable
+	})
+	return variables, nil
+}
+
+// captureOutputs saves the outputs of the workspace a run applied so downstream stacks can use them
+func (o *orchestrator) captureOutputs(ctx context.Context, e *execution) error {
+	values, err := e.tf.Output(ctx)
+	if err != nil {
+		return fmt.Errorf("terraform output failed: %w", err)
+	}
+
+	return o.outputs.SaveOutputs(ctx, &internal.StackOutputs{
+		Stack:      e.run.Stack,
+		Workspace:  e.run.Workspace,
+		RunID:      e.run.ID,
+		CommitSHA:  e.run.CommitSHA,
+		Outputs:    values,
+		CapturedAt: time.Now().UTC(),
+	})
+}
+
+// writeVariables writes the stack's variables to the module directory so terraform plan picks them up
+func writeVariables(moduleDir string, variables map[string]interface{}) error {
+	if len(variables) == 0 {
+		return nil
+	}
+	b, err := json.MarshalIndent(variables, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode stack variables: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(moduleDir, varsFile), b, 0o600); err != nil {
+		return fmt.Errorf("unable to write stack variables: %w", err)
+	}
+	return nil
+}
+
+// prune removes the checkouts of finished runs of the workspace other than run. The checkout of the latest run is
+// kept so its state lock can be force unlocked, checkouts of runs that have not finished, such as runs awaiting
+// approval, are kept for their saved plan.
+func (o *orchestrator) prune(run *internal.Run) {
+	dir := o.workspaceDir(run.Stack, run.Workspace)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == run.ID {
+			continue
+		}
+		other, err := o.store.Get(context.Background(), entry.Name())
+		if err != nil && !errors.Is(err, internal.ErrRunNotFound) {
+			o.log.Errw(err, "Unable to look up run of checkout", "run_id", entry.Name())
+			continue
+		}
+		if other != nil && !other.Status.Terminal() {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			o.log.Errw(err, "Unable to remove checkout", "run_id", entry.Name())
+		}
+	}
+}
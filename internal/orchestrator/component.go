@@ -0,0 +1,23 @@
+package orchestrator
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent(
+	"orchestrator",
+	[]config.EnvVar{
+		config.EnvWorkDir,
+		config.EnvRunLogDir,
+		config.EnvTerraformInitTimeout,
+		config.EnvTerraformPlanTimeout,
+		config.EnvTerraformApplyTimeout,
+		config.EnvRetryInitAttempts,
+		config.EnvRetryPlanAttempts,
+		config.EnvRetryInitialBackoff,
+		config.EnvRetryMaxBackoff},
+	NewOrchestrator,
+	NewRecovery,
+	NewConfigValidator,
+)
@@ -0,0 +1,324 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	planFile = "tfplan"
+
+	// varsFile is written into the module directory with the stack's variables, terraform loads *.auto.tfvars.json
+	// files automatically
+	varsFile = "deploy-runner.auto.tfvars.json"
+)
+
+type orchestrator struct {
+	log      internal.BackgroundLog
+	git      internal.GitClient
+	tf       internal.TerraformFactory
+	locker   internal.StackLocker
+	queue    internal.RunQueue
+	store    internal.RunStore
+	stacks   internal.StackRegistry
+	outputs  internal.OutputStore
+	workDir  string
+	logDir   string
+	timeouts phaseTimeouts
+	retries  retryPolicy
+
+	mu sync.Mutex
+	// active holds the cancel func of each run currently executing
+	active map[string]context.CancelFunc
+	// cancelRequests holds who asked for a run to be cancelled, it is kept until the run finishes executing
+	cancelRequests map[string]string
+}
+
+func NewOrchestrator(cfg *viper.Viper, log internal.BackgroundLog, git internal.GitClient, tf internal.TerraformFactory, locker internal.StackLocker, queue internal.RunQueue, store internal.RunStore, stacks internal.StackRegistry, outputs internal.OutputStore) (internal.Orchestrator, error) {
+	retries, err := loadRetryPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &orchestrator{
+		log:      log.ChildLog("orchestrator"),
+		git:      git,
+		tf:       tf,
+		locker:   locker,
+		queue:    queue,
+		store:    store,
+		workDir:  cfg.GetString(config.WorkDir.String()),
+		logDir:   cfg.GetString(config.RunLogDir.String()),
+		stacks:   stacks,
+		outputs:  outputs,
+		timeouts: loadTimeouts(cfg),
+		retries:  retries,
+
+		active:         make(map[string]context.CancelFunc),
+		cancelRequests: make(map[string]string),
+	}, nil
+}
+
+func (o *orchestrator) Submit(ctx context.Context, req internal.DeployRequest) (*internal.Run, error) {
+	if err := req.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrInvalidRequest, err)
+	}
+
+	stack, ok := o.stacks.Get(req.Stack)
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown stack %s", internal.ErrInvalidRequest, req.Stack)
+	}
+	if req.Workspace != "" && !stack.HasWorkspace(req.Workspace) {
+		return nil, fmt.Errorf("%w: stack %s has no workspace %s", internal.ErrInvalidRequest, req.Stack, req.Workspace)
+	}
+
+	run := internal.NewRun(req, stack)
+	run.LogPath = filepath.Join(o.logDir, run.ID+".log")
+	if err := o.store.Create(ctx, run); err != nil {
+		return nil, fmt.Errorf("unable to save run: %w", err)
+	}
+
+	// A copy is returned since a worker can pick up and start mutating the queued run straight away
+	submitted := *run
+	position := o.queue.Enqueue(run)
+	o.log.InfowCtx(ctx, "Run queued", "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace, "requester", run.Requester, "position", position)
+	return &submitted, nil
+}
+
+// execution holds the state of a run while it is being executed
+type execution struct {
+	run      *internal.Run
+	stack    internal.Stack
+	dir      string
+	tf       internal.TerraformClient
+	output   io.Writer
+	tail     *tailWriter
+	timeouts phaseTimeouts
+}
+
+func (o *orchestrator) Execute(parent context.Context, run *internal.Run) error {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	registered := o.register(run.ID, cancel)
+	defer o.unregister(run.ID)
+	if !registered {
+		return o.fail(ctx, run, context.Canceled)
+	}
+
+	unlock, err := o.locker.Lock(ctx, run.Stack, run.Workspace)
+	if err != nil {
+		return o.fail(ctx, run, fmt.Errorf("unable to acquire stack lock: %w", err))
+	}
+	defer unlock()
+	defer o.prune(run)
+
+	stack, ok := o.stacks.Get(run.Stack)
+	if !ok {
+		return o.fail(ctx, run, fmt.Errorf("stack %s is no longer configured", run.Stack))
+	}
+
+	logFile, err := o.openLog(run)
+	if err != nil {
+		return o.fail(ctx, run, err)
+	}
+	defer logFile.Close()
+
+	e := &execution{
+		run:      run,
+		stack:    stack,
+		dir:      o.runDir(run),
+		tail:     newTailWriter(outputTailSize),
+		timeouts: o.timeouts.forStack(stack),
+	}
+	e.output = io.MultiWriter(logFile, e.tail)
+	if w := internal.RunOutput(parent); w != nil {
+		e.output = io.MultiWriter(e.output, w)
+	}
+
+	// An approved run already has a plan, it is applied from the checkout it was made in
+	if run.ApprovedBy != "" {
+		if err := o.resume(e); err != nil {
+			return o.failExecution(ctx, e, fmt.Errorf("unable to resume approved run: %w", err))
+		}
+	} else {
+		o.transition(ctx, run, internal.RunStatusPlanning)
+
+		if err := o.phase(ctx, e, phaseInit, e.timeouts.Init, o.initialize); err != nil {
+			return o.failExecution(ctx, e, fmt.Errorf("init phase failed: %w", err))
+		}
+
+		if err := o.phase(ctx, e, phasePlan, e.timeouts.Plan, o.plan); err != nil {
+			return o.failExecution(ctx, e, fmt.Errorf("plan phase failed: %w", err))
+		}
+
+		if run.PlanOnly || !run.HasChanges {
+			o.transition(ctx, run, internal.RunStatusPlanned)
+			return nil
+		}
+
+		if len(stack.Approvers) > 0 {
+			o.transition(ctx, run, internal.RunStatusAwaitingApproval)
+			return nil
+		}
+	}
+
+	o.transition(ctx, run, internal.RunStatusApplying)
+	if err := o.phase(ctx, e, phaseApply, e.timeouts.Apply, o.apply); err != nil {
+		return o.failExecution(ctx, e, fmt.Errorf("apply phase failed: %w", err))
+	}
+
+	// The apply has already succeeded so failing to capture outputs is logged rather than failing the run, downstream
+	// stacks keep using the previous outputs whose run is recorded in their provenance
+	if err := o.captureOutputs(ctx, e); err != nil {
+		o.log.Errw(err, "Unable to capture stack outputs", "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace)
+	}
+
+	o.transition(ctx, run, internal.RunStatusApplied)
+	return nil
+}
+
+// initialize checks out the run's commit and initializes terraform in it
+func (o *orchestrator) initialize(ctx context.Context, e *execution) error {
+	if err := os.RemoveAll(e.dir); err != nil {
+		return fmt.Errorf("unable to clean checkout directory: %w", err)
+	}
+
+	sha, err := o.git.Clone(ctx, e.run.Repository, e.run.Ref, e.dir)
+	if err != nil {
+		return err
+	}
+	e.run.CommitSHA = sha
+
+	variables, err := o.variables(ctx, e)
+	if err != nil {
+		return err
+	}
+	moduleDir := filepath.Join(e.dir, e.run.Path)
+	if err := writeVariables(moduleDir, variables); err != nil {
+		return err
+	}
+
+	if e.tf, err = o.tf.NewClient(moduleDir, e.output, terraformOptions(e.stack)); err != nil {
+		return err
+	}
+
+	if err := e.tf.Init(ctx); err != nil {
+		return fmt.Errorf("terraform init failed: %w", err)
+	}
+
+	if err := e.tf.Workspace(ctx, e.run.Workspace); err != nil {
+		return fmt.Errorf("terraform workspace select failed: %w", err)
+	}
+	return nil
+}
+
+// resume picks up the initialized checkout and saved plan of a run that waited for approval
+func (o *orchestrator) resume(e *execution) error {
+	moduleDir := filepath.Join(e.dir, e.run.Path)
+	if _, err := os.Stat(filepath.Join(moduleDir, planFile)); err != nil {
+		return fmt.Errorf("plan of the run is no longer available: %w", err)
+	}
+
+	var err error
+	e.tf, err = o.tf.NewClient(moduleDir, e.output, terraformOptions(e.stack))
+	return err
+}
+
+func (o *orchestrator) plan(ctx context.Context, e *execution) error {
+	changes, err := e.tf.Plan(ctx, planFile)
+	if err != nil {
+		return err
+	}
+	e.run.HasChanges = changes
+
+	if changes {
+		if e.run.Plan, err = e.tf.ShowPlan(ctx, planFile); err != nil {
+			return fmt.Errorf("terraform show failed: %w", err)
+		}
+	}
+	return nil
+}
+
+func (o *orchestrator) apply(ctx context.Context, e *execution) error {
+	return e.tf.Apply(ctx, planFile)
+}
+
+// phase runs a phase of the run, the timeout bounds the phase as a whole including any retries
+func (o *orchestrator) phase(ctx context.Context, e *execution, phase string, timeout time.Duration, f func(ctx context.Context, e *execution) error) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return o.attempt(ctx, e, phase, func(ctx context.Context) error {
+		return f(ctx, e)
+	})
+}
+
+func (o *orchestrator) workspaceDir(stack, workspace string) string {
+	return filepath.Join(o.workDir, stack, workspace)
+}
+
+// runDir is where a run checks out the stack, each run has its own directory so the plan of a run awaiting approval
+// is kept while other runs of the workspace execute
+func (o *orchestrator) runDir(run *internal.Run) string {
+	return filepath.Join(o.workspaceDir(run.Stack, run.Workspace), run.ID)
+}
+
+func (o *orchestrator) openLog(run *internal.Run) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(run.LogPath), 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create run log directory: %w", err)
+	}
+	f, err := os.OpenFile(run.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open run log: %w", err)
+	}
+	return f, nil
+}
+
+func (o *orchestrator) transition(ctx context.Context, run *internal.Run, status internal.RunStatus) {
+	o.log.Infow("Run status changed", "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace, "from", run.Status, "to", status)
+	run.Status = status
+	o.save(ctx, run)
+}
+
+func (o *orchestrator) save(ctx context.Context, run *internal.Run) {
+	run.UpdatedAt = time.Now().UTC()
+	if err := o.store.Update(ctx, run); err != nil {
+		o.log.Errw(err, "Unable to save run", "run_id", run.ID, "status", run.Status)
+	}
+}
+
+// failExecution fails a run that got as far as running terraform keeping the tail of its output
+func (o *orchestrator) failExecution(ctx context.Context, e *execution, err error) error {
+	e.run.OutputTail = e.tail.String()
+	return o.fail(ctx, e.run, err)
+}
+
+func (o *orchestrator) fail(ctx context.Context, run *internal.Run, err error) error {
+	status := internal.RunStatusFailed
+	var locked *internal.StateLockedError
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		status = internal.RunStatusCancelled
+		if run.Status == internal.RunStatusApplying {
+			status = internal.RunStatusCancelledDuringApply
+		}
+		run.CancelledBy = o.cancelRequester(run.ID)
+	case errors.Is(err, context.DeadlineExceeded):
+		status = internal.RunStatusTimedOut
+	case errors.As(err, &locked):
+		status = internal.RunStatusStateLocked
+	}
+
+	run.Error = err.Error()
+	// ctx may already be cancelled but the final status still has to be saved
+	o.transition(context.Background(), run, status)
+	return err
+}
@@ -0,0 +1,433 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/locking"
+	"deploy-runner/internal/logging"
+	"deploy-runner/internal/queue"
+	"deploy-runner/internal/stacks"
+	"errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrchestrator(t *testing.T) {
+	t.Run("TestApply", testApply)
+	t.Run("TestStateLocked", testStateLocked)
+	t.Run("TestCancelQueued", testCancelQueued)
+	t.Run("TestCancelDuringPlan", testCancelDuringPlan)
+	t.Run("TestCancelDuringApply", testCancelDuringApply)
+	t.Run("TestPlanTimeout", testPlanTimeout)
+	t.Run("TestRetryPlan", testRetryPlan)
+	t.Run("TestNoRetryApply", testNoRetryApply)
+	t.Run("TestUnknownStack", testUnknownStack)
+	t.Run("TestApproval", testApproval)
+	t.Run("TestInputsFromUpstream", testInputsFromUpstream)
+	t.Run("TestRunOutput", testRunOutput)
+}
+
+type fakeGit struct{}
+
+func (g *fakeGit) Clone(ctx context.Context, repo, ref, dir string) (string, error) {
+	return "0123456789abcdef0123456789abcdef01234567", os.MkdirAll(dir, 0o755)
+}
+
+type fakeTerraform struct {
+	plan    func(ctx context.Context) (bool, error)
+	apply   func(ctx context.Context) error
+	output  io.Writer
+	workDir string
+}
+
+func (f *fakeTerraform) NewClient(workDir string, output io.Writer, opts internal.TerraformOptions) (internal.TerraformClient, error) {
+	f.output = output
+	f.workDir = workDir
+	return f, nil
+}
+
+func (f *fakeTerraform) Init(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeTerraform) Workspace(ctx context.Context, name string) error {
+	return nil
+}
+
+func (f *fakeTerraform) Plan(ctx context.Context, planFile string) (bool, error) {
+	if f.plan == nil {
+		return true, os.WriteFile(filepath.Join(f.workDir, planFile), nil, 0o644)
+	}
+	return f.plan(ctx)
+}
+
+func (f *fakeTerraform) ShowPlan(ctx context.Context, planFile string) (*internal.PlanSummary, error) {
+	return &internal.PlanSummary{Add: 1}, nil
+}
+
+func (f *fakeTerraform) Apply(ctx context.Context, planFile string) error {
+	if f.apply == nil {
+		return nil
+	}
+	return f.apply(ctx)
+}
+
+func (f *fakeTerraform) Output(ctx context.Context) (map[string]internal.OutputValue, error) {
+	return map[string]internal.OutputValue{"vpc_id": {Value: []byte(`"vpc-123"`)}}, nil
+}
+
+func (f *fakeTerraform) ForceUnlock(ctx context.Context, lockID string) error {
+	return nil
+}
+
+func (f *fakeTerraform) Validate(ctx context.Context) ([]internal.TerraformDiagnostic, error) {
+	return nil, nil
+}
+
+func (f *fakeTerraform) FormatCheck(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeTerraform) Test(ctx context.Context) error {
+	return nil
+}
+
+// memOutputs is an in memory internal.OutputStore
+type memOutputs struct {
+	mu      sync.Mutex
+	outputs map[string]internal.StackOutputs
+}
+
+func (s *memOutputs) SaveOutputs(ctx context.Context, outputs *internal.StackOutputs) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs[outputs.Stack+"/"+outputs.Workspace] = *outputs
+	return nil
+}
+
+func (s *memOutputs) LatestOutputs(ctx context.Context, stack, workspace string) (*internal.StackOutputs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	outputs, ok := s.outputs[stack+"/"+workspace]
+	if !ok {
+		return nil, internal.ErrOutputsNotFound
+	}
+	return &outputs, nil
+}
+
+// memStore is an in memory internal.RunStore
+type memStore struct {
+	mu   sync.Mutex
+	runs map[string]internal.Run
+}
+
+func (s *memStore) Create(ctx context.Context, run *internal.Run) error {
+	return s.Update(ctx, run)
+}
+
+func (s *memStore) Update(ctx context.Context, run *internal.Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = *run
+	return nil
+}
+
+func (s *memStore) UpdateWithEvent(ctx context.Context, run *internal.Run, eventType internal.EventType, message string) error {
+	return s.Update(ctx, run)
+}
+
+func (s *memStore) Get(ctx context.Context, id string) (*internal.Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, internal.ErrRunNotFound
+	}
+	return &run, nil
+}
+
+func (s *memStore) List(ctx context.Context, filter internal.RunFilter) (internal.RunPage, error) {
+	return internal.RunPage{}, nil
+}
+
+func (s *memStore) Transitions(ctx context.Context, id string) ([]internal.PhaseTransition, error) {
+	return nil, nil
+}
+
+func newTestOrchestrator(t *testing.T, tf *fakeTerraform) (*orchestrator, internal.RunQueue, *memStore) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.WorkDir.String(), t.TempDir())
+	cfg.Set(config.RunLogDir.String(), t.TempDir())
+	cfg.Set(config.TerraformInitTimeout.String(), "1m")
+	cfg.Set(config.TerraformPlanTimeout.String(), "1m")
+	cfg.Set(config.TerraformApplyTimeout.String(), "1m")
+	cfg.Set(config.RetryInitAttempts.String(), 3)
+	cfg.Set(config.RetryPlanAttempts.String(), 3)
+	cfg.Set(config.RetryInitialBackoff.String(), "1ms")
+	cfg.Set(config.RetryMaxBackoff.String(), "5ms")
+	cfg.Set(config.RetryableErrors.String(), []string{"connection reset by peer"})
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network":  map[string]interface{}{"repository": "https://example.com/infra.git", "workspaces": []string{"dev", "prod"}},
+		"slow":     map[string]interface{}{"repository": "https://example.com/infra.git", "timeouts": map[string]interface{}{"plan": "50ms"}},
+		"reviewed": map[string]interface{}{"repository": "https://example.com/infra.git", "approvers": []string{"carol"}},
+		"cluster": map[string]interface{}{
+			"repository": "https://example.com/infra.git",
+			"workspaces": []string{"dev"},
+			"depends_on": []string{"network"},
+			"inputs":     map[string]interface{}{"vpc_id": map[string]interface{}{"stack": "network", "output": "vpc_id"}},
+		},
+	})
+
+	registry, err := stacks.NewRegistry(cfg)
+	assert.NoError(t, err)
+	q := queue.NewRunQueue()
+	store := &memStore{runs: make(map[string]internal.Run)}
+	outputs := &memOutputs{outputs: make(map[string]internal.StackOutputs)}
+	o, err := NewOrchestrator(cfg, logging.NewBackgroundLog(cfg), &fakeGit{}, tf, locking.NewStackLocker(), q, store, registry, outputs)
+	assert.NoError(t, err)
+	return o.(*orchestrator), q, store
+}
+
+func submit(t *testing.T, o *orchestrator, q internal.RunQueue) *internal.Run {
+	return submitStack(t, o, q, "network")
+}
+
+func submitStack(t *testing.T, o *orchestrator, q internal.RunQueue, stack string) *internal.Run {
+	_, err := o.Submit(context.Background(), internal.DeployRequest{Stack: stack, Requester: "alice"})
+	assert.NoError(t, err)
+	run, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	return run
+}
+
+func testApply(t *testing.T) {
+	o, q, store := newTestOrchestrator(t, &fakeTerraform{})
+	run := submit(t, o, q)
+
+	assert.NoError(t, o.Execute(context.Background(), run))
+	saved, err := store.Get(context.Background(), run.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RunStatusApplied, saved.Status)
+	assert.Equal(t, 1, saved.Plan.Add)
+}
+
+func testStateLocked(t *testing.T) {
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{plan: func(ctx context.Context) (bool, error) {
+		return false, &internal.StateLockedError{LockID: "abc"}
+	}})
+	run := submit(t, o, q)
+
+	assert.Error(t, o.Execute(context.Background(), run))
+	assert.Equal(t, internal.RunStatusStateLocked, run.Status)
+}
+
+func testCancelQueued(t *testing.T) {
+	o, q, store := newTestOrchestrator(t, &fakeTerraform{})
+	run, err := o.Submit(context.Background(), internal.DeployRequest{Stack: "network", Requester: "alice"})
+	assert.NoError(t, err)
+
+	_, err = o.CancelRun(context.Background(), run.ID, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, q.Stats().Depth)
+
+	saved, _ := store.Get(context.Background(), run.ID)
+	assert.Equal(t, internal.RunStatusCancelled, saved.Status)
+	assert.Equal(t, "bob", saved.CancelledBy)
+
+	_, err = o.CancelRun(context.Background(), run.ID, "bob")
+	assert.ErrorIs(t, err, internal.ErrRunNotCancellable)
+}
+
+// blockUntilCancelled stands in for a terraform command that runs until it is interrupted
+func blockUntilCancelled(started chan struct{}) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.New("not cancelled")
+		}
+	}
+}
+
+func testCancelDuringPlan(t *testing.T) {
+	started := make(chan struct{})
+	block := blockUntilCancelled(started)
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{plan: func(ctx context.Context) (bool, error) {
+		return false, block(ctx)
+	}})
+	run := submit(t, o, q)
+
+	go func() {
+		<-started
+		_, _ = o.CancelRun(context.Background(), run.ID, "bob")
+	}()
+	assert.ErrorIs(t, o.Execute(context.Background(), run), context.Canceled)
+	assert.Equal(t, internal.RunStatusCancelled, run.Status)
+	assert.Equal(t, "bob", run.CancelledBy)
+}
+
+func testCancelDuringApply(t *testing.T) {
+	started := make(chan struct{})
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{apply: blockUntilCancelled(started)})
+	run := submit(t, o, q)
+
+	go func() {
+		<-started
+		_, _ = o.CancelRun(context.Background(), run.ID, "bob")
+	}()
+	assert.ErrorIs(t, o.Execute(context.Background(), run), context.Canceled)
+	assert.Equal(t, internal.RunStatusCancelledDuringApply, run.Status)
+}
+
+func testPlanTimeout(t *testing.T) {
+	tf := &fakeTerraform{}
+	tf.plan = func(ctx context.Context) (bool, error) {
+		_, _ = io.WriteString(tf.output, "Refreshing state...\nStill waiting on provider\n")
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	o, q, _ := newTestOrchestrator(t, tf)
+	run := submitStack(t, o, q, "slow")
+
+	assert.ErrorIs(t, o.Execute(context.Background(), run), context.DeadlineExceeded)
+	assert.Equal(t, internal.RunStatusTimedOut, run.Status)
+	assert.Equal(t, "Refreshing state...\nStill waiting on provider", run.OutputTail)
+}
+
+func testRetryPlan(t *testing.T) {
+	calls := 0
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{plan: func(ctx context.Context) (bool, error) {
+		calls++
+		if calls < 3 {
+			return false, errors.New("Error: Failed to query available provider packages: read: connection reset by peer")
+		}
+		return true, nil
+	}})
+	run := submit(t, o, q)
+
+	assert.NoError(t, o.Execute(context.Background(), run))
+	assert.Equal(t, internal.RunStatusApplied, run.Status)
+
+	phases := make([]string, 0)
+	for _, a := range run.Attempts {
+		phases = append(phases, a.Phase)
+	}
+	assert.Equal(t, []string{"init", "plan", "plan", "plan", "apply"}, phases)
+	assert.True(t, run.Attempts[1].Retryable)
+	assert.Empty(t, run.Attempts[3].Error)
+}
+
+func testNoRetryApply(t *testing.T) {
+	calls := 0
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{apply: func(ctx context.Context) error {
+		calls++
+		return errors.New("connection reset by peer")
+	}})
+	run := submit(t, o, q)
+
+	assert.Error(t, o.Execute(context.Background(), run))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, internal.RunStatusFailed, run.Status)
+}
+
+func testUnknownStack(t *testing.T) {
+	o, _, _ := newTestOrchestrator(t, &fakeTerraform{})
+
+	_, err := o.Submit(context.Background(), internal.DeployRequest{Stack: "unknown", Requester: "alice"})
+	assert.ErrorIs(t, err, internal.ErrInvalidRequest)
+
+	_, err = o.Submit(context.Background(), internal.DeployRequest{Stack: "network", Workspace: "staging", Requester: "alice"})
+	assert.ErrorIs(t, err, internal.ErrInvalidRequest)
+
+	run, err := o.Submit(context.Background(), internal.DeployRequest{Stack: "network", Requester: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, "dev", run.Workspace)
+	assert.Equal(t, "https://example.com/infra.git", run.Repository)
+}
+
+func testApproval(t *testing.T) {
+	applied := 0
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{apply: func(ctx context.Context) error {
+		applied++
+		return nil
+	}})
+	run := submitStack(t, o, q, "reviewed")
+
+	assert.NoError(t, o.Execute(context.Background(), run))
+	assert.Equal(t, internal.RunStatusAwaitingApproval, run.Status)
+	assert.Equal(t, 0, applied)
+	q.Done(run)
+
+	_, err := o.Approve(context.Background(), run.ID, "alice")
+	assert.ErrorIs(t, err, internal.ErrNotApprover)
+
+	_, err = o.Approve(context.Background(), run.ID, "carol")
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = o.Reject(context.Background(), run.ID, "carol", "")
+	assert.ErrorIs(t, err, internal.ErrRunNotAwaitingApproval)
+
+	approved, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, o.Execute(context.Background(), approved))
+	assert.Equal(t, internal.RunStatusApplied, approved.Status)
+	assert.Equal(t, "carol", approved.ApprovedBy)
+	assert.Equal(t, 1, applied)
+}
+
+func testInputsFromUpstream(t *testing.T) {
+	o, q, _ := newTestOrchestrator(t, &fakeTerraform{})
+
+	// cluster can not be planned before network has been applied
+	run := submitStack(t, o, q, "cluster")
+	assert.Error(t, o.Execute(context.Background(), run))
+	assert.Contains(t, run.Error, "has not been applied")
+	q.Done(run)
+
+	upstream := submit(t, o, q)
+	assert.NoError(t, o.Execute(context.Background(), upstream))
+	q.Done(upstream)
+
+	run = submitStack(t, o, q, "cluster")
+	assert.NoError(t, o.Execute(context.Background(), run))
+	if !assert.Len(t, run.Inputs, 1) {
+		return
+	}
+	assert.Equal(t, upstream.ID, run.Inputs[0].RunID)
+	assert.Equal(t, "vpc_id", run.Inputs[0].Output)
+
+	vars, err := os.ReadFile(filepath.Join(o.runDir(run), varsFile))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"vpc_id": "vpc-123"}`, string(vars))
+}
+
+func testRunOutput(t *testing.T) {
+	tf := &fakeTerraform{}
+	tf.apply = func(ctx context.Context) error {
+		_, err := tf.output.Write([]byte("Apply complete!\n"))
+		return err
+	}
+	o, q, _ := newTestOrchestrator(t, tf)
+	run := submit(t, o, q)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, o.Execute(internal.WithRunOutput(context.Background(), out), run))
+	assert.Equal(t, "Apply complete!\n", out.String())
+
+	logs, err := os.ReadFile(run.LogPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "Apply complete!\n", string(logs))
+}
@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"fmt"
+	"go.uber.org/fx"
+	"sort"
+	"time"
+)
+
+type serviceOut struct {
+	fx.Out
+	Service app.Service `group:"services"`
+}
+
+// recovery looks for runs left in a non terminal state by a previous process on startup and settles them. The recovery
+// events are recorded in the outbox together with the runs so they are published like every other run event.
+type recovery struct {
+	log   internal.BackgroundLog
+	store internal.RunStore
+	queue internal.RunQueue
+}
+
+func NewRecovery(log internal.BackgroundLog, store internal.RunStore, queue internal.RunQueue) serviceOut {
+	return serviceOut{Service: &recovery{
+		log:   log.ChildLog("recovery"),
+		store: store,
+		queue: queue,
+	}}
+}
+
+func (r *recovery) Start(ctx context.Context) error {
+	queued, err := r.listAll(ctx, internal.RunStatusQueued)
+	if err != nil {
+		return err
+	}
+	// Runs are listed newest first, re-enqueue them in their original arrival order
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].CreatedAt.Before(queued[j].CreatedAt)
+	})
+	for _, run := range queued {
+		// Recorded before the run is queued again so a worker picking it up straight away is not overwritten
+		if err := r.store.UpdateWithEvent(ctx, run, internal.EventRunRequeued, "run was queued when the runner stopped and has been queued again"); err != nil {
+			r.log.Errw(err, "Unable to save recovered run", "run_id", run.ID, "status", run.Status)
+		}
+		r.queue.Enqueue(run)
+	}
+
+	planning, err := r.listAll(ctx, internal.RunStatusPlanning)
+	if err != nil {
+		return err
+	}
+	for _, run := range planning {
+		// Planning never changes infrastructure so the run can just be failed and submitted again by the requester
+		r.settle(ctx, run, internal.RunStatusFailed, internal.EventRunInterrupted, "runner stopped while the run was planning")
+	}
+
+	applying, err := r.listAll(ctx, internal.RunStatusApplying)
+	if err != nil {
+		return err
+	}
+	for _, run := range applying {
+		// The apply may have partially completed and the state lock may still be held, neither is safe to fix
+		// automatically so the state lock is left in place for a human to look at
+		r.settle(ctx, run, internal.RunStatusNeedsAttention, internal.EventRunNeedsAttention, "runner stopped while the run was applying, the state lock may still be held")
+	}
+
+	r.log.Infow("Recovered runs", "requeued", len(queued), "interrupted", len(planning), "needs_attention", len(applying))
+	return nil
+}
+
+func (r *recovery) Stop(ctx context.Context) error {
+	return nil
+}
+
+func (r *recovery) Disabled() bool {
+	return false
+}
+
+func (r *recovery) settle(ctx context.Context, run *internal.Run, status internal.RunStatus, eventType internal.EventType, message string) {
+	run.Status = status
+	run.Error = message
+	run.UpdatedAt = time.Now().UTC()
+	if err := r.store.UpdateWithEvent(ctx, run, eventType, message); err != nil {
+		r.log.Errw(err, "Unable to save recovered run", "run_id", run.ID, "status", status)
+	}
+}
+
+func (r *recovery) listAll(ctx context.Context, status internal.RunStatus) ([]*internal.Run, error) {
+	runs := make([]*internal.Run, 0)
+	filter := internal.RunFilter{Status: status}
+	for {
+		page, err := r.store.List(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list %s runs: %w", status, err)
+		}
+		runs = append(runs, page.Runs...)
+		if page.NextCursor == "" {
+			return runs, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
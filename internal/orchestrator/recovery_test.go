@@ -0,0 +1,141 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"deploy-runner/internal/store"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/fx/fxtest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecovery(t *testing.T) {
+	t.Run("TestSettlesInterruptedRuns", testSettlesInterruptedRuns)
+	t.Run("TestNothingToRecover", testNothingToRecover)
+}
+
+// recordingQueue records the runs enqueued, recovery never takes runs out of the queue
+type recordingQueue struct {
+	internal.RunQueue
+	enqueued []string
+}
+
+func (q *recordingQueue) Enqueue(run *internal.Run) int {
+	q.enqueued = append(q.enqueued, run.ID)
+	return len(q.enqueued)
+}
+
+// newTestRecovery returns a recovery using the bbolt store, the outbox is the one the run store records events in
+func newTestRecovery(t *testing.T) (*recovery, internal.RunStore, internal.EventOutbox, *recordingQueue) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.StorePath.String(), filepath.Join(t.TempDir(), "runs.db"))
+
+	lc := fxtest.NewLifecycle(t)
+	db, err := store.NewDB(cfg, lc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lc.RequireStart()
+	t.Cleanup(func() { lc.RequireStop() })
+
+	s := store.NewRunStore(db)
+	q := &recordingQueue{}
+	r := NewRecovery(logging.NewBackgroundLog(cfg), s, q).Service.(*recovery)
+	return r, s, store.NewEventOutbox(db), q
+}
+
+// recorded returns the type and run of the events recorded in the outbox after sequence
+func recorded(t *testing.T, outbox internal.EventOutbox, after uint64) []recordedEvent {
+	entries, err := outbox.Pending(context.Background(), after, 100)
+	assert.NoError(t, err)
+	events := make([]recordedEvent, 0, len(entries))
+	for _, e := range entries {
+		events = append(events, recordedEvent{Type: e.Event.Type, RunID: e.Event.Run.ID, Status: e.Event.Run.Status})
+	}
+	return events
+}
+
+type recordedEvent struct {
+	Type   internal.EventType
+	RunID  string
+	Status internal.RunStatus
+}
+
+// lastSequence returns the sequence of the newest event in the outbox
+func lastSequence(t *testing.T, outbox internal.EventOutbox) uint64 {
+	entries, err := outbox.Pending(context.Background(), 0, 100)
+	assert.NoError(t, err)
+	if len(entries) == 0 {
+		return 0
+	}
+	return entries[len(entries)-1].Sequence
+}
+
+// createRun saves a run that the previous process left with the given status
+func createRun(t *testing.T, s internal.RunStore, id string, status internal.RunStatus, created time.Time) {
+	run := &internal.Run{ID: id, Stack: "network", Workspace: "default", Requester: "alice", Status: internal.RunStatusQueued, CreatedAt: created, UpdatedAt: created}
+	assert.NoError(t, s.Create(context.Background(), run))
+	if status != internal.RunStatusQueued {
+		run.Status = status
+		assert.NoError(t, s.Update(context.Background(), run))
+	}
+}
+
+func testSettlesInterruptedRuns(t *testing.T) {
+	r, s, outbox, q := newTestRecovery(t)
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	createRun(t, s, "queued-first", internal.RunStatusQueued, start)
+	createRun(t, s, "queued-third", internal.RunStatusQueued, start.Add(2*time.Hour))
+	createRun(t, s, "queued-second", internal.RunStatusQueued, start.Add(time.Hour))
+	createRun(t, s, "planning", internal.RunStatusPlanning, start.Add(3*time.Hour))
+	createRun(t, s, "applying", internal.RunStatusApplying, start.Add(4*time.Hour))
+	createRun(t, s, "applied", internal.RunStatusApplied, start.Add(5*time.Hour))
+	before := lastSequence(t, outbox)
+
+	assert.NoError(t, r.Start(ctx))
+
+	// Queued runs go back in the queue in the order they were submitted
+	assert.Equal(t, []string{"queued-first", "queued-second", "queued-third"}, q.enqueued)
+
+	planning, err := s.Get(ctx, "planning")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RunStatusFailed, planning.Status)
+	assert.NotEmpty(t, planning.Error)
+
+	applying, err := s.Get(ctx, "applying")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RunStatusNeedsAttention, applying.Status)
+	assert.NotEmpty(t, applying.Error)
+
+	applied, err := s.Get(ctx, "applied")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RunStatusApplied, applied.Status)
+
+	// Only the recovery events are recorded, together with the runs they settled
+	assert.Equal(t, []recordedEvent{
+		{internal.EventRunRequeued, "queued-first", internal.RunStatusQueued},
+		{internal.EventRunRequeued, "queued-second", internal.RunStatusQueued},
+		{internal.EventRunRequeued, "queued-third", internal.RunStatusQueued},
+		{internal.EventRunInterrupted, "planning", internal.RunStatusFailed},
+		{internal.EventRunNeedsAttention, "applying", internal.RunStatusNeedsAttention},
+	}, recorded(t, outbox, before))
+}
+
+func testNothingToRecover(t *testing.T) {
+	r, s, outbox, q := newTestRecovery(t)
+	createRun(t, s, "applied", internal.RunStatusApplied, time.Now().UTC())
+	before := lastSequence(t, outbox)
+
+	assert.NoError(t, r.Start(context.Background()))
+	assert.Empty(t, q.enqueued)
+	assert.Empty(t, recorded(t, outbox, before))
+}
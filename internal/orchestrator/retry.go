@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"regexp"
+	"time"
+)
+
+const (
+	phaseInit  = "init"
+	phasePlan  = "plan"
+	phaseApply = "apply"
+)
+
+type retryPolicy struct {
+	attempts       map[string]int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	retryable      []*regexp.Regexp
+}
+
+func loadRetryPolicy(cfg *viper.Viper) (retryPolicy, error) {
+	p := retryPolicy{
+		attempts: map[string]int{
+			phaseInit: cfg.GetInt(config.RetryInitAttempts.String()),
+			phasePlan: cfg.GetInt(config.RetryPlanAttempts.String()),
+			// Applies are never retried since a failed apply can leave infrastructure partially changed
+			phaseApply: 1,
+		},
+		initialBackoff: cfg.GetDuration(config.RetryInitialBackoff.String()),
+		maxBackoff:     cfg.GetDuration(config.RetryMaxBackoff.String()),
+	}
+
+	for _, pattern := range cfg.GetStringSlice(config.RetryableErrors.String()) {
+		r, err := regexp.Compile(pattern)
+		if err != nil {
+			return p, fmt.Errorf("invalid %s pattern %q: %w", config.RetryableErrors, pattern, err)
+		}
+		p.retryable = append(p.retryable, r)
+	}
+	return p, nil
+}
+
+// isRetryable classifies an error as transient by matching it against the known retryable messages. Cancellations,
+// timeouts and state lock errors are never retryable.
+func (p retryPolicy) isRetryable(err error) bool {
+	var locked *internal.StateLockedError
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &locked) {
+		return false
+	}
+
+	message := err.Error()
+	for _, r := range p.retryable {
+		if r.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the wait before the given retry, doubling each time up to the max
+func (p retryPolicy) backoff(retry int) time.Duration {
+	wait := p.initialBackoff
+	for i := 1; i < retry && wait < p.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.maxBackoff {
+		wait = p.maxBackoff
+	}
+	return wait
+}
+
+// attempt runs a phase recording each try on the run and retrying transient failures according to the policy
+func (o *orchestrator) attempt(ctx context.Context, e *execution, phase string, f func(ctx context.Context) error) error {
+	maxAttempts := o.retries.attempts[phase]
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for number := 1; ; number++ {
+		a := internal.Attempt{Phase: phase, Number: number, StartedAt: time.Now().UTC()}
+		err := f(ctx)
+		a.FinishedAt = time.Now().UTC()
+		if err != nil {
+			a.Error = err.Error()
+			a.Retryable = o.retries.isRetryable(err)
+		}
+		e.run.Attempts = append(e.run.Attempts, a)
+		o.save(ctx, e.run)
+
+		if err == nil || !a.Retryable || number >= maxAttempts {
+			return err
+		}
+
+		wait := o.retries.backoff(number)
+		o.log.Warnw("Retrying run phase after transient failure", "run_id", e.run.ID, "phase", phase, "attempt", number, "backoff", wait, "error", err.Error())
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fmt.Errorf("%w (while waiting to retry after: %v)", ctx.Err(), err)
+		}
+	}
+}
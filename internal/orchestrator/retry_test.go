@@ -0,0 +1,56 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("TestIsRetryable", testIsRetryable)
+	t.Run("TestBackoff", testBackoff)
+	t.Run("TestInvalidPattern", testInvalidPattern)
+}
+
+func newTestPolicy(t *testing.T) retryPolicy {
+	cfg := viper.New()
+	cfg.Set(config.RetryInitialBackoff.String(), "1s")
+	cfg.Set(config.RetryMaxBackoff.String(), "5s")
+	cfg.Set(config.RetryableErrors.String(), []string{"connection reset by peer", "(502|503) "})
+	p, err := loadRetryPolicy(cfg)
+	assert.NoError(t, err)
+	return p
+}
+
+func testIsRetryable(t *testing.T) {
+	p := newTestPolicy(t)
+
+	assert.True(t, p.isRetryable(errors.New("read tcp: connection reset by peer")))
+	assert.True(t, p.isRetryable(errors.New("registry responded with 503 Service Unavailable")))
+	assert.False(t, p.isRetryable(errors.New("Error: Unsupported argument")))
+	assert.False(t, p.isRetryable(fmt.Errorf("connection reset by peer: %w", context.DeadlineExceeded)))
+	assert.False(t, p.isRetryable(&internal.StateLockedError{Err: errors.New("connection reset by peer")}))
+}
+
+func testBackoff(t *testing.T) {
+	p := newTestPolicy(t)
+
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 4*time.Second, p.backoff(3))
+	assert.Equal(t, 5*time.Second, p.backoff(4))
+	assert.Equal(t, 5*time.Second, p.backoff(10))
+}
+
+func testInvalidPattern(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set(config.RetryableErrors.String(), []string{"("})
+	_, err := loadRetryPolicy(cfg)
+	assert.Error(t, err)
+}
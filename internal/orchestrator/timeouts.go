@@ -0,0 +1,41 @@
+package orchestrator
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"github.com/spf13/viper"
+	"time"
+)
+
+type phaseTimeouts struct {
+	Init  time.Duration
+	Plan  time.Duration
+	Apply time.Duration
+}
+
+// merge fills any phase without a timeout from defaults
+func (t phaseTimeouts) merge(defaults phaseTimeouts) phaseTimeouts {
+	if t.Init <= 0 {
+		t.Init = defaults.Init
+	}
+	if t.Plan <= 0 {
+		t.Plan = defaults.Plan
+	}
+	if t.Apply <= 0 {
+		t.Apply = defaults.Apply
+	}
+	return t
+}
+
+// forStack returns the timeouts of a stack, phases the stack does not override use the receiver's timeouts
+func (t phaseTimeouts) forStack(stack internal.Stack) phaseTimeouts {
+	return phaseTimeouts(stack.Timeouts).merge(t)
+}
+
+func loadTimeouts(cfg *viper.Viper) phaseTimeouts {
+	return phaseTimeouts{
+		Init:  cfg.GetDuration(config.TerraformInitTimeout.String()),
+		Plan:  cfg.GetDuration(config.TerraformPlanTimeout.String()),
+		Apply: cfg.GetDuration(config.TerraformApplyTimeout.String()),
+	}
+}
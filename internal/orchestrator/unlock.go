@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var errModuleFound = errors.New("module found")
+
+func (o *orchestrator) ForceUnlock(ctx context.Context, stack, workspace, lockID string) error {
+	unlock, ok := o.locker.TryLock(stack, workspace)
+	if !ok {
+		return internal.ErrStackBusy
+	}
+	defer unlock()
+
+	dir := o.workspaceDir(stack, workspace)
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		return internal.ErrWorkspaceNotFound
+	}
+
+	// The run that checked out the workspace is not known here so find an initialized module by its .terraform
+	// directory, every checkout of the workspace shares the same state
+	moduleDir, err := findModuleDir(dir)
+	if err != nil {
+		return err
+	}
+
+	s, _ := o.stacks.Get(stack)
+	tf, err := o.tf.NewClient(moduleDir, nil, terraformOptions(s))
+	if err != nil {
+		return err
+	}
+
+	return tf.ForceUnlock(ctx, lockID)
+}
+
+func findModuleDir(root string) (string, error) {
+	found := ""
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() && d.Name() == ".terraform" {
+			found = filepath.Dir(path)
+			return errModuleFound
+		}
+		return nil
+	})
+	if err != nil && err != errModuleFound {
+		return "", err
+	}
+	if found == "" {
+		return "", internal.ErrWorkspaceNotFound
+	}
+	return found, nil
+}
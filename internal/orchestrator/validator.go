@@ -0,0 +1,33 @@
+package orchestrator
+
+import (
+	"deploy-runner/config"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+type validatorOut struct {
+	fx.Out
+	Validator config.Validator `group:"configValidators"`
+}
+
+type configValidator struct {
+	cfg *viper.Viper
+}
+
+func NewConfigValidator(cfg *viper.Viper) validatorOut {
+	return validatorOut{Validator: &configValidator{cfg: cfg}}
+}
+
+func (v *configValidator) Validate() error {
+	t := loadTimeouts(v.cfg)
+	if t.Init <= 0 || t.Plan <= 0 || t.Apply <= 0 {
+		return fmt.Errorf("%s, %s and %s must all be greater than zero", config.TerraformInitTimeout, config.TerraformPlanTimeout, config.TerraformApplyTimeout)
+	}
+
+	if _, err := loadRetryPolicy(v.cfg); err != nil {
+		return err
+	}
+	return nil
+}
@@ -1 +1,26 @@
 package internal
+
+// OutputFormat is how client commands print their results, it is selected with the --output flag
+type OutputFormat string
+
+const (
+	OutputFormatTable OutputFormat = "table"
+	OutputFormatJSON  OutputFormat = "json"
+	OutputFormatYAML  OutputFormat = "yaml"
+)
+
+// OutputTemplatePrefix is followed by a Go text/template in the output format, e.g. go-template={{.status}}
+const OutputTemplatePrefix = "go-template="
+
+// Table is the table view of a command result with one row per item
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// OutputWriter prints the result of a client command in the selected output format. The json, yaml and template
+// formats all work on the json form of the value so field names are the same in every machine readable format, table
+// is printed instead of the value for the table format.
+type OutputWriter interface {
+	Write(value interface{}, table Table) error
+}
@@ -0,0 +1,8 @@
+package output
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("output", []config.EnvVar{config.EnvOutputFormat}, NewWriter, NewConfigValidator)
@@ -0,0 +1,108 @@
+package output
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+type writer struct {
+	format   internal.OutputFormat
+	template *template.Template
+	out      io.Writer
+}
+
+func NewWriter(cfg *viper.Viper) (internal.OutputWriter, error) {
+	return New(cfg.GetString(config.OutputFormat.String()), os.Stdout)
+}
+
+// New returns an OutputWriter printing to out in format
+func New(format string, out io.Writer) (internal.OutputWriter, error) {
+	f, tmpl, err := parseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	return &writer{format: f, template: tmpl, out: out}, nil
+}
+
+// parseFormat parses the output format, the template is only set for the go-template format
+func parseFormat(format string) (internal.OutputFormat, *template.Template, error) {
+	switch f := internal.OutputFormat(format); f {
+	case internal.OutputFormatTable, internal.OutputFormatJSON, internal.OutputFormatYAML:
+		return f, nil, nil
+	}
+
+	if !strings.HasPrefix(format, internal.OutputTemplatePrefix) {
+		return "", nil, fmt.Errorf("unknown output format %q, expected %s, %s, %s or %s<template>", format,
+			internal.OutputFormatTable, internal.OutputFormatJSON, internal.OutputFormatYAML, internal.OutputTemplatePrefix)
+	}
+	tmpl, err := template.New("output").Option("missingkey=error").Parse(strings.TrimPrefix(format, internal.OutputTemplatePrefix))
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid output template: %w", err)
+	}
+	return "", tmpl, nil
+}
+
+func (w *writer) Write(value interface{}, table internal.Table) error {
+	switch {
+	case w.template != nil:
+		generic, err := toGeneric(value)
+		if err != nil {
+			return err
+		}
+		return w.template.Execute(w.out, generic)
+	case w.format == internal.OutputFormatTable:
+		return w.writeTable(table)
+	case w.format == internal.OutputFormatJSON:
+		enc := json.NewEncoder(w.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case w.format == internal.OutputFormatYAML:
+		generic, err := toGeneric(value)
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		_, err = w.out.Write(data)
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q", w.format)
+	}
+}
+
+func (w *writer) writeTable(table internal.Table) error {
+	tw := tabwriter.NewWriter(w.out, 0, 8, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, strings.Join(table.Columns, "\t")); err != nil {
+		return err
+	}
+	for _, row := range table.Rows {
+		if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// toGeneric round trips value through json so yaml and templates see the same field names as json
+func toGeneric(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
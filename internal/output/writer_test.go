@@ -0,0 +1,50 @@
+package output
+
+import (
+	"bytes"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	t.Run("TestFormats", testFormats)
+	t.Run("TestInvalidFormat", testInvalidFormat)
+}
+
+type result struct {
+	RunID  string `json:"run_id"`
+	Status string `json:"status"`
+}
+
+func testFormats(t *testing.T) {
+	value := []result{{RunID: "abc", Status: "applied"}, {RunID: "defgh", Status: "failed"}}
+	table := internal.Table{Columns: []string{"RUN", "STATUS"}, Rows: [][]string{{"abc", "applied"}, {"defgh", "failed"}}}
+
+	expected := map[string]string{
+		"table": "RUN    STATUS\nabc    applied\ndefgh  failed\n",
+		"json":  "[\n  {\n    \"run_id\": \"abc\",\n    \"status\": \"applied\"\n  },\n  {\n    \"run_id\": \"defgh\",\n    \"status\": \"failed\"\n  }\n]\n",
+		"yaml":  "- run_id: abc\n  status: applied\n- run_id: defgh\n  status: failed\n",
+		"go-template={{range .}}{{.run_id}}={{.status}} {{end}}": "abc=applied defgh=failed ",
+	}
+	for format, out := range expected {
+		var buf bytes.Buffer
+		w, err := New(format, &buf)
+		assert.NoError(t, err, format)
+		assert.NoError(t, w.Write(value, table), format)
+		assert.Equal(t, out, buf.String(), format)
+	}
+}
+
+func testInvalidFormat(t *testing.T) {
+	for _, format := range []string{"xml", "go-template={{.status", ""} {
+		cfg := viper.New()
+		cfg.Set(config.OutputFormat.String(), format)
+		v := NewConfigValidator(cfg)
+		assert.Error(t, v.Validator.Validate(), format)
+		_, err := New(format, &bytes.Buffer{})
+		assert.Error(t, err, format)
+	}
+}
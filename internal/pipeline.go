@@ -0,0 +1,187 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrPipelineRunNotFound is returned by a PipelineStore when a pipeline run does not exist
+var ErrPipelineRunNotFound = errors.New("pipeline run not found")
+
+// ErrPipelineNotFound is returned when starting a pipeline that is not configured
+var ErrPipelineNotFound = errors.New("pipeline not found")
+
+// ErrNotAwaitingPromotion is returned when promoting a pipeline run that is not waiting at a manual gate
+var ErrNotAwaitingPromotion = errors.New("pipeline run is not awaiting promotion")
+
+// Pipeline promotes the same commit of a stack through its workspaces one stage at a time
+type Pipeline struct {
+	Name   string          `json:"name" mapstructure:"-"`
+	Stack  string          `json:"stack" mapstructure:"stack"`
+	Stages []PipelineStage `json:"stages" mapstructure:"stages"`
+}
+
+// PipelineStage applies the pipeline's commit to a workspace of the stack
+type PipelineStage struct {
+	Workspace string `json:"workspace" mapstructure:"workspace"`
+
+	// Soak is how long the stage has to stay applied before the commit is promoted to the next stage
+	Soak time.Duration `json:"soak,omitempty" mapstructure:"soak"`
+
+	// Gate makes the stage wait for one of the stack's approvers to promote the commit to it
+	Gate bool `json:"gate,omitempty" mapstructure:"gate"`
+}
+
+// pipelineStageJSON is PipelineStage with the soak time as a duration string
+type pipelineStageJSON struct {
+	Workspace string `json:"workspace"`
+	Soak      string `json:"soak,omitempty"`
+	Gate      bool   `json:"gate,omitempty"`
+}
+
+func (s PipelineStage) MarshalJSON() ([]byte, error) {
+	stage := pipelineStageJSON{Workspace: s.Workspace, Gate: s.Gate}
+	if s.Soak > 0 {
+		stage.Soak = s.Soak.String()
+	}
+	return json.Marshal(stage)
+}
+
+func (s *PipelineStage) UnmarshalJSON(data []byte) error {
+	var stage pipelineStageJSON
+	if err := json.Unmarshal(data, &stage); err != nil {
+		return err
+	}
+	*s = PipelineStage{Workspace: stage.Workspace, Gate: stage.Gate}
+	if stage.Soak != "" {
+		soak, err := time.ParseDuration(stage.Soak)
+		if err != nil {
+			return err
+		}
+		s.Soak = soak
+	}
+	return nil
+}
+
+// PipelineStatus is the state of a pipeline run
+type PipelineStatus string
+
+const (
+	PipelineStatusRunning PipelineStatus = "running"
+
+	// PipelineStatusAwaitingPromotion means the next stage has a gate and waits to be promoted
+	PipelineStatusAwaitingPromotion PipelineStatus = "awaiting_promotion"
+
+	PipelineStatusSucceeded PipelineStatus = "succeeded"
+	PipelineStatusFailed    PipelineStatus = "failed"
+)
+
+// Terminal returns true when a pipeline run in this status will not make any further progress
+func (s PipelineStatus) Terminal() bool {
+	return s == PipelineStatusSucceeded || s == PipelineStatusFailed
+}
+
+// StageStatus is the state of a stage of a pipeline run
+type StageStatus string
+
+const (
+	StageStatusPending StageStatus = "pending"
+	StageStatusRunning StageStatus = "running"
+
+	// StageStatusSoaking means the stage has been applied and is waiting out its soak time
+	StageStatusSoaking StageStatus = "soaking"
+
+	// StageStatusAwaitingPromotion means the stage has a gate and is waiting to be promoted
+	StageStatusAwaitingPromotion StageStatus = "awaiting_promotion"
+
+	StageStatusSucceeded StageStatus = "succeeded"
+	StageStatusFailed    StageStatus = "failed"
+
+	// StageStatusSkipped means an earlier stage failed so the stage was never run
+	StageStatusSkipped StageStatus = "skipped"
+)
+
+// PipelineStageRun is the progress of a stage of a pipeline run, the stage is copied from the pipeline when the
+// pipeline run starts so changing the pipeline does not affect runs in progress
+type PipelineStageRun struct {
+	Stage      PipelineStage `json:"stage"`
+	Status     StageStatus   `json:"status"`
+	RunID      string        `json:"run_id,omitempty"`
+	SoakUntil  time.Time     `json:"soak_until,omitempty"`
+	PromotedBy string        `json:"promoted_by,omitempty"`
+}
+
+// PipelineRun is a commit being promoted through the stages of a pipeline
+type PipelineRun struct {
+	ID       string `json:"id"`
+	Pipeline string `json:"pipeline"`
+	Stack    string `json:"stack"`
+
+	// Ref is the ref the first stage was run at, CommitSHA is the commit it resolved to which every later stage runs
+	Ref       string `json:"ref,omitempty"`
+	CommitSHA string `json:"commit_sha,omitempty"`
+
+	Requester string              `json:"requester"`
+	Status    PipelineStatus      `json:"status"`
+	Stages    []*PipelineStageRun `json:"stages"`
+	Error     string              `json:"error,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// PipelineRequest is a request to promote a ref through a pipeline
+type PipelineRequest struct {
+	Ref       string `json:"ref"`
+	Requester string `json:"requester"`
+}
+
+// PipelineRunFilter narrows down the pipeline runs returned by PipelineStore.ListPipelineRuns, zero values match
+// everything
+type PipelineRunFilter struct {
+	Pipeline string
+	Status   PipelineStatus
+
+	// Limit is the max number of pipeline runs in a page
+	Limit int
+
+	// Cursor is the PipelineRunPage.NextCursor of the previous page
+	Cursor string
+}
+
+// PipelineRunPage is a page of pipeline runs ordered newest first
+type PipelineRunPage struct {
+	Runs       []*PipelineRun `json:"runs"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// PipelineStore persists pipeline runs
+type PipelineStore interface {
+	// CreatePipelineRun saves a new pipeline run
+	CreatePipelineRun(ctx context.Context, run *PipelineRun) error
+
+	// UpdatePipelineRun saves an existing pipeline run
+	UpdatePipelineRun(ctx context.Context, run *PipelineRun) error
+
+	// GetPipelineRun loads a pipeline run by id
+	GetPipelineRun(ctx context.Context, id string) (*PipelineRun, error)
+
+	// ListPipelineRuns returns a page of pipeline runs matching filter
+	ListPipelineRuns(ctx context.Context, filter PipelineRunFilter) (PipelineRunPage, error)
+
+	// ActivePipelineRuns returns every pipeline run that has not finished
+	ActivePipelineRuns(ctx context.Context) ([]*PipelineRun, error)
+}
+
+// PipelineRunner starts pipeline runs and moves them through their stages
+type PipelineRunner interface {
+	// Pipelines returns every configured pipeline ordered by name
+	Pipelines() []Pipeline
+
+	// StartPipeline runs the first stage of a pipeline
+	StartPipeline(ctx context.Context, name string, req PipelineRequest) (*PipelineRun, error)
+
+	// Promote runs the stage of a pipeline run waiting at a gate, the approver has to be an approver of the stack
+	Promote(ctx context.Context, id, approver string) (*PipelineRun, error)
+}
@@ -0,0 +1,8 @@
+package pipelines
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("pipelines", []config.EnvVar{}, NewRunner, NewConfigValidator)
@@ -0,0 +1,62 @@
+package pipelines
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"fmt"
+	"github.com/spf13/viper"
+	"sort"
+)
+
+func loadPipelines(cfg *viper.Viper, stacks internal.StackRegistry) (map[string]internal.Pipeline, error) {
+	pipelines := make(map[string]internal.Pipeline)
+	if err := cfg.UnmarshalKey(config.Pipelines.String(), &pipelines); err != nil {
+		return pipelines, fmt.Errorf("invalid %s: %w", config.Pipelines, err)
+	}
+
+	for name, p := range pipelines {
+		p.Name = name
+		if err := validate(p, stacks); err != nil {
+			return pipelines, fmt.Errorf("invalid %s: pipeline %s: %w", config.Pipelines, name, err)
+		}
+		pipelines[name] = p
+	}
+	return pipelines, nil
+}
+
+func validate(p internal.Pipeline, stacks internal.StackRegistry) error {
+	stack, ok := stacks.Get(p.Stack)
+	if !ok {
+		return fmt.Errorf("unknown stack %s", p.Stack)
+	}
+	if len(p.Stages) == 0 {
+		return fmt.Errorf("at least one stage is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, stage := range p.Stages {
+		switch {
+		case !stack.HasWorkspace(stage.Workspace):
+			return fmt.Errorf("stage %s is not a workspace of stack %s", stage.Workspace, p.Stack)
+		case seen[stage.Workspace]:
+			return fmt.Errorf("stage %s is in the pipeline more than once", stage.Workspace)
+		case stage.Soak < 0:
+			return fmt.Errorf("stage %s soak must not be negative", stage.Workspace)
+		case stage.Gate && len(stack.Approvers) == 0:
+			return fmt.Errorf("stage %s has a gate but stack %s has no approvers to promote to it", stage.Workspace, p.Stack)
+		}
+		seen[stage.Workspace] = true
+	}
+	return nil
+}
+
+func sorted(pipelines map[string]internal.Pipeline) []internal.Pipeline {
+	list := make([]internal.Pipeline, 0, len(pipelines))
+	for _, p := range pipelines {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
@@ -0,0 +1,311 @@
+package pipelines
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"sync"
+	"time"
+)
+
+// soakCheckInterval is how often stages that are soaking are checked for their soak time being over
+const soakCheckInterval = 10 * time.Second
+
+type runnerOut struct {
+	fx.Out
+	Runner     internal.PipelineRunner
+	Service    app.Service              `group:"services"`
+	Subscriber internal.EventSubscriber `group:"eventSubscribers"`
+}
+
+// runner moves pipeline runs through their stages. Run events move a stage on once its run finishes and a
+// background loop promotes stages whose soak time is over.
+type runner struct {
+	log          internal.BackgroundLog
+	stacks       internal.StackRegistry
+	orchestrator internal.Orchestrator
+	store        internal.PipelineStore
+	pipelines    map[string]internal.Pipeline
+	interval     time.Duration
+	now          func() time.Time
+
+	// mu serializes changes to pipeline runs between run events, the soak loop and promotions
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(cfg *viper.Viper, log internal.BackgroundLog, stacks internal.StackRegistry, orchestrator internal.Orchestrator, store internal.PipelineStore) (runnerOut, error) {
+	pipelines, err := loadPipelines(cfg, stacks)
+	if err != nil {
+		return runnerOut{}, err
+	}
+	r := newRunner(log, stacks, orchestrator, store, pipelines)
+	return runnerOut{Runner: r, Service: r, Subscriber: r}, nil
+}
+
+func newRunner(log internal.BackgroundLog, stacks internal.StackRegistry, orchestrator internal.Orchestrator, store internal.PipelineStore, pipelines map[string]internal.Pipeline) *runner {
+	return &runner{
+		log:          log.ChildLog("pipelines"),
+		stacks:       stacks,
+		orchestrator: orchestrator,
+		store:        store,
+		pipelines:    pipelines,
+		interval:     soakCheckInterval,
+		now:          func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (r *runner) Pipelines() []internal.Pipeline {
+	return sorted(r.pipelines)
+}
+
+func (r *runner) StartPipeline(ctx context.Context, name string, req internal.PipelineRequest) (*internal.PipelineRun, error) {
+	pipeline, ok := r.pipelines[name]
+	if !ok {
+		return nil, internal.ErrPipelineNotFound
+	}
+	if req.Requester == "" {
+		return nil, fmt.Errorf("%w: requester is required", internal.ErrInvalidRequest)
+	}
+
+	now := r.now()
+	run := &internal.PipelineRun{
+		ID:        internal.NewRunID(),
+		Pipeline:  name,
+		Stack:     pipeline.Stack,
+		Ref:       req.Ref,
+		Requester: req.Requester,
+		Status:    internal.PipelineStatusRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for _, stage := range pipeline.Stages {
+		run.Stages = append(run.Stages, &internal.PipelineStageRun{Stage: stage, Status: internal.StageStatusPending})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.store.CreatePipelineRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("unable to save pipeline run: %w", err)
+	}
+	r.log.InfowCtx(ctx, "Pipeline started", "pipeline_run_id", run.ID, "pipeline", name, "ref", req.Ref, "requester", req.Requester)
+
+	if err := r.advance(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *runner) Promote(ctx context.Context, id, approver string) (*internal.PipelineRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, err := r.store.GetPipelineRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != internal.PipelineStatusAwaitingPromotion {
+		return nil, internal.ErrNotAwaitingPromotion
+	}
+	stack, ok := r.stacks.Get(run.Stack)
+	if !ok || !stack.CanApprove(approver) {
+		return nil, internal.ErrNotApprover
+	}
+
+	for _, stage := range run.Stages {
+		if stage.Status == internal.StageStatusAwaitingPromotion {
+			stage.Status = internal.StageStatusPending
+			stage.PromotedBy = approver
+		}
+	}
+	run.Status = internal.PipelineStatusRunning
+	r.log.InfowCtx(ctx, "Pipeline promoted", "pipeline_run_id", run.ID, "approver", approver)
+
+	if err := r.advance(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+func (r *runner) Name() string {
+	return "pipelines"
+}
+
+func (r *runner) Handle(ctx context.Context, event internal.Event) error {
+	run := event.Run
+	if run == nil || run.Pipeline == "" || !run.Status.Terminal() {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pipelineRun, err := r.store.GetPipelineRun(ctx, run.Pipeline)
+	if err != nil {
+		return fmt.Errorf("unable to load pipeline run %s: %w", run.Pipeline, err)
+	}
+
+	// Events can be delivered more than once, only the first one for the stage's run moves the pipeline on
+	var stage *internal.PipelineStageRun
+	for _, s := range pipelineRun.Stages {
+		if s.RunID == run.ID && s.Status == internal.StageStatusRunning {
+			stage = s
+		}
+	}
+	if stage == nil {
+		return nil
+	}
+
+	if run.Status != internal.RunStatusApplied && run.Status != internal.RunStatusPlanned {
+		r.fail(pipelineRun, stage, fmt.Sprintf("run %s of stage %s ended %s", run.ID, stage.Stage.Workspace, run.Status))
+		return r.save(ctx, pipelineRun)
+	}
+
+	// Every later stage runs the commit the first stage resolved its ref to
+	if pipelineRun.CommitSHA == "" {
+		pipelineRun.CommitSHA = run.CommitSHA
+	}
+	stage.Status = internal.StageStatusSucceeded
+	if stage.Stage.Soak > 0 {
+		stage.Status = internal.StageStatusSoaking
+		stage.SoakUntil = r.now().Add(stage.Stage.Soak)
+	}
+	r.log.Infow("Pipeline stage applied", "pipeline_run_id", pipelineRun.ID, "workspace", stage.Stage.Workspace, "run_id", run.ID, "status", stage.Status)
+
+	return r.advance(ctx, pipelineRun)
+}
+
+func (r *runner) Start(ctx context.Context) error {
+	loopCtx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go r.loop(loopCtx)
+	return nil
+}
+
+func (r *runner) Stop(ctx context.Context) error {
+	if r.cancel == nil {
+		return nil
+	}
+	r.cancel()
+	r.wg.Wait()
+	return nil
+}
+
+func (r *runner) Disabled() bool {
+	return len(r.pipelines) == 0
+}
+
+func (r *runner) loop(ctx context.Context) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.checkSoaking(ctx)
+		}
+	}
+}
+
+// checkSoaking moves on the pipeline runs with a stage whose soak time is over
+func (r *runner) checkSoaking(ctx context.Context) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runs, err := r.store.ActivePipelineRuns(ctx)
+	if err != nil {
+		r.log.Errw(err, "Unable to list active pipeline runs")
+		return
+	}
+	for _, run := range runs {
+		for _, stage := range run.Stages {
+			if stage.Status == internal.StageStatusSoaking && !r.now().Before(stage.SoakUntil) {
+				if err := r.advance(ctx, run); err != nil {
+					r.log.Errw(err, "Unable to advance pipeline run", "pipeline_run_id", run.ID)
+				}
+				break
+			}
+		}
+	}
+}
+
+// advance runs the next stage of a pipeline run once the stages before it have succeeded and saves the run
+func (r *runner) advance(ctx context.Context, run *internal.PipelineRun) error {
+	for _, stage := range run.Stages {
+		switch stage.Status {
+		case internal.StageStatusSucceeded:
+			continue
+		case internal.StageStatusSoaking:
+			if r.now().Before(stage.SoakUntil) {
+				return r.save(ctx, run)
+			}
+			stage.Status = internal.StageStatusSucceeded
+			continue
+		case internal.StageStatusPending:
+			if stage.Stage.Gate && stage.PromotedBy == "" {
+				stage.Status = internal.StageStatusAwaitingPromotion
+				run.Status = internal.PipelineStatusAwaitingPromotion
+				r.log.Infow("Pipeline awaiting promotion", "pipeline_run_id", run.ID, "workspace", stage.Stage.Workspace)
+				return r.save(ctx, run)
+			}
+			r.submit(ctx, run, stage)
+		}
+		return r.save(ctx, run)
+	}
+
+	run.Status = internal.PipelineStatusSucceeded
+	r.log.Infow("Pipeline succeeded", "pipeline_run_id", run.ID, "pipeline", run.Pipeline, "commit_sha", run.CommitSHA)
+	return r.save(ctx, run)
+}
+
+func (r *runner) submit(ctx context.Context, run *internal.PipelineRun, stage *internal.PipelineStageRun) {
+	ref := run.Ref
+	if run.CommitSHA != "" {
+		ref = run.CommitSHA
+	}
+
+	submitted, err := r.orchestrator.Submit(ctx, internal.DeployRequest{
+		Stack:     run.Stack,
+		Workspace: stage.Stage.Workspace,
+		Ref:       ref,
+		Requester: run.Requester,
+		Pipeline:  run.ID,
+	})
+	if err != nil {
+		r.log.Errw(err, "Unable to submit pipeline stage run", "pipeline_run_id", run.ID, "workspace", stage.Stage.Workspace)
+		r.fail(run, stage, fmt.Sprintf("unable to submit run of stage %s: %v", stage.Stage.Workspace, err))
+		return
+	}
+	stage.RunID = submitted.ID
+	stage.Status = internal.StageStatusRunning
+}
+
+// fail fails a pipeline run at stage skipping every stage after it
+func (r *runner) fail(run *internal.PipelineRun, stage *internal.PipelineStageRun, message string) {
+	stage.Status = internal.StageStatusFailed
+	for _, s := range run.Stages {
+		if s.Status == internal.StageStatusPending || s.Status == internal.StageStatusAwaitingPromotion {
+			s.Status = internal.StageStatusSkipped
+		}
+	}
+	run.Status = internal.PipelineStatusFailed
+	run.Error = message
+	r.log.Infow("Pipeline failed", "pipeline_run_id", run.ID, "pipeline", run.Pipeline, "error", message)
+}
+
+func (r *runner) save(ctx context.Context, run *internal.PipelineRun) error {
+	run.UpdatedAt = r.now()
+	if err := r.store.UpdatePipelineRun(ctx, run); err != nil {
+		return fmt.Errorf("unable to save pipeline run %s: %w", run.ID, err)
+	}
+	return nil
+}
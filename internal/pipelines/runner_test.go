@@ -0,0 +1,163 @@
+package pipelines
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"deploy-runner/internal/stacks"
+	"encoding/json"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testSHA = "0123456789abcdef0123456789abcdef01234567"
+
+func TestRunner(t *testing.T) {
+	t.Run("TestPromotion", testPromotion)
+	t.Run("TestStageFailure", testStageFailure)
+}
+
+type fakeOrchestrator struct {
+	internal.Orchestrator
+	requests []internal.DeployRequest
+	runs     []*internal.Run
+}
+
+func (o *fakeOrchestrator) Submit(ctx context.Context, req internal.DeployRequest) (*internal.Run, error) {
+	o.requests = append(o.requests, req)
+	run := internal.NewRun(req, internal.Stack{Repository: "https://example.com/infra.git"})
+	o.runs = append(o.runs, run)
+	return run, nil
+}
+
+// memPipelineStore is an in memory internal.PipelineStore, runs are round tripped through json like the real store
+type memPipelineStore struct {
+	runs map[string][]byte
+}
+
+func (s *memPipelineStore) CreatePipelineRun(ctx context.Context, run *internal.PipelineRun) error {
+	return s.UpdatePipelineRun(ctx, run)
+}
+
+func (s *memPipelineStore) UpdatePipelineRun(ctx context.Context, run *internal.PipelineRun) error {
+	data, err := json.Marshal(run)
+	s.runs[run.ID] = data
+	return err
+}
+
+func (s *memPipelineStore) GetPipelineRun(ctx context.Context, id string) (*internal.PipelineRun, error) {
+	data, ok := s.runs[id]
+	if !ok {
+		return nil, internal.ErrPipelineRunNotFound
+	}
+	var run internal.PipelineRun
+	return &run, json.Unmarshal(data, &run)
+}
+
+func (s *memPipelineStore) ListPipelineRuns(ctx context.Context, filter internal.PipelineRunFilter) (internal.PipelineRunPage, error) {
+	return internal.PipelineRunPage{}, nil
+}
+
+func (s *memPipelineStore) ActivePipelineRuns(ctx context.Context) ([]*internal.PipelineRun, error) {
+	runs := make([]*internal.PipelineRun, 0)
+	for id := range s.runs {
+		run, _ := s.GetPipelineRun(ctx, id)
+		if !run.Status.Terminal() {
+			runs = append(runs, run)
+		}
+	}
+	return runs, nil
+}
+
+func newTestRunner(t *testing.T) (*runner, *fakeOrchestrator, *memPipelineStore, *time.Time) {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://example.com/infra.git", "workspaces": []string{"dev", "staging", "prod"}, "approvers": []string{"carol"}},
+	})
+	cfg.Set(config.Pipelines.String(), map[string]interface{}{
+		"network": map[string]interface{}{"stack": "network", "stages": []map[string]interface{}{
+			{"workspace": "dev", "soak": "1h"},
+			{"workspace": "staging"},
+			{"workspace": "prod", "gate": true},
+		}},
+	})
+	registry, err := stacks.NewRegistry(cfg)
+	assert.NoError(t, err)
+	pipelines, err := loadPipelines(cfg, registry)
+	assert.NoError(t, err)
+
+	o := &fakeOrchestrator{}
+	store := &memPipelineStore{runs: make(map[string][]byte)}
+	r := newRunner(logging.NewBackgroundLog(cfg), registry, o, store, pipelines)
+	now := time.Date(2021, 11, 1, 9, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, o, store, &now
+}
+
+// finish settles the latest submitted run and hands its event to the runner
+func finish(t *testing.T, r *runner, o *fakeOrchestrator, status internal.RunStatus) {
+	run := o.runs[len(o.runs)-1]
+	run.Status = status
+	run.CommitSHA = testSHA
+	assert.NoError(t, r.Handle(context.Background(), internal.NewEvent(internal.RunStatusEventType(status), run, "")))
+}
+
+func testPromotion(t *testing.T) {
+	r, o, store, now := newTestRunner(t)
+	ctx := context.Background()
+
+	run, err := r.StartPipeline(ctx, "network", internal.PipelineRequest{Ref: "main", Requester: "alice"})
+	assert.NoError(t, err)
+	finish(t, r, o, internal.RunStatusApplied)
+
+	// dev soaks for an hour before staging runs
+	r.checkSoaking(ctx)
+	assert.Len(t, o.requests, 1)
+	*now = now.Add(time.Hour)
+	r.checkSoaking(ctx)
+	if !assert.Len(t, o.requests, 2) {
+		return
+	}
+	assert.Equal(t, "staging", o.requests[1].Workspace)
+	assert.Equal(t, testSHA, o.requests[1].Ref)
+	assert.Equal(t, run.ID, o.requests[1].Pipeline)
+
+	finish(t, r, o, internal.RunStatusPlanned)
+	saved, _ := store.GetPipelineRun(ctx, run.ID)
+	assert.Equal(t, internal.PipelineStatusAwaitingPromotion, saved.Status)
+
+	_, err = r.Promote(ctx, run.ID, "alice")
+	assert.ErrorIs(t, err, internal.ErrNotApprover)
+	_, err = r.Promote(ctx, run.ID, "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", o.requests[2].Workspace)
+	assert.Equal(t, testSHA, o.requests[2].Ref)
+
+	finish(t, r, o, internal.RunStatusApplied)
+	saved, _ = store.GetPipelineRun(ctx, run.ID)
+	assert.Equal(t, internal.PipelineStatusSucceeded, saved.Status)
+	assert.Equal(t, "carol", saved.Stages[2].PromotedBy)
+}
+
+func testStageFailure(t *testing.T) {
+	r, o, store, _ := newTestRunner(t)
+	ctx := context.Background()
+
+	run, err := r.StartPipeline(ctx, "network", internal.PipelineRequest{Requester: "alice"})
+	assert.NoError(t, err)
+	finish(t, r, o, internal.RunStatusFailed)
+
+	saved, _ := store.GetPipelineRun(ctx, run.ID)
+	assert.Equal(t, internal.PipelineStatusFailed, saved.Status)
+	assert.Equal(t, internal.StageStatusFailed, saved.Stages[0].Status)
+	assert.Equal(t, internal.StageStatusSkipped, saved.Stages[2].Status)
+	assert.Len(t, o.requests, 1)
+
+	_, err = r.StartPipeline(ctx, "unknown", internal.PipelineRequest{Requester: "alice"})
+	assert.ErrorIs(t, err, internal.ErrPipelineNotFound)
+}
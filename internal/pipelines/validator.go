@@ -0,0 +1,27 @@
+package pipelines
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+type validatorOut struct {
+	fx.Out
+	Validator config.Validator `group:"configValidators"`
+}
+
+type configValidator struct {
+	cfg    *viper.Viper
+	stacks internal.StackRegistry
+}
+
+func NewConfigValidator(cfg *viper.Viper, stacks internal.StackRegistry) validatorOut {
+	return validatorOut{Validator: &configValidator{cfg: cfg, stacks: stacks}}
+}
+
+func (v *configValidator) Validate() error {
+	_, err := loadPipelines(v.cfg, v.stacks)
+	return err
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// QueueEntry is a run waiting in the RunQueue
+type QueueEntry struct {
+	Run        *Run          `json:"run"`
+	EnqueuedAt time.Time     `json:"enqueued_at"`
+	Wait       time.Duration `json:"wait"`
+
+	// Position is the 1 based position of the run among all waiting runs
+	Position int `json:"position"`
+
+	// StackPosition is the 1 based position of the run among runs waiting for the same stack workspace
+	StackPosition int `json:"stack_position"`
+}
+
+// QueueStats is a point in time view of the RunQueue
+type QueueStats struct {
+	Depth   int          `json:"depth"`
+	Running int          `json:"running"`
+	Entries []QueueEntry `json:"entries"`
+}
+
+// RunQueue holds submitted runs until a worker is free. Runs for the same stack workspace are handed out strictly in
+// arrival order and never concurrently, runs for different stack workspaces can be handed out in parallel.
+type RunQueue interface {
+	// Enqueue adds a run to the back of the queue and returns its position
+	Enqueue(run *Run) int
+
+	// Dequeue blocks until a run whose stack workspace is not already running is available or ctx is done
+	Dequeue(ctx context.Context) (*Run, error)
+
+	// Done must be called once a dequeued run finishes so the next run for its stack workspace can be handed out
+	Done(run *Run)
+
+	// Remove takes a waiting run out of the queue, it returns false if the run is not waiting
+	Remove(runID string) (*Run, bool)
+
+	// Entry returns the queue entry of a waiting run
+	Entry(runID string) (QueueEntry, bool)
+
+	// Stats returns a snapshot of the queue
+	Stats() QueueStats
+}
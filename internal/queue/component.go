@@ -0,0 +1,8 @@
+package queue
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("queue", []config.EnvVar{config.EnvWorkerCount}, NewRunQueue, NewWorkerPool)
@@ -0,0 +1,138 @@
+package queue
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	run        *internal.Run
+	enqueuedAt time.Time
+}
+
+type runQueue struct {
+	mu      sync.Mutex
+	entries []entry
+	running map[string]bool
+
+	// changed is closed and replaced whenever the queue changes so blocked Dequeue calls re-check for work
+	changed chan struct{}
+}
+
+func NewRunQueue() internal.RunQueue {
+	return &runQueue{
+		running: make(map[string]bool),
+		changed: make(chan struct{}),
+	}
+}
+
+func (q *runQueue) Enqueue(run *internal.Run) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.entries = append(q.entries, entry{run: run, enqueuedAt: time.Now().UTC()})
+	q.notify()
+	return len(q.entries)
+}
+
+func (q *runQueue) Dequeue(ctx context.Context) (*internal.Run, error) {
+	for {
+		q.mu.Lock()
+		for i, e := range q.entries {
+			key := stackKey(e.run)
+			if q.running[key] {
+				continue
+			}
+
+			q.running[key] = true
+			q.entries = append(q.entries[:i:i], q.entries[i+1:]...)
+			q.mu.Unlock()
+			return e.run, nil
+		}
+		changed := q.changed
+		q.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *runQueue) Done(run *internal.Run) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.running, stackKey(run))
+	q.notify()
+}
+
+func (q *runQueue) Remove(runID string) (*internal.Run, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i, e := range q.entries {
+		if e.run.ID == runID {
+			q.entries = append(q.entries[:i:i], q.entries[i+1:]...)
+			q.notify()
+			return e.run, true
+		}
+	}
+	return nil, false
+}
+
+func (q *runQueue) Entry(runID string) (internal.QueueEntry, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, e := range q.snapshot() {
+		if e.Run.ID == runID {
+			return e, true
+		}
+	}
+	return internal.QueueEntry{}, false
+}
+
+func (q *runQueue) Stats() internal.QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return internal.QueueStats{
+		Depth:   len(q.entries),
+		Running: len(q.running),
+		Entries: q.snapshot(),
+	}
+}
+
+// snapshot must be called with the lock held
+func (q *runQueue) snapshot() []internal.QueueEntry {
+	now := time.Now().UTC()
+	stackPositions := make(map[string]int)
+	entries := make([]internal.QueueEntry, 0, len(q.entries))
+	for i, e := range q.entries {
+		key := stackKey(e.run)
+		stackPositions[key]++
+		run := *e.run
+		entries = append(entries, internal.QueueEntry{
+			Run:           &run,
+			EnqueuedAt:    e.enqueuedAt,
+			Wait:          now.Sub(e.enqueuedAt),
+			Position:      i + 1,
+			StackPosition: stackPositions[key],
+		})
+	}
+	return entries
+}
+
+// notify must be called with the lock held
+func (q *runQueue) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func stackKey(run *internal.Run) string {
+	return run.Stack + "/" + run.Workspace
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRunQueue(t *testing.T) {
+	t.Run("TestSameStackSerialized", testSameStackSerialized)
+	t.Run("TestDifferentStacksParallel", testDifferentStacksParallel)
+	t.Run("TestPositions", testPositions)
+	t.Run("TestDequeueBlocksUntilDone", testDequeueBlocksUntilDone)
+}
+
+func newRun(id, stack, workspace string) *internal.Run {
+	return &internal.Run{ID: id, Stack: stack, Workspace: workspace, Status: internal.RunStatusQueued}
+}
+
+func testSameStackSerialized(t *testing.T) {
+	q := NewRunQueue()
+	q.Enqueue(newRun("1", "network", "prod"))
+	q.Enqueue(newRun("2", "network", "prod"))
+	q.Enqueue(newRun("3", "cluster", "prod"))
+
+	first, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1", first.ID)
+
+	// Run 2 has to wait for run 1 so run 3 is handed out next
+	next, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "3", next.ID)
+
+	q.Done(first)
+	next, err = q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "2", next.ID)
+}
+
+func testDifferentStacksParallel(t *testing.T) {
+	q := NewRunQueue()
+	q.Enqueue(newRun("1", "network", "prod"))
+	q.Enqueue(newRun("2", "network", "staging"))
+
+	first, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	second, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "1", first.ID)
+	assert.Equal(t, "2", second.ID)
+	assert.Equal(t, 2, q.Stats().Running)
+}
+
+func testPositions(t *testing.T) {
+	q := NewRunQueue()
+	assert.Equal(t, 1, q.Enqueue(newRun("1", "network", "prod")))
+	assert.Equal(t, 2, q.Enqueue(newRun("2", "cluster", "prod")))
+	assert.Equal(t, 3, q.Enqueue(newRun("3", "network", "prod")))
+
+	entry, ok := q.Entry("3")
+	assert.True(t, ok)
+	assert.Equal(t, 3, entry.Position)
+	assert.Equal(t, 2, entry.StackPosition)
+
+	_, ok = q.Entry("missing")
+	assert.False(t, ok)
+	assert.Equal(t, 3, q.Stats().Depth)
+}
+
+func testDequeueBlocksUntilDone(t *testing.T) {
+	q := NewRunQueue()
+	q.Enqueue(newRun("1", "network", "prod"))
+	q.Enqueue(newRun("2", "network", "prod"))
+
+	first, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		q.Done(first)
+	}()
+	next, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "2", next.ID)
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"sync"
+)
+
+type serviceOut struct {
+	fx.Out
+	Service app.Service `group:"services"`
+}
+
+type workerPool struct {
+	log          internal.BackgroundLog
+	queue        internal.RunQueue
+	orchestrator internal.Orchestrator
+	count        int
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+func NewWorkerPool(cfg *viper.Viper, log internal.BackgroundLog, queue internal.RunQueue, orchestrator internal.Orchestrator) serviceOut {
+	count := cfg.GetInt(config.WorkerCount.String())
+	if count < 1 {
+		count = 1
+	}
+
+	return serviceOut{Service: &workerPool{
+		log:          log.ChildLog("workers"),
+		queue:        queue,
+		orchestrator: orchestrator,
+		count:        count,
+	}}
+}
+
+func (p *workerPool) Start(ctx context.Context) error {
+	workCtx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+
+	p.log.Infof("Starting %d run workers", p.count)
+	for i := 0; i < p.count; i++ {
+		p.wg.Add(1)
+		go p.work(workCtx, i)
+	}
+	return nil
+}
+
+func (p *workerPool) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+	p.cancel()
+
+	// In flight runs are allowed to finish so terraform is not interrupted in the middle of an apply
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *workerPool) Disabled() bool {
+	return false
+}
+
+func (p *workerPool) work(ctx context.Context, worker int) {
+	defer p.wg.Done()
+	for {
+		run, err := p.queue.Dequeue(ctx)
+		if err != nil {
+			return
+		}
+
+		p.log.Infow("Worker picked up run", "worker", worker, "run_id", run.ID, "stack", run.Stack, "workspace", run.Workspace)
+		if err := p.orchestrator.Execute(context.Background(), run); err != nil {
+			p.log.Errw(err, "Run failed", "worker", worker, "run_id", run.ID, "status", run.Status)
+		}
+		p.queue.Done(run)
+	}
+}
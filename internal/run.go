@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// RunStatus is the current state of a run in its lifecycle
+type RunStatus string
+
+const (
+	RunStatusQueued   RunStatus = "queued"
+	RunStatusPlanning RunStatus = "planning"
+	RunStatusPlanned  RunStatus = "planned"
+
+	// RunStatusAwaitingApproval means the plan has changes that have to be approved before the run is applied
+	RunStatusAwaitingApproval RunStatus = "awaiting_approval"
+
+	RunStatusApplying RunStatus = "applying"
+	RunStatusApplied  RunStatus = "applied"
+	RunStatusFailed   RunStatus = "failed"
+
+	// RunStatusStateLocked means terraform gave up waiting for a state lock held outside of this runner
+	RunStatusStateLocked RunStatus = "state_locked"
+
+	// RunStatusCancelled means the run was cancelled before it started applying so no infrastructure was changed
+	RunStatusCancelled RunStatus = "cancelled"
+
+	// RunStatusCancelledDuringApply means the run was cancelled while applying, terraform was interrupted and may have
+	// changed some infrastructure before it stopped
+	RunStatusCancelledDuringApply RunStatus = "cancelled_during_apply"
+
+	// RunStatusTimedOut means a phase of the run took longer than its timeout and terraform was interrupted
+	RunStatusTimedOut RunStatus = "timed_out"
+
+	// RunStatusNeedsAttention means the runner stopped while the run was applying, the state of the stack is unknown and
+	// its state lock may still be held so a human has to look at it
+	RunStatusNeedsAttention RunStatus = "errored_needs_attention"
+)
+
+// RunStatuses are all the statuses a run can be in
+var RunStatuses = []RunStatus{RunStatusQueued, RunStatusPlanning, RunStatusPlanned, RunStatusAwaitingApproval,
+	RunStatusApplying, RunStatusApplied, RunStatusFailed, RunStatusStateLocked, RunStatusCancelled,
+	RunStatusCancelledDuringApply, RunStatusTimedOut, RunStatusNeedsAttention}
+
+// Terminal returns true when a run in this status will not make any further progress
+func (s RunStatus) Terminal() bool {
+	switch s {
+	case RunStatusPlanned, RunStatusApplied, RunStatusFailed, RunStatusStateLocked, RunStatusCancelled,
+		RunStatusCancelledDuringApply, RunStatusTimedOut, RunStatusNeedsAttention:
+		return true
+	default:
+		return false
+	}
+}
+
+// Run is a single clone -> init -> plan -> (optional) apply execution against a stack workspace
+type Run struct {
+	ID         string       `json:"id"`
+	Stack      string       `json:"stack"`
+	Workspace  string       `json:"workspace"`
+	Repository string       `json:"repository"`
+	Ref        string       `json:"ref"`
+	Path       string       `json:"path"`
+	CommitSHA  string       `json:"commit_sha,omitempty"`
+	Requester  string       `json:"requester"`
+	PlanOnly   bool         `json:"plan_only"`
+	Status     RunStatus    `json:"status"`
+	HasChanges bool         `json:"has_changes"`
+	Plan       *PlanSummary `json:"plan,omitempty"`
+	Error      string       `json:"error,omitempty"`
+
+	// PullRequest is the number of the pull request that triggered the run
+	PullRequest int `json:"pull_request,omitempty"`
+
+	// Group is the id of the deploy group the run is part of
+	Group string `json:"group,omitempty"`
+
+	// Pipeline is the id of the pipeline run the run is a stage of
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// Inputs records the upstream outputs injected as variables when the run was planned
+	Inputs []InputProvenance `json:"inputs,omitempty"`
+
+	// Attempts records every try at each phase of the run
+	Attempts []Attempt `json:"attempts,omitempty"`
+
+	// OutputTail is the end of the terraform output captured when the run did not succeed
+	OutputTail string `json:"output_tail,omitempty"`
+
+	// ApprovedBy is who approved applying the plan of a stack that needs approval
+	ApprovedBy string `json:"approved_by,omitempty"`
+
+	// CancelledBy is who asked for the run to be cancelled, or who rejected its plan
+	CancelledBy string `json:"cancelled_by,omitempty"`
+
+	// LogPath points at the file the terraform output of the run is written to
+	LogPath string `json:"log_path,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Attempt is a single try at a phase of a run
+type Attempt struct {
+	Phase      string    `json:"phase"`
+	Number     int       `json:"number"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+
+	// Retryable is whether the error was classified as transient
+	Retryable bool `json:"retryable"`
+}
+
+// PlanSummary counts the resource changes in a plan
+type PlanSummary struct {
+	Add     int `json:"add"`
+	Change  int `json:"change"`
+	Destroy int `json:"destroy"`
+}
+
+// DeployRequest is a request to run the terraform pipeline for a stack workspace, the repository and module path
+// come from the stack in the StackRegistry
+type DeployRequest struct {
+	Stack string `json:"stack"`
+
+	// Workspace defaults to the first workspace of the stack
+	Workspace string `json:"workspace"`
+
+	// Ref is a branch, tag or commit SHA of the stack's repository, the default branch is used when it is empty
+	Ref       string `json:"ref"`
+	Requester string `json:"requester"`
+	PlanOnly  bool   `json:"plan_only"`
+
+	// PullRequest is the number of the pull request that triggered the run, results are commented on it
+	PullRequest int `json:"pull_request,omitempty"`
+
+	// Group is set by the GroupCoordinator for runs of a deploy group, it can not be requested directly
+	Group string `json:"-"`
+
+	// Pipeline is set by the PipelineRunner for runs of a pipeline stage, it can not be requested directly
+	Pipeline string `json:"-"`
+}
+
+func (r DeployRequest) Validate() error {
+	switch {
+	case r.Stack == "":
+		return errors.New("stack is required")
+	case r.Requester == "":
+		return errors.New("requester is required")
+	}
+	return nil
+}
+
+// NewRun creates a queued run for a deploy request of a stack
+func NewRun(req DeployRequest, stack Stack) *Run {
+	workspace := req.Workspace
+	if workspace == "" && len(stack.Workspaces) > 0 {
+		workspace = stack.Workspaces[0]
+	} else if workspace == "" {
+		workspace = "default"
+	}
+
+	now := time.Now().UTC()
+	return &Run{
+		ID:          NewRunID(),
+		Stack:       req.Stack,
+		Workspace:   workspace,
+		Repository:  stack.Repository,
+		Ref:         req.Ref,
+		Path:        stack.Path,
+		Requester:   req.Requester,
+		PlanOnly:    req.PlanOnly,
+		PullRequest: req.PullRequest,
+		Group:       req.Group,
+		Pipeline:    req.Pipeline,
+		Status:      RunStatusQueued,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// NewRunID generates a new random run id
+func NewRunID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"crypto/subtle"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"net/http"
+	"strings"
+)
+
+// hooksPrefix is where git hosts post their webhooks, those requests are verified with the secret of each git host
+// instead of a bearer token
+const hooksPrefix = "/hooks/"
+
+type apiToken struct {
+	Name  string `mapstructure:"name"`
+	Token string `mapstructure:"token"`
+}
+
+func loadTokens(cfg *viper.Viper) ([]apiToken, error) {
+	var tokens []apiToken
+	if err := cfg.UnmarshalKey(config.ApiTokens.String(), &tokens); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", config.ApiTokens, err)
+	}
+
+	names := make(map[string]bool)
+	values := make(map[string]bool)
+	for _, t := range tokens {
+		switch {
+		case t.Name == "":
+			return nil, fmt.Errorf("invalid %s: every token needs a name", config.ApiTokens)
+		case names[t.Name]:
+			return nil, fmt.Errorf("invalid %s: %s is defined more than once", config.ApiTokens, t.Name)
+		case t.Token == "":
+			return nil, fmt.Errorf("invalid %s: %s needs a token", config.ApiTokens, t.Name)
+		case values[t.Token]:
+			return nil, fmt.Errorf("invalid %s: the token of %s is used by another caller", config.ApiTokens, t.Name)
+		}
+		names[t.Name] = true
+		values[t.Token] = true
+	}
+	return tokens, nil
+}
+
+// authenticate rejects requests that do not have the bearer token of a configured caller and sets the name of the
+// caller on the request context, every request is rejected when no tokens are configured
+func authenticate(tokens []apiToken) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasPrefix(r.URL.Path, hooksPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			name, ok := caller(tokens, r.Header.Get("Authorization"))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(map[string]string{"error": "a valid bearer token is required"})
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(internal.WithCaller(r.Context(), name)))
+		})
+	}
+}
+
+// caller returns the name of the caller the bearer token in header belongs to, every token is compared so the time
+// taken does not tell which caller a token is close to
+func caller(tokens []apiToken, header string) (string, bool) {
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || token == "" {
+		return "", false
+	}
+
+	name, ok := "", false
+	for _, t := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(token)) == 1 {
+			name, ok = t.Name, true
+		}
+	}
+	return name, ok
+}
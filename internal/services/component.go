@@ -1,8 +1,8 @@
 package services
 
 import (
-	"tracks-grpc/config"
-	"tracks-grpc/internal"
+	"deploy-runner/config"
+	"deploy-runner/internal"
 )
 
-var Component = internal.NewComponent("services", []config.EnvVar{}, NewServer)
+var Component = internal.NewComponent("services", []config.EnvVar{config.EnvHttpAddress}, NewServer, NewRouter, NewConfigValidator)
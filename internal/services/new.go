@@ -6,7 +6,6 @@ import (
 	"deploy-runner/internal/app"
 	"fmt"
 	"github.com/go-chi/chi/v5"
-	_ "github.com/go-chi/chi/v5/middleware"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 )
@@ -17,7 +16,7 @@ type serviceOut struct {
 }
 
 func NewServer(cfg *viper.Viper, log internal.BackgroundLog, rt *chi.Mux) serviceOut {
-	addr := fmt.Sprintf(":%s", cfg.GetString(config.GrpcAddress.String()))
+	addr := fmt.Sprintf(":%s", cfg.GetString(config.HttpAddress.String()))
 	return serviceOut{Service: &server{
 		log:     log,
 		address: addr,
@@ -0,0 +1,28 @@
+package services
+
+import (
+	"deploy-runner/internal"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+type routesContainer struct {
+	fx.In
+	Routes []internal.ApiRoutes `group:"routes"`
+}
+
+func NewRouter(cfg *viper.Viper, c routesContainer) (*chi.Mux, error) {
+	tokens, err := loadTokens(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rt := chi.NewRouter()
+	rt.Use(middleware.RequestID, middleware.Recoverer, authenticate(tokens))
+	for _, routes := range c.Routes {
+		routes.Mount(rt)
+	}
+	return rt, nil
+}
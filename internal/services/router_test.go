@@ -0,0 +1,84 @@
+package services
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"github.com/go-chi/chi/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	t.Run("TestAuthenticatesCaller", testAuthenticatesCaller)
+	t.Run("TestHooksSkipAuthentication", testHooksSkipAuthentication)
+	t.Run("TestInvalidTokens", testInvalidTokens)
+}
+
+// callerRoutes responds with the name of the authenticated caller
+type callerRoutes struct{}
+
+func (callerRoutes) Mount(r chi.Router) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(internal.Caller(r.Context())))
+	}
+	r.Get("/runs", handler)
+	r.Post("/hooks/github", handler)
+}
+
+func newTestRouter(t *testing.T, tokens []map[string]interface{}) *chi.Mux {
+	cfg := viper.New()
+	cfg.Set(config.ApiTokens.String(), tokens)
+	rt, err := NewRouter(cfg, routesContainer{Routes: []internal.ApiRoutes{callerRoutes{}}})
+	assert.NoError(t, err)
+	return rt
+}
+
+func request(rt http.Handler, method, path, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, req)
+	return rec
+}
+
+func testAuthenticatesCaller(t *testing.T) {
+	rt := newTestRouter(t, []map[string]interface{}{{"name": "alice", "token": "alice-token"}, {"name": "ci", "token": "ci-token"}})
+
+	rec := request(rt, http.MethodGet, "/runs", "Bearer ci-token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ci", rec.Body.String())
+
+	for _, authorization := range []string{"", "Bearer", "Bearer wrong", "ci-token", "Basic ci-token"} {
+		rec = request(rt, http.MethodGet, "/runs", authorization)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, authorization)
+	}
+
+	// Without tokens the api is closed
+	rec = request(newTestRouter(t, nil), http.MethodGet, "/runs", "Bearer ci-token")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func testHooksSkipAuthentication(t *testing.T) {
+	rec := request(newTestRouter(t, nil), http.MethodPost, "/hooks/github", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
+
+func testInvalidTokens(t *testing.T) {
+	for _, tokens := range [][]map[string]interface{}{
+		{{"token": "x"}},
+		{{"name": "alice"}},
+		{{"name": "alice", "token": "x"}, {"name": "alice", "token": "y"}},
+		{{"name": "alice", "token": "x"}, {"name": "bob", "token": "x"}},
+	} {
+		cfg := viper.New()
+		cfg.Set(config.ApiTokens.String(), tokens)
+		_, err := loadTokens(cfg)
+		assert.Error(t, err)
+	}
+}
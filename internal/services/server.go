@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"deploy-runner/internal"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"net"
+	"net/http"
 )
 
 const (
@@ -15,20 +17,31 @@ type server struct {
 	log        internal.BackgroundLog
 	router     *chi.Mux
 	address    string
+	httpServer *http.Server
 }
 
 func (s *server) Start(ctx context.Context) error {
 	lis, err := net.Listen(networkProtocol, s.address)
 	if err != nil {
-		s.log.ErrorfCtx(ctx, "Unable to initialize listener for tracksapi server")
+		s.log.ErrorfCtx(ctx, "Unable to initialize listener for api server")
 		return err
 	}
-	s.log.Infof("Starting grpc server on port: %s", lis.Addr().String())
+	s.log.Infof("Starting http server on port: %s", lis.Addr().String())
+
+	s.httpServer = &http.Server{Handler: s.router}
+	go func() {
+		if err := s.httpServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Err(err, "Http server stopped unexpectedly")
+		}
+	}()
 	return nil
 }
 
 func (s *server) Stop(ctx context.Context) error {
-	return nil
+	if s.httpServer == nil {
+		return nil
+	}
+	return s.httpServer.Shutdown(ctx)
 }
 
 func (s *server) Disabled() bool {
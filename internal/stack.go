@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Stack is a terraform root module in a git repository that the runner deploys to one or more workspaces
+type Stack struct {
+	Name       string   `json:"name" mapstructure:"-"`
+	Repository string   `json:"repository" mapstructure:"repository"`
+	Path       string   `json:"path,omitempty" mapstructure:"path"`
+	Workspaces []string `json:"workspaces" mapstructure:"workspaces"`
+
+	// DependsOn are stacks that have to be deployed before this one when they are deployed together in a group
+	DependsOn []string `json:"depends_on,omitempty" mapstructure:"depends_on"`
+
+	// TerraformVersion selects the terraform binary used for the stack, the default binary is used when it is empty
+	TerraformVersion string `json:"terraform_version,omitempty" mapstructure:"terraform_version"`
+
+	// BackendConfig is passed to terraform init as -backend-config key=value pairs
+	BackendConfig map[string]string `json:"backend_config,omitempty" mapstructure:"backend_config"`
+
+	// Variables are input variables passed to terraform plan
+	Variables map[string]interface{} `json:"variables,omitempty" mapstructure:"variables"`
+
+	// Inputs are variables sourced from the latest outputs of upstream stacks, keyed by variable name
+	Inputs map[string]StackInput `json:"inputs,omitempty" mapstructure:"inputs"`
+
+	// Approvers can approve applying plans of the stack, plans with changes wait for approval when there are any
+	Approvers []string `json:"approvers,omitempty" mapstructure:"approvers"`
+
+	// Schedules submit runs of the stack periodically, e.g. to detect drift
+	Schedules []StackSchedule `json:"schedules,omitempty" mapstructure:"schedules"`
+
+	// Timeouts override the default phase timeouts for the stack
+	Timeouts StackTimeouts `json:"timeouts" mapstructure:"timeouts"`
+}
+
+// RedactedValue replaces the values of backend config and variables in stacks returned by the api
+const RedactedValue = "(redacted)"
+
+// Redacted returns a copy of the stack with the values of its backend config and variables replaced, they can hold
+// credentials. The keys are kept so it is still visible what is set.
+func (s Stack) Redacted() Stack {
+	if s.BackendConfig != nil {
+		backendConfig := make(map[string]string, len(s.BackendConfig))
+		for k := range s.BackendConfig {
+			backendConfig[k] = RedactedValue
+		}
+		s.BackendConfig = backendConfig
+	}
+	if s.Variables != nil {
+		variables := make(map[string]interface{}, len(s.Variables))
+		for k := range s.Variables {
+			variables[k] = RedactedValue
+		}
+		s.Variables = variables
+	}
+	return s
+}
+
+// CanApprove reports if someone is an approver of the stack
+func (s Stack) CanApprove(approver string) bool {
+	for _, a := range s.Approvers {
+		if a == approver {
+			return true
+		}
+	}
+	return false
+}
+
+// HasWorkspace reports if a workspace is one of the stack's workspaces
+func (s Stack) HasWorkspace(workspace string) bool {
+	for _, w := range s.Workspaces {
+		if w == workspace {
+			return true
+		}
+	}
+	return false
+}
+
+// StackInput sources a variable from an output of an upstream stack
+type StackInput struct {
+	// Stack is the upstream stack, it has to be one of the stack's dependencies
+	Stack string `json:"stack" mapstructure:"stack"`
+
+	// Workspace is the upstream workspace, the workspace of the run is used when it is empty
+	Workspace string `json:"workspace,omitempty" mapstructure:"workspace"`
+
+	Output string `json:"output" mapstructure:"output"`
+}
+
+// StackSchedule submits a run of a stack on a cron schedule
+type StackSchedule struct {
+	// Cron is a standard 5 field cron expression
+	Cron string `json:"cron" mapstructure:"cron"`
+
+	// Workspace is the workspace to run, every workspace of the stack is run when it is empty
+	Workspace string `json:"workspace,omitempty" mapstructure:"workspace"`
+
+	// Apply applies the plan, scheduled runs only plan unless it is set
+	Apply bool `json:"apply" mapstructure:"apply"`
+}
+
+// StackTimeouts bound each phase of the stack's runs, zero uses the default timeout of the phase
+type StackTimeouts struct {
+	Init  time.Duration `json:"init" mapstructure:"init"`
+	Plan  time.Duration `json:"plan" mapstructure:"plan"`
+	Apply time.Duration `json:"apply" mapstructure:"apply"`
+}
+
+// MarshalJSON writes the timeouts as duration strings rather than nanoseconds
+func (t StackTimeouts) MarshalJSON() ([]byte, error) {
+	format := func(d time.Duration) string {
+		if d <= 0 {
+			return ""
+		}
+		return d.String()
+	}
+	return json.Marshal(map[string]string{"init": format(t.Init), "plan": format(t.Plan), "apply": format(t.Apply)})
+}
+
+func (t *StackTimeouts) UnmarshalJSON(data []byte) error {
+	var timeouts map[string]string
+	if err := json.Unmarshal(data, &timeouts); err != nil {
+		return err
+	}
+
+	*t = StackTimeouts{}
+	for name, d := range map[string]*time.Duration{"init": &t.Init, "plan": &t.Plan, "apply": &t.Apply} {
+		if timeouts[name] == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(timeouts[name])
+		if err != nil {
+			return fmt.Errorf("invalid %s timeout: %w", name, err)
+		}
+		*d = parsed
+	}
+	return nil
+}
+
+// StackRegistry holds the configured stacks and the repositories the runner is allowed to pull
+type StackRegistry interface {
+	// Get returns a stack by name
+	Get(name string) (Stack, bool)
+
+	// List returns every stack ordered by name
+	List() []Stack
+
+	// ForRepository returns the stacks defined in a repository, any of the urls a git host uses for the repository
+	// can be passed and they are compared ignoring scheme, user and a trailing .git
+	ForRepository(url string) []Stack
+
+	// RepositoryAllowed reports if a repository is in the allow list, every repository is allowed when it is empty
+	RepositoryAllowed(url string) bool
+}
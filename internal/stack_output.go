@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrOutputsNotFound is returned by an OutputStore when a stack workspace has no captured outputs
+var ErrOutputsNotFound = errors.New("stack workspace has no captured outputs")
+
+// StackOutputs are the outputs of a stack workspace captured after a run applied it
+type StackOutputs struct {
+	Stack      string                 `json:"stack"`
+	Workspace  string                 `json:"workspace"`
+	RunID      string                 `json:"run_id"`
+	CommitSHA  string                 `json:"commit_sha,omitempty"`
+	Outputs    map[string]OutputValue `json:"outputs"`
+	CapturedAt time.Time              `json:"captured_at"`
+}
+
+// Redacted returns a copy of the outputs with the values of sensitive outputs removed
+func (o StackOutputs) Redacted() StackOutputs {
+	outputs := make(map[string]OutputValue, len(o.Outputs))
+	for name, v := range o.Outputs {
+		if v.Sensitive {
+			v.Value = nil
+		}
+		outputs[name] = v
+	}
+	o.Outputs = outputs
+	return o
+}
+
+// InputProvenance records where a variable sourced from an upstream stack's output came from
+type InputProvenance struct {
+	Variable  string `json:"variable"`
+	Stack     string `json:"stack"`
+	Workspace string `json:"workspace"`
+	Output    string `json:"output"`
+
+	// RunID and CommitSHA are the upstream run that applied the output
+	RunID      string    `json:"run_id"`
+	CommitSHA  string    `json:"commit_sha,omitempty"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// OutputStore persists the latest outputs of each stack workspace
+type OutputStore interface {
+	// SaveOutputs replaces the outputs of a stack workspace
+	SaveOutputs(ctx context.Context, outputs *StackOutputs) error
+
+	// LatestOutputs loads the outputs of a stack workspace
+	LatestOutputs(ctx context.Context, stack, workspace string) (*StackOutputs, error)
+}
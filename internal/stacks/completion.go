@@ -0,0 +1,32 @@
+package stacks
+
+import (
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+type completer struct {
+	stacks internal.StackRegistry
+}
+
+// NewCompleter completes the first argument with the names of the stacks in the local config, for commands that run
+// without a server
+func NewCompleter(stacks internal.StackRegistry) app.ArgCompleter {
+	return &completer{stacks: stacks}
+}
+
+func (c *completer) Complete(args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []string
+	for _, stack := range c.stacks.List() {
+		if strings.HasPrefix(stack.Name, toComplete) {
+			completions = append(completions, stack.Name+"\t"+stack.Repository)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,8 @@
+package stacks
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("stacks", []config.EnvVar{}, NewRegistry, NewConfigValidator, NewScheduler)
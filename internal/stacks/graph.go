@@ -0,0 +1,92 @@
+package stacks
+
+import (
+	"deploy-runner/internal"
+	"fmt"
+	"strings"
+)
+
+// validateDependencies checks every dependency is a stack and that the dependencies form a DAG
+func (r *registry) validateDependencies() error {
+	for _, s := range r.List() {
+		for _, dep := range s.DependsOn {
+			if _, ok := r.stacks[dep]; !ok {
+				return fmt.Errorf("stack %s depends on unknown stack %s", s.Name, dep)
+			}
+		}
+		if err := r.validateInputs(s); err != nil {
+			return fmt.Errorf("stack %s: %w", s.Name, err)
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			// The cycle is the part of the path from the first visit of name
+			for i, p := range path {
+				if p == name {
+					return fmt.Errorf("stack dependency cycle %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range r.stacks[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+
+	// Visited in name order so the same cycle is reported every time
+	for _, s := range r.List() {
+		if err := visit(s.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateInputs checks every input of a stack comes from an output of one of its dependencies, so the upstream
+// stack is applied first when they are deployed together
+func (r *registry) validateInputs(s internal.Stack) error {
+	for variable, input := range s.Inputs {
+		upstream, ok := r.stacks[input.Stack]
+		switch {
+		case !ok || !dependsOn(s, input.Stack):
+			return fmt.Errorf("input %s comes from stack %s which is not in depends_on", variable, input.Stack)
+		case input.Output == "":
+			return fmt.Errorf("input %s needs an output", variable)
+		case input.Workspace != "" && !upstream.HasWorkspace(input.Workspace):
+			return fmt.Errorf("input %s comes from workspace %s which is not a workspace of stack %s", variable, input.Workspace, input.Stack)
+		}
+		if _, ok := s.Variables[variable]; ok {
+			return fmt.Errorf("variable %s is both a variable and an input", variable)
+		}
+	}
+	return nil
+}
+
+func dependsOn(s internal.Stack, name string) bool {
+	for _, dep := range s.DependsOn {
+		if dep == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package stacks
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+	"path"
+	"sort"
+	"strings"
+)
+
+const defaultWorkspace = "default"
+
+type allowedRepository struct {
+	URL string `mapstructure:"url"`
+	Key string `mapstructure:"key"`
+}
+
+type registry struct {
+	stacks  map[string]internal.Stack
+	allowed map[string]bool
+}
+
+func NewRegistry(cfg *viper.Viper) (internal.StackRegistry, error) {
+	r, err := load(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func load(cfg *viper.Viper) (*registry, error) {
+	r := &registry{
+		stacks:  make(map[string]internal.Stack),
+		allowed: make(map[string]bool),
+	}
+
+	var allowed []allowedRepository
+	if err := cfg.UnmarshalKey(config.AllowedGitRepositories.String(), &allowed); err != nil {
+		return r, fmt.Errorf("invalid %s: %w", config.AllowedGitRepositories, err)
+	}
+	for _, a := range allowed {
+		if a.URL == "" {
+			return r, fmt.Errorf("invalid %s: every repository needs a url", config.AllowedGitRepositories)
+		}
+		r.allowed[internal.NormalizeRepository(a.URL)] = true
+	}
+
+	var stacks map[string]internal.Stack
+	if err := cfg.UnmarshalKey(config.Stacks.String(), &stacks); err != nil {
+		return r, fmt.Errorf("invalid %s: %w", config.Stacks, err)
+	}
+	for name, s := range stacks {
+		s.Name = name
+		if len(s.Workspaces) == 0 {
+			s.Workspaces = []string{defaultWorkspace}
+		}
+		if err := r.validate(s); err != nil {
+			return r, fmt.Errorf("invalid %s: stack %s: %w", config.Stacks, name, err)
+		}
+		r.stacks[name] = s
+	}
+
+	if err := r.validateDependencies(); err != nil {
+		return r, fmt.Errorf("invalid %s: %w", config.Stacks, err)
+	}
+	return r, nil
+}
+
+func (r *registry) validate(s internal.Stack) error {
+	switch {
+	case s.Repository == "":
+		return fmt.Errorf("repository is required")
+	case !r.RepositoryAllowed(s.Repository):
+		return fmt.Errorf("repository %s is not in %s", s.Repository, config.AllowedGitRepositories)
+	case path.IsAbs(s.Path) || strings.HasPrefix(path.Clean(s.Path), ".."):
+		return fmt.Errorf("path must be relative to the repository root")
+	case strings.ContainsAny(s.TerraformVersion, `/\`) || strings.HasPrefix(s.TerraformVersion, "."):
+		return fmt.Errorf("terraform_version %s is not a version", s.TerraformVersion)
+	case s.Timeouts.Init < 0 || s.Timeouts.Plan < 0 || s.Timeouts.Apply < 0:
+		return fmt.Errorf("timeouts must not be negative")
+	}
+
+	for _, schedule := range s.Schedules {
+		if _, err := cron.ParseStandard(schedule.Cron); err != nil {
+			return fmt.Errorf("invalid schedule %q: %w", schedule.Cron, err)
+		}
+		if schedule.Workspace != "" && !s.HasWorkspace(schedule.Workspace) {
+			return fmt.Errorf("schedule %q runs workspace %s which is not a workspace of the stack", schedule.Cron, schedule.Workspace)
+		}
+	}
+	return nil
+}
+
+func (r *registry) Get(name string) (internal.Stack, bool) {
+	s, ok := r.stacks[name]
+	return s, ok
+}
+
+func (r *registry) List() []internal.Stack {
+	stacks := make([]internal.Stack, 0, len(r.stacks))
+	for _, s := range r.stacks {
+		stacks = append(stacks, s)
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		return stacks[i].Name < stacks[j].Name
+	})
+	return stacks
+}
+
+func (r *registry) ForRepository(repository string) []internal.Stack {
+	key := internal.NormalizeRepository(repository)
+	stacks := make([]internal.Stack, 0)
+	for _, s := range r.List() {
+		if internal.NormalizeRepository(s.Repository) == key {
+			stacks = append(stacks, s)
+		}
+	}
+	return stacks
+}
+
+func (r *registry) RepositoryAllowed(repository string) bool {
+	return len(r.allowed) == 0 || r.allowed[internal.NormalizeRepository(repository)]
+}
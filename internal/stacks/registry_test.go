@@ -0,0 +1,107 @@
+package stacks
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("TestForRepository", testForRepository)
+	t.Run("TestValidation", testValidation)
+	t.Run("TestRedacted", testRedacted)
+}
+
+func testForRepository(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set(config.AllowedGitRepositories.String(), []map[string]interface{}{{"url": "https://github.com/example/infra.git"}})
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://github.com/example/infra.git", "path": "network", "workspaces": []string{"dev", "prod"}},
+		"cluster": map[string]interface{}{"repository": "git@github.com:example/infra.git", "path": "cluster"},
+	})
+	r, err := load(cfg)
+	assert.NoError(t, err)
+
+	stacks := r.ForRepository("git@github.com:example/infra.git")
+	if !assert.Len(t, stacks, 2) {
+		return
+	}
+	assert.Equal(t, "cluster", stacks[0].Name)
+	assert.Equal(t, []string{"default"}, stacks[0].Workspaces)
+	assert.Equal(t, []string{"dev", "prod"}, stacks[1].Workspaces)
+
+	assert.True(t, r.RepositoryAllowed("https://github.com/example/infra"))
+	assert.False(t, r.RepositoryAllowed("https://github.com/example/other.git"))
+	assert.Empty(t, r.ForRepository("https://github.com/example/other.git"))
+}
+
+func testValidation(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set(config.AllowedGitRepositories.String(), []map[string]interface{}{{"url": "https://github.com/example/infra.git"}})
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://github.com/example/other.git"},
+	})
+	_, err := load(cfg)
+	assert.Error(t, err)
+
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://github.com/example/infra.git", "path": "../outside"},
+	})
+	_, err = load(cfg)
+	assert.Error(t, err)
+
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://github.com/example/infra.git", "schedules": []map[string]interface{}{{"cron": "every day"}}},
+	})
+	_, err = load(cfg)
+	assert.Error(t, err)
+
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{
+			"repository":        "https://github.com/example/infra.git",
+			"terraform_version": "1.0.11",
+			"approvers":         []string{"alice"},
+			"schedules":         []map[string]interface{}{{"cron": "0 6 * * *", "workspace": "default"}},
+			"timeouts":          map[string]interface{}{"apply": "2h"},
+		},
+	})
+	r, err := load(cfg)
+	assert.NoError(t, err)
+	stack, _ := r.Get("network")
+	assert.Equal(t, 2*time.Hour, stack.Timeouts.Apply)
+	assert.True(t, stack.CanApprove("alice"))
+
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{"repository": "https://github.com/example/infra.git", "depends_on": []string{"dns"}},
+		"cluster": map[string]interface{}{"repository": "https://github.com/example/infra.git", "depends_on": []string{"network"}},
+		"dns":     map[string]interface{}{"repository": "https://github.com/example/infra.git", "depends_on": []string{"cluster"}},
+	})
+	_, err = load(cfg)
+	assert.EqualError(t, err, "invalid STACKS: stack dependency cycle cluster -> network -> dns -> cluster")
+}
+
+func testRedacted(t *testing.T) {
+	cfg := viper.New()
+	cfg.Set(config.Stacks.String(), map[string]interface{}{
+		"network": map[string]interface{}{
+			"repository":     "https://github.com/example/infra.git",
+			"backend_config": map[string]interface{}{"access_key": "AKIA123"},
+			"variables":      map[string]interface{}{"db_password": "secret"},
+		},
+	})
+	r, err := load(cfg)
+	assert.NoError(t, err)
+
+	stack, _ := r.Get("network")
+	redacted := stack.Redacted()
+	assert.Equal(t, map[string]string{"access_key": internal.RedactedValue}, redacted.BackendConfig)
+	assert.Equal(t, map[string]interface{}{"db_password": internal.RedactedValue}, redacted.Variables)
+
+	// The stack used for runs keeps its values
+	stack, _ = r.Get("network")
+	assert.Equal(t, "AKIA123", stack.BackendConfig["access_key"])
+	assert.Equal(t, "secret", stack.Variables["db_password"])
+}
@@ -0,0 +1,89 @@
+package stacks
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/fx"
+)
+
+// scheduleRequester is the requester of runs submitted by a stack schedule
+const scheduleRequester = "schedule"
+
+type serviceOut struct {
+	fx.Out
+	Service app.Service `group:"services"`
+}
+
+// scheduler submits runs of stacks on their cron schedules
+type scheduler struct {
+	log          internal.BackgroundLog
+	stacks       internal.StackRegistry
+	orchestrator internal.Orchestrator
+	cron         *cron.Cron
+}
+
+func NewScheduler(log internal.BackgroundLog, stacks internal.StackRegistry, orchestrator internal.Orchestrator) serviceOut {
+	return serviceOut{Service: &scheduler{
+		log:          log.ChildLog("scheduler"),
+		stacks:       stacks,
+		orchestrator: orchestrator,
+	}}
+}
+
+func (s *scheduler) Start(ctx context.Context) error {
+	s.cron = cron.New()
+	for _, stack := range s.stacks.List() {
+		for _, schedule := range stack.Schedules {
+			stack, schedule := stack, schedule
+			if _, err := s.cron.AddFunc(schedule.Cron, func() { s.submit(stack, schedule) }); err != nil {
+				return err
+			}
+		}
+	}
+	s.cron.Start()
+	return nil
+}
+
+func (s *scheduler) Stop(ctx context.Context) error {
+	if s.cron == nil {
+		return nil
+	}
+	// Stop only waits for schedules that are submitting runs, the runs themselves are executed by the workers
+	select {
+	case <-s.cron.Stop().Done():
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func (s *scheduler) Disabled() bool {
+	for _, stack := range s.stacks.List() {
+		if len(stack.Schedules) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *scheduler) submit(stack internal.Stack, schedule internal.StackSchedule) {
+	workspaces := stack.Workspaces
+	if schedule.Workspace != "" {
+		workspaces = []string{schedule.Workspace}
+	}
+
+	for _, workspace := range workspaces {
+		run, err := s.orchestrator.Submit(context.Background(), internal.DeployRequest{
+			Stack:     stack.Name,
+			Workspace: workspace,
+			Requester: scheduleRequester,
+			PlanOnly:  !schedule.Apply,
+		})
+		if err != nil {
+			s.log.Errw(err, "Unable to submit scheduled run", "stack", stack.Name, "workspace", workspace, "cron", schedule.Cron)
+			continue
+		}
+		s.log.Infow("Scheduled run submitted", "run_id", run.ID, "stack", stack.Name, "workspace", workspace, "cron", schedule.Cron)
+	}
+}
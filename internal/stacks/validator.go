@@ -0,0 +1,25 @@
+package stacks
+
+import (
+	"deploy-runner/config"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+type validatorOut struct {
+	fx.Out
+	Validator config.Validator `group:"configValidators"`
+}
+
+type configValidator struct {
+	cfg *viper.Viper
+}
+
+func NewConfigValidator(cfg *viper.Viper) validatorOut {
+	return validatorOut{Validator: &configValidator{cfg: cfg}}
+}
+
+func (v *configValidator) Validate() error {
+	_, err := load(v.cfg)
+	return err
+}
@@ -0,0 +1,32 @@
+package internal
+
+import "context"
+
+// CommitState is the state of a commit status shown by a git host
+type CommitState string
+
+const (
+	CommitStatePending CommitState = "pending"
+	CommitStateRunning CommitState = "running"
+	CommitStateSuccess CommitState = "success"
+	CommitStateFailure CommitState = "failure"
+)
+
+// CommitStatus is the result of a run shown against a commit by its git host
+type CommitStatus struct {
+	SHA string
+
+	// Context tells the statuses of a commit apart, there is one per stack and workspace
+	Context     string
+	State       CommitState
+	Description string
+}
+
+// CommitStatusReporter posts run results to the git host of a repository
+type CommitStatusReporter interface {
+	// SetStatus creates or replaces the status of a commit for the status's context
+	SetStatus(ctx context.Context, status CommitStatus) error
+
+	// Comment adds a comment to a pull request, or merge request on hosts that call them that
+	Comment(ctx context.Context, pullRequest int, body string) error
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRunNotFound is returned by a RunStore when a run does not exist
+var ErrRunNotFound = errors.New("run not found")
+
+// PhaseTransition records a run moving from one status to another
+type PhaseTransition struct {
+	From    RunStatus `json:"from"`
+	To      RunStatus `json:"to"`
+	At      time.Time `json:"at"`
+	Message string    `json:"message,omitempty"`
+}
+
+// RunFilter narrows down the runs returned by RunStore.List, zero values match everything
+type RunFilter struct {
+	Stack     string
+	Status    RunStatus
+	Requester string
+	Since     time.Time
+	Until     time.Time
+
+	// Limit is the max number of runs in a page
+	Limit int
+
+	// Cursor is the RunPage.NextCursor of the previous page
+	Cursor string
+}
+
+// RunPage is a page of runs ordered newest first
+type RunPage struct {
+	Runs       []*Run `json:"runs"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// RunStore persists run history so it survives restarts
+type RunStore interface {
+	// Create saves a new run
+	Create(ctx context.Context, run *Run) error
+
+	// Update saves an existing run, a PhaseTransition is recorded when its status changed since it was last saved.
+	// Create and Update also record a lifecycle Event in the EventOutbox for the run's new status.
+	Update(ctx context.Context, run *Run) error
+
+	// UpdateWithEvent saves an existing run like Update but records an Event of eventType with message in the
+	// EventOutbox instead of the lifecycle Event, whether or not the status changed
+	UpdateWithEvent(ctx context.Context, run *Run, eventType EventType, message string) error
+
+	// Get loads a run by id
+	Get(ctx context.Context, id string) (*Run, error)
+
+	// List returns a page of runs matching filter
+	List(ctx context.Context, filter RunFilter) (RunPage, error)
+
+	// Transitions returns the status changes of a run oldest first
+	Transitions(ctx context.Context, id string) ([]PhaseTransition, error)
+}
+
+// OutboxEntry is an event waiting in the EventOutbox to be published
+type OutboxEntry struct {
+	Sequence uint64
+	Event    Event
+
+	// Delivered are the names of the subscribers that have already handled the event
+	Delivered []string
+}
+
+// EventOutbox holds run lifecycle events written by the RunStore in the same transaction as the change they describe,
+// so an event is never lost if the runner stops before it has been published
+type EventOutbox interface {
+	// Pending returns up to limit unpublished events with a sequence after the given one oldest first
+	Pending(ctx context.Context, after uint64, limit int) ([]OutboxEntry, error)
+
+	// MarkDelivered records that a subscriber has handled an event so it is not handed the event again
+	MarkDelivered(ctx context.Context, sequence uint64, subscriber string) error
+
+	// Ack removes an event every subscriber has handled from the outbox
+	Ack(ctx context.Context, sequence uint64) error
+}
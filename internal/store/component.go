@@ -0,0 +1,8 @@
+package store
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent("store", []config.EnvVar{config.EnvStorePath}, NewDB, NewRunStore, NewEventOutbox, NewDeliveryStore, NewGroupStore, NewOutputStore, NewPipelineStore, NewGitHookStore)
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/config"
+	"fmt"
+	"github.com/spf13/viper"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/fx"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const openTimeout = 5 * time.Second
+
+// NewDB opens the embedded database and applies any pending migrations
+func NewDB(cfg *viper.Viper, lc fx.Lifecycle) (*bolt.DB, error) {
+	db, err := open(cfg.GetString(config.StorePath.String()))
+	if err != nil {
+		return nil, err
+	}
+
+	lc.Append(fx.Hook{OnStop: func(ctx context.Context) error {
+		return db.Close()
+	}})
+	return db, nil
+}
+
+func open(path string) (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create store directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open store %s: %w", path, err)
+	}
+
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("unable to migrate store %s: %w", path, err)
+	}
+	return db, nil
+}
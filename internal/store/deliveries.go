@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+type deliveryStore struct {
+	db *bolt.DB
+}
+
+func NewDeliveryStore(db *bolt.DB) internal.DeliveryStore {
+	return &deliveryStore{db: db}
+}
+
+func (s *deliveryStore) CreateDelivery(ctx context.Context, delivery *internal.WebhookDelivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		deliveries := tx.Bucket(deliveriesBucket)
+		if deliveries.Get([]byte(delivery.ID)) != nil {
+			return fmt.Errorf("webhook delivery %s already exists", delivery.ID)
+		}
+
+		if err := tx.Bucket(deliveriesByTimeBucket).Put(timeKey(delivery.CreatedAt, delivery.ID), []byte(delivery.ID)); err != nil {
+			return err
+		}
+		if delivery.RedeliveryOf == "" {
+			if err := tx.Bucket(deliveriesByEventBucket).Put(eventKey(delivery.EventID, delivery.Subscription), []byte(delivery.ID)); err != nil {
+				return err
+			}
+		}
+		return putDelivery(tx, delivery)
+	})
+}
+
+func (s *deliveryStore) UpdateDelivery(ctx context.Context, delivery *internal.WebhookDelivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(deliveriesBucket).Get([]byte(delivery.ID)) == nil {
+			return internal.ErrDeliveryNotFound
+		}
+		return putDelivery(tx, delivery)
+	})
+}
+
+func (s *deliveryStore) GetDelivery(ctx context.Context, id string) (*internal.WebhookDelivery, error) {
+	var delivery internal.WebhookDelivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getDelivery(tx, []byte(id), &delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (s *deliveryStore) FindDelivery(ctx context.Context, eventID, subscription string) (*internal.WebhookDelivery, error) {
+	var delivery internal.WebhookDelivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		id := tx.Bucket(deliveriesByEventBucket).Get(eventKey(eventID, subscription))
+		if id == nil {
+			return internal.ErrDeliveryNotFound
+		}
+		return getDelivery(tx, id, &delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (s *deliveryStore) ListDeliveries(ctx context.Context, filter internal.DeliveryFilter) (internal.DeliveryPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	var start []byte
+	if filter.Cursor != "" {
+		var err error
+		if start, err = hex.DecodeString(filter.Cursor); err != nil {
+			return internal.DeliveryPage{}, fmt.Errorf("%w: bad cursor", internal.ErrInvalidRequest)
+		}
+	}
+
+	page := internal.DeliveryPage{Deliveries: make([]*internal.WebhookDelivery, 0, limit)}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(deliveriesByTimeBucket).Cursor()
+
+		// Walked newest first the same way as runs, the cursor is the last key returned
+		var k, v []byte
+		if start == nil {
+			k, v = c.Last()
+		} else {
+			c.Seek(start)
+			k, v = c.Prev()
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			var delivery internal.WebhookDelivery
+			if err := getDelivery(tx, v, &delivery); err != nil {
+				return err
+			}
+			if !matchesDelivery(&delivery, filter) {
+				continue
+			}
+
+			if len(page.Deliveries) == limit {
+				last := page.Deliveries[len(page.Deliveries)-1]
+				page.NextCursor = hex.EncodeToString(timeKey(last.CreatedAt, last.ID))
+				return nil
+			}
+			page.Deliveries = append(page.Deliveries, &delivery)
+		}
+		return nil
+	})
+
+	return page, err
+}
+
+func (s *deliveryStore) PendingDeliveries(ctx context.Context, now time.Time, limit int) ([]*internal.WebhookDelivery, error) {
+	deliveries := make([]*internal.WebhookDelivery, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(pendingDeliveryBucket).Cursor()
+		for k, _ := c.First(); k != nil && len(deliveries) < limit; k, _ = c.Next() {
+			var delivery internal.WebhookDelivery
+			if err := getDelivery(tx, k, &delivery); err != nil {
+				return err
+			}
+			if delivery.NextAttemptAt.After(now) {
+				continue
+			}
+			deliveries = append(deliveries, &delivery)
+		}
+		return nil
+	})
+	return deliveries, err
+}
+
+// eventKey is the key of the first delivery of an event to a subscription, names and ids can not contain a NUL byte
+func eventKey(eventID, subscription string) []byte {
+	return []byte(eventID + "\x00" + subscription)
+}
+
+// putDelivery saves a delivery keeping the index of pending deliveries up to date
+func putDelivery(tx *bolt.Tx, delivery *internal.WebhookDelivery) error {
+	pending := tx.Bucket(pendingDeliveryBucket)
+	var err error
+	if delivery.Status == internal.DeliveryStatusPending {
+		err = pending.Put([]byte(delivery.ID), nil)
+	} else {
+		err = pending.Delete([]byte(delivery.ID))
+	}
+	if err != nil {
+		return err
+	}
+	return putJSON(tx.Bucket(deliveriesBucket), []byte(delivery.ID), delivery)
+}
+
+func getDelivery(tx *bolt.Tx, id []byte, delivery *internal.WebhookDelivery) error {
+	data := tx.Bucket(deliveriesBucket).Get(id)
+	if data == nil {
+		return internal.ErrDeliveryNotFound
+	}
+	return json.Unmarshal(data, delivery)
+}
+
+func matchesDelivery(delivery *internal.WebhookDelivery, filter internal.DeliveryFilter) bool {
+	switch {
+	case filter.Subscription != "" && delivery.Subscription != filter.Subscription:
+		return false
+	case filter.RunID != "" && delivery.RunID != filter.RunID:
+		return false
+	case filter.Status != "" && delivery.Status != filter.Status:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+)
+
+type gitHookStore struct {
+	db *bolt.DB
+}
+
+func NewGitHookStore(db *bolt.DB) internal.GitHookStore {
+	return &gitHookStore{db: db}
+}
+
+func (s *gitHookStore) SaveHookDelivery(ctx context.Context, delivery *internal.GitHookDelivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(gitHookDeliveriesBucket), []byte(delivery.ID), delivery)
+	})
+}
+
+func (s *gitHookStore) GetHookDelivery(ctx context.Context, id string) (*internal.GitHookDelivery, error) {
+	var delivery internal.GitHookDelivery
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gitHookDeliveriesBucket).Get([]byte(id))
+		if data == nil {
+			return internal.ErrHookDeliveryNotFound
+		}
+		return json.Unmarshal(data, &delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+type groupStore struct {
+	db *bolt.DB
+}
+
+func NewGroupStore(db *bolt.DB) internal.GroupStore {
+	return &groupStore{db: db}
+}
+
+func (s *groupStore) CreateGroup(ctx context.Context, group *internal.DeployGroup) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		groups := tx.Bucket(groupsBucket)
+		if groups.Get([]byte(group.ID)) != nil {
+			return fmt.Errorf("deploy group %s already exists", group.ID)
+		}
+		return putJSON(groups, []byte(group.ID), group)
+	})
+}
+
+func (s *groupStore) UpdateGroup(ctx context.Context, group *internal.DeployGroup) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		groups := tx.Bucket(groupsBucket)
+		if groups.Get([]byte(group.ID)) == nil {
+			return internal.ErrGroupNotFound
+		}
+		return putJSON(groups, []byte(group.ID), group)
+	})
+}
+
+func (s *groupStore) GetGroup(ctx context.Context, id string) (*internal.DeployGroup, error) {
+	var group internal.DeployGroup
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(groupsBucket).Get([]byte(id))
+		if data == nil {
+			return internal.ErrGroupNotFound
+		}
+		return json.Unmarshal(data, &group)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
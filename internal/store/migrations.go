@@ -0,0 +1,95 @@
+package store
+
+import (
+	"encoding/binary"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	metaBucket        = []byte("meta")
+	runsBucket        = []byte("runs")
+	runsByTimeBucket  = []byte("runs_by_created")
+	transitionsBucket = []byte("transitions")
+	outboxBucket      = []byte("outbox")
+
+	// outboxDeliveriesBucket has a key per outbox sequence and subscriber that has handled the event
+	outboxDeliveriesBucket = []byte("outbox_deliveries")
+
+	deliveriesBucket        = []byte("webhook_deliveries")
+	deliveriesByTimeBucket  = []byte("webhook_deliveries_by_created")
+	pendingDeliveryBucket   = []byte("webhook_deliveries_pending")
+	deliveriesByEventBucket = []byte("webhook_deliveries_by_event")
+
+	groupsBucket  = []byte("deploy_groups")
+	outputsBucket = []byte("stack_outputs")
+
+	pipelineRunsBucket       = []byte("pipeline_runs")
+	pipelineRunsByTimeBucket = []byte("pipeline_runs_by_created")
+	activePipelineRunsBucket = []byte("pipeline_runs_active")
+
+	gitHookDeliveriesBucket = []byte("git_hook_deliveries")
+
+	schemaVersionKey = []byte("schema_version")
+)
+
+type migration struct {
+	name string
+	up   func(tx *bolt.Tx) error
+}
+
+// migrations are applied in order and must never be reordered or removed, the schema version stored in the database
+// is the number of migrations that have been applied
+var migrations = []migration{
+	{name: "create run buckets", up: createBuckets(runsBucket, runsByTimeBucket, transitionsBucket)},
+	{name: "create outbox bucket", up: createBuckets(outboxBucket)},
+	{name: "create webhook delivery buckets", up: createBuckets(deliveriesBucket, deliveriesByTimeBucket, pendingDeliveryBucket)},
+	{name: "create deploy group bucket", up: createBuckets(groupsBucket)},
+	{name: "create stack outputs bucket", up: createBuckets(outputsBucket)},
+	{name: "create pipeline run buckets", up: createBuckets(pipelineRunsBucket, pipelineRunsByTimeBucket, activePipelineRunsBucket)},
+	{name: "create outbox deliveries bucket", up: createBuckets(outboxDeliveriesBucket)},
+	{name: "create webhook deliveries by event bucket", up: createBuckets(deliveriesByEventBucket)},
+	{name: "create git webhook deliveries bucket", up: createBuckets(gitHookDeliveriesBucket)},
+}
+
+func migrate(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists(metaBucket)
+		if err != nil {
+			return err
+		}
+
+		version := 0
+		if v := meta.Get(schemaVersionKey); v != nil {
+			version = int(binary.BigEndian.Uint64(v))
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("database schema version %d is newer than this binary supports (%d)", version, len(migrations))
+		}
+
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i].up(tx); err != nil {
+				return fmt.Errorf("migration %d (%s) failed: %w", i+1, migrations[i].name, err)
+			}
+		}
+
+		return meta.Put(schemaVersionKey, itob(uint64(len(migrations))))
+	})
+}
+
+func createBuckets(names ...[]byte) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
@@ -0,0 +1,76 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/internal"
+	"encoding/binary"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+)
+
+type outbox struct {
+	db *bolt.DB
+}
+
+func NewEventOutbox(db *bolt.DB) internal.EventOutbox {
+	return &outbox{db: db}
+}
+
+func (o *outbox) Pending(ctx context.Context, after uint64, limit int) ([]internal.OutboxEntry, error) {
+	entries := make([]internal.OutboxEntry, 0)
+	err := o.db.View(func(tx *bolt.Tx) error {
+		deliveries := tx.Bucket(outboxDeliveriesBucket)
+		c := tx.Bucket(outboxBucket).Cursor()
+		for k, v := c.Seek(itob(after + 1)); k != nil && len(entries) < limit; k, v = c.Next() {
+			entry := internal.OutboxEntry{Sequence: binary.BigEndian.Uint64(k)}
+			if err := json.Unmarshal(v, &entry.Event); err != nil {
+				return err
+			}
+
+			dc := deliveries.Cursor()
+			for dk, _ := dc.Seek(k); dk != nil && bytes.HasPrefix(dk, k); dk, _ = dc.Next() {
+				entry.Delivered = append(entry.Delivered, string(dk[len(k):]))
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (o *outbox) MarkDelivered(ctx context.Context, sequence uint64, subscriber string) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxDeliveriesBucket).Put(deliveryKey(sequence, subscriber), []byte{})
+	})
+}
+
+func (o *outbox) Ack(ctx context.Context, sequence uint64) error {
+	return o.db.Update(func(tx *bolt.Tx) error {
+		prefix := itob(sequence)
+		c := tx.Bucket(outboxDeliveriesBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Seek(prefix) {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(outboxBucket).Delete(prefix)
+	})
+}
+
+// deliveryKey is the outbox sequence followed by the subscriber name so the deliveries of an event are next to each
+// other
+func deliveryKey(sequence uint64, subscriber string) []byte {
+	return append(itob(sequence), subscriber...)
+}
+
+// appendOutbox records an event to be published, keys are the bucket sequence so events are published in the order
+// they were recorded
+func appendOutbox(tx *bolt.Tx, event internal.Event) error {
+	b := tx.Bucket(outboxBucket)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	return putJSON(b, itob(seq), event)
+}
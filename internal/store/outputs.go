@@ -0,0 +1,42 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+)
+
+type outputStore struct {
+	db *bolt.DB
+}
+
+func NewOutputStore(db *bolt.DB) internal.OutputStore {
+	return &outputStore{db: db}
+}
+
+func (s *outputStore) SaveOutputs(ctx context.Context, outputs *internal.StackOutputs) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(outputsBucket), outputsKey(outputs.Stack, outputs.Workspace), outputs)
+	})
+}
+
+func (s *outputStore) LatestOutputs(ctx context.Context, stack, workspace string) (*internal.StackOutputs, error) {
+	var outputs internal.StackOutputs
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(outputsBucket).Get(outputsKey(stack, workspace))
+		if data == nil {
+			return internal.ErrOutputsNotFound
+		}
+		return json.Unmarshal(data, &outputs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &outputs, nil
+}
+
+// outputsKey separates the stack and workspace with a byte neither can contain
+func outputsKey(stack, workspace string) []byte {
+	return []byte(stack + "\x00" + workspace)
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+)
+
+type pipelineStore struct {
+	db *bolt.DB
+}
+
+func NewPipelineStore(db *bolt.DB) internal.PipelineStore {
+	return &pipelineStore{db: db}
+}
+
+func (s *pipelineStore) CreatePipelineRun(ctx context.Context, run *internal.PipelineRun) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(pipelineRunsBucket).Get([]byte(run.ID)) != nil {
+			return fmt.Errorf("pipeline run %s already exists", run.ID)
+		}
+
+		if err := tx.Bucket(pipelineRunsByTimeBucket).Put(timeKey(run.CreatedAt, run.ID), []byte(run.ID)); err != nil {
+			return err
+		}
+		return putPipelineRun(tx, run)
+	})
+}
+
+func (s *pipelineStore) UpdatePipelineRun(ctx context.Context, run *internal.PipelineRun) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(pipelineRunsBucket).Get([]byte(run.ID)) == nil {
+			return internal.ErrPipelineRunNotFound
+		}
+		return putPipelineRun(tx, run)
+	})
+}
+
+func (s *pipelineStore) GetPipelineRun(ctx context.Context, id string) (*internal.PipelineRun, error) {
+	var run internal.PipelineRun
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getPipelineRun(tx, []byte(id), &run)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *pipelineStore) ListPipelineRuns(ctx context.Context, filter internal.PipelineRunFilter) (internal.PipelineRunPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	var start []byte
+	if filter.Cursor != "" {
+		var err error
+		if start, err = hex.DecodeString(filter.Cursor); err != nil {
+			return internal.PipelineRunPage{}, fmt.Errorf("%w: bad cursor", internal.ErrInvalidRequest)
+		}
+	}
+
+	page := internal.PipelineRunPage{Runs: make([]*internal.PipelineRun, 0, limit)}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(pipelineRunsByTimeBucket).Cursor()
+
+		// Walked newest first the same way as runs, the cursor is the last key returned
+		var k, v []byte
+		if start == nil {
+			k, v = c.Last()
+		} else {
+			c.Seek(start)
+			k, v = c.Prev()
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			var run internal.PipelineRun
+			if err := getPipelineRun(tx, v, &run); err != nil {
+				return err
+			}
+			if (filter.Pipeline != "" && run.Pipeline != filter.Pipeline) || (filter.Status != "" && run.Status != filter.Status) {
+				continue
+			}
+
+			if len(page.Runs) == limit {
+				last := page.Runs[len(page.Runs)-1]
+				page.NextCursor = hex.EncodeToString(timeKey(last.CreatedAt, last.ID))
+				return nil
+			}
+			page.Runs = append(page.Runs, &run)
+		}
+		return nil
+	})
+
+	return page, err
+}
+
+func (s *pipelineStore) ActivePipelineRuns(ctx context.Context) ([]*internal.PipelineRun, error) {
+	runs := make([]*internal.PipelineRun, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(activePipelineRunsBucket).ForEach(func(k, _ []byte) error {
+			var run internal.PipelineRun
+			if err := getPipelineRun(tx, k, &run); err != nil {
+				return err
+			}
+			runs = append(runs, &run)
+			return nil
+		})
+	})
+	return runs, err
+}
+
+// putPipelineRun saves a pipeline run keeping the index of active pipeline runs up to date
+func putPipelineRun(tx *bolt.Tx, run *internal.PipelineRun) error {
+	active := tx.Bucket(activePipelineRunsBucket)
+	var err error
+	if run.Status.Terminal() {
+		err = active.Delete([]byte(run.ID))
+	} else {
+		err = active.Put([]byte(run.ID), nil)
+	}
+	if err != nil {
+		return err
+	}
+	return putJSON(tx.Bucket(pipelineRunsBucket), []byte(run.ID), run)
+}
+
+func getPipelineRun(tx *bolt.Tx, id []byte, run *internal.PipelineRun) error {
+	data := tx.Bucket(pipelineRunsBucket).Get(id)
+	if data == nil {
+		return internal.ErrPipelineRunNotFound
+	}
+	return json.Unmarshal(data, run)
+}
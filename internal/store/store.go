@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+const defaultPageSize = 50
+
+type runStore struct {
+	db *bolt.DB
+}
+
+func NewRunStore(db *bolt.DB) internal.RunStore {
+	return &runStore{db: db}
+}
+
+func (s *runStore) Create(ctx context.Context, run *internal.Run) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		if runs.Get([]byte(run.ID)) != nil {
+			return fmt.Errorf("run %s already exists", run.ID)
+		}
+
+		if err := putJSON(runs, []byte(run.ID), run); err != nil {
+			return err
+		}
+		if err := tx.Bucket(runsByTimeBucket).Put(timeKey(run.CreatedAt, run.ID), []byte(run.ID)); err != nil {
+			return err
+		}
+
+		if err := appendTransition(tx, run.ID, internal.PhaseTransition{To: run.Status, At: run.CreatedAt}); err != nil {
+			return err
+		}
+		return appendOutbox(tx, internal.NewEvent(internal.RunStatusEventType(run.Status), run, ""))
+	})
+}
+
+func (s *runStore) Update(ctx context.Context, run *internal.Run) error {
+	return s.update(run, "", "")
+}
+
+func (s *runStore) UpdateWithEvent(ctx context.Context, run *internal.Run, eventType internal.EventType, message string) error {
+	return s.update(run, eventType, message)
+}
+
+// update saves a run, the lifecycle event of its status is recorded when eventType is empty
+func (s *runStore) update(run *internal.Run, eventType internal.EventType, message string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		var existing internal.Run
+		if err := getJSON(runs, []byte(run.ID), &existing); err != nil {
+			return err
+		}
+
+		if existing.Status != run.Status {
+			transition := internal.PhaseTransition{From: existing.Status, To: run.Status, At: run.UpdatedAt}
+			if run.Status.Terminal() {
+				transition.Message = run.Error
+			}
+			if err := appendTransition(tx, run.ID, transition); err != nil {
+				return err
+			}
+			if eventType == "" {
+				eventType, message = internal.RunStatusEventType(run.Status), transition.Message
+			}
+		}
+
+		if eventType != "" {
+			event := internal.NewEvent(eventType, run, message)
+			event.PreviousStatus = existing.Status
+			if err := appendOutbox(tx, event); err != nil {
+				return err
+			}
+		}
+
+		return putJSON(runs, []byte(run.ID), run)
+	})
+}
+
+func (s *runStore) Get(ctx context.Context, id string) (*internal.Run, error) {
+	var run internal.Run
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return getJSON(tx.Bucket(runsBucket), []byte(id), &run)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *runStore) List(ctx context.Context, filter internal.RunFilter) (internal.RunPage, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	var start []byte
+	if filter.Cursor != "" {
+		var err error
+		if start, err = hex.DecodeString(filter.Cursor); err != nil {
+			return internal.RunPage{}, fmt.Errorf("%w: bad cursor", internal.ErrInvalidRequest)
+		}
+	}
+
+	page := internal.RunPage{Runs: make([]*internal.Run, 0, limit)}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		runs := tx.Bucket(runsBucket)
+		c := tx.Bucket(runsByTimeBucket).Cursor()
+
+		// Runs are walked newest first, the cursor is the last key returned so paging resumes just before it
+		var k, v []byte
+		if start == nil {
+			k, v = c.Last()
+		} else {
+			c.Seek(start)
+			k, v = c.Prev()
+		}
+
+		for ; k != nil; k, v = c.Prev() {
+			created := keyTime(k)
+			if !filter.Until.IsZero() && created.After(filter.Until) {
+				continue
+			}
+			if !filter.Since.IsZero() && created.Before(filter.Since) {
+				break
+			}
+
+			var run internal.Run
+			if err := getJSON(runs, v, &run); err != nil {
+				return err
+			}
+			if !matches(&run, filter) {
+				continue
+			}
+
+			if len(page.Runs) == limit {
+				// There is at least one more match so hand out a cursor for the next page
+				page.NextCursor = hex.EncodeToString(lastKey(page.Runs))
+				return nil
+			}
+			page.Runs = append(page.Runs, &run)
+		}
+		return nil
+	})
+
+	return page, err
+}
+
+func (s *runStore) Transitions(ctx context.Context, id string) ([]internal.PhaseTransition, error) {
+	transitions := make([]internal.PhaseTransition, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(transitionsBucket).Bucket([]byte(id))
+		if b == nil {
+			return internal.ErrRunNotFound
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var t internal.PhaseTransition
+			if err := json.Unmarshal(v, &t); err != nil {
+				return err
+			}
+			transitions = append(transitions, t)
+			return nil
+		})
+	})
+	return transitions, err
+}
+
+func matches(run *internal.Run, filter internal.RunFilter) bool {
+	switch {
+	case filter.Stack != "" && run.Stack != filter.Stack:
+		return false
+	case filter.Status != "" && run.Status != filter.Status:
+		return false
+	case filter.Requester != "" && run.Requester != filter.Requester:
+		return false
+	}
+	return true
+}
+
+func appendTransition(tx *bolt.Tx, runID string, transition internal.PhaseTransition) error {
+	b, err := tx.Bucket(transitionsBucket).CreateBucketIfNotExists([]byte(runID))
+	if err != nil {
+		return err
+	}
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	return putJSON(b, itob(seq), transition)
+}
+
+func putJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, data)
+}
+
+func getJSON(b *bolt.Bucket, key []byte, v interface{}) error {
+	data := b.Get(key)
+	if data == nil {
+		return internal.ErrRunNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+// timeKey orders runs by creation time with the id appended to keep keys unique
+func timeKey(t time.Time, id string) []byte {
+	return append(itob(uint64(t.UnixNano())), []byte(id)...)
+}
+
+func keyTime(k []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k[:8]))).UTC()
+}
+
+func lastKey(runs []*internal.Run) []byte {
+	last := runs[len(runs)-1]
+	return timeKey(last.CreatedAt, last.ID)
+}
@@ -0,0 +1,246 @@
+package store
+
+import (
+	"context"
+	"deploy-runner/internal"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunStore(t *testing.T) {
+	t.Run("TestCreateGetUpdate", testCreateGetUpdate)
+	t.Run("TestListPagingAndFilters", testListPagingAndFilters)
+	t.Run("TestReopenKeepsRuns", testReopenKeepsRuns)
+	t.Run("TestOutbox", testOutbox)
+	t.Run("TestDeliveries", testDeliveries)
+	t.Run("TestOutputs", testOutputs)
+}
+
+func newTestStore(t *testing.T) (internal.RunStore, string) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	db, err := open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return NewRunStore(db), path
+}
+
+func testRun(id, stack, requester string, created time.Time) *internal.Run {
+	return &internal.Run{ID: id, Stack: stack, Workspace: "default", Requester: requester, Status: internal.RunStatusQueued, CreatedAt: created, UpdatedAt: created}
+}
+
+func testCreateGetUpdate(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	run := testRun("1", "network", "alice", now)
+	assert.NoError(t, s.Create(ctx, run))
+	assert.Error(t, s.Create(ctx, run))
+
+	run.Status = internal.RunStatusPlanning
+	run.UpdatedAt = now.Add(time.Second)
+	assert.NoError(t, s.Update(ctx, run))
+
+	run.Status = internal.RunStatusFailed
+	run.Error = "boom"
+	assert.NoError(t, s.Update(ctx, run))
+
+	loaded, err := s.Get(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RunStatusFailed, loaded.Status)
+
+	transitions, err := s.Transitions(ctx, "1")
+	assert.NoError(t, err)
+	assert.Len(t, transitions, 3)
+	assert.Equal(t, internal.RunStatusPlanning, transitions[2].From)
+	assert.Equal(t, "boom", transitions[2].Message)
+
+	_, err = s.Get(ctx, "missing")
+	assert.ErrorIs(t, err, internal.ErrRunNotFound)
+}
+
+func testListPagingAndFilters(t *testing.T) {
+	s, _ := newTestStore(t)
+	ctx := context.Background()
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		stack := "network"
+		if i%2 == 1 {
+			stack = "cluster"
+		}
+		assert.NoError(t, s.Create(ctx, testRun(fmt.Sprint(i), stack, "alice", start.Add(time.Duration(i)*time.Hour))))
+	}
+
+	page, err := s.List(ctx, internal.RunFilter{Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4", "3"}, ids(page.Runs))
+	assert.NotEmpty(t, page.NextCursor)
+
+	page, err = s.List(ctx, internal.RunFilter{Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "1"}, ids(page.Runs))
+
+	page, err = s.List(ctx, internal.RunFilter{Limit: 2, Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0"}, ids(page.Runs))
+	assert.Empty(t, page.NextCursor)
+
+	page, err = s.List(ctx, internal.RunFilter{Stack: "network"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4", "2", "0"}, ids(page.Runs))
+
+	page, err = s.List(ctx, internal.RunFilter{Since: start.Add(time.Hour), Until: start.Add(3 * time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3", "2", "1"}, ids(page.Runs))
+
+	_, err = s.List(ctx, internal.RunFilter{Cursor: "not-hex"})
+	assert.ErrorIs(t, err, internal.ErrInvalidRequest)
+}
+
+func testReopenKeepsRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	db, err := open(path)
+	assert.NoError(t, err)
+	assert.NoError(t, NewRunStore(db).Create(context.Background(), testRun("1", "network", "alice", time.Now().UTC())))
+	assert.NoError(t, db.Close())
+
+	// Reopening runs the migrations again which must be a no-op
+	db, err = open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	run, err := NewRunStore(db).Get(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "network", run.Stack)
+}
+
+func testOutbox(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	db, err := open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	s, outbox := NewRunStore(db), NewEventOutbox(db)
+	ctx := context.Background()
+
+	run := testRun("1", "network", "alice", time.Now().UTC())
+	assert.NoError(t, s.Create(ctx, run))
+	run.CommitSHA = "abc"
+	assert.NoError(t, s.Update(ctx, run))
+	run.Status = internal.RunStatusPlanning
+	assert.NoError(t, s.Update(ctx, run))
+	run.Status = internal.RunStatusTimedOut
+	run.Error = "plan phase failed: context deadline exceeded"
+	assert.NoError(t, s.Update(ctx, run))
+
+	// Saves without a status change do not record an event
+	entries, err := outbox.Pending(ctx, 0, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, entries, 3) {
+		return
+	}
+	assert.Equal(t, internal.EventRunQueued, entries[0].Event.Type)
+	assert.Equal(t, internal.EventRunPlanning, entries[1].Event.Type)
+	assert.Equal(t, internal.RunStatusQueued, entries[1].Event.PreviousStatus)
+	assert.Equal(t, "abc", entries[1].Event.Run.CommitSHA)
+	assert.Equal(t, internal.EventRunFailed, entries[2].Event.Type)
+	assert.Equal(t, internal.RunStatusTimedOut, entries[2].Event.Run.Status)
+	assert.Equal(t, run.Error, entries[2].Event.Message)
+
+	assert.NoError(t, outbox.MarkDelivered(ctx, entries[1].Sequence, "kafka"))
+	assert.NoError(t, outbox.MarkDelivered(ctx, entries[1].Sequence, "webhooks"))
+	assert.NoError(t, outbox.Ack(ctx, entries[0].Sequence))
+	entries, err = outbox.Pending(ctx, 0, 1)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, internal.EventRunPlanning, entries[0].Event.Type)
+	assert.Equal(t, []string{"kafka", "webhooks"}, entries[0].Delivered)
+
+	// Acking removes the deliveries of the event with it
+	sequence := entries[0].Sequence
+	assert.NoError(t, outbox.Ack(ctx, sequence))
+	entries, err = outbox.Pending(ctx, sequence-1, 10)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Empty(t, entries[0].Delivered)
+}
+
+func testDeliveries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runs.db")
+	db, err := open(path)
+	assert.NoError(t, err)
+	defer db.Close()
+	s := NewDeliveryStore(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	for i, sub := range []string{"chatops", "audit", "chatops"} {
+		created := now.Add(time.Duration(i) * time.Second)
+		assert.NoError(t, s.CreateDelivery(ctx, &internal.WebhookDelivery{ID: fmt.Sprint(i), Subscription: sub, EventID: fmt.Sprint("event-", i), Status: internal.DeliveryStatusPending, NextAttemptAt: created, CreatedAt: created}))
+	}
+	assert.NoError(t, s.CreateDelivery(ctx, &internal.WebhookDelivery{ID: "3", Subscription: "audit", EventID: "event-1", RedeliveryOf: "1", CreatedAt: now}))
+
+	found, err := s.FindDelivery(ctx, "event-1", "audit")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", found.ID)
+	_, err = s.FindDelivery(ctx, "event-1", "chatops")
+	assert.ErrorIs(t, err, internal.ErrDeliveryNotFound)
+
+	pending, err := s.PendingDeliveries(ctx, now.Add(time.Second), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	d, err := s.GetDelivery(ctx, "0")
+	assert.NoError(t, err)
+	d.Status = internal.DeliveryStatusSucceeded
+	assert.NoError(t, s.UpdateDelivery(ctx, d))
+
+	pending, err = s.PendingDeliveries(ctx, now.Add(time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	page, err := s.ListDeliveries(ctx, internal.DeliveryFilter{Subscription: "chatops", Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "2", page.Deliveries[0].ID)
+	page, err = s.ListDeliveries(ctx, internal.DeliveryFilter{Subscription: "chatops", Cursor: page.NextCursor})
+	assert.NoError(t, err)
+	assert.Equal(t, "0", page.Deliveries[0].ID)
+	assert.Equal(t, internal.DeliveryStatusSucceeded, page.Deliveries[0].Status)
+
+	_, err = s.GetDelivery(ctx, "missing")
+	assert.ErrorIs(t, err, internal.ErrDeliveryNotFound)
+}
+
+func ids(runs []*internal.Run) []string {
+	result := make([]string, 0, len(runs))
+	for _, r := range runs {
+		result = append(result, r.ID)
+	}
+	return result
+}
+
+func testOutputs(t *testing.T) {
+	db, err := open(filepath.Join(t.TempDir(), "runs.db"))
+	assert.NoError(t, err)
+	defer db.Close()
+	s := NewOutputStore(db)
+	ctx := context.Background()
+
+	_, err = s.LatestOutputs(ctx, "network", "dev")
+	assert.ErrorIs(t, err, internal.ErrOutputsNotFound)
+
+	for _, runID := range []string{"first", "second"} {
+		assert.NoError(t, s.SaveOutputs(ctx, &internal.StackOutputs{Stack: "network", Workspace: "dev", RunID: runID, Outputs: map[string]internal.OutputValue{
+			"vpc_id":   {Value: []byte(`"vpc-123"`)},
+			"password": {Value: []byte(`"secret"`), Sensitive: true},
+		}}))
+	}
+
+	outputs, err := s.LatestOutputs(ctx, "network", "dev")
+	assert.NoError(t, err)
+	assert.Equal(t, "second", outputs.RunID)
+	assert.Nil(t, outputs.Redacted().Outputs["password"].Value)
+	assert.JSONEq(t, `"secret"`, string(outputs.Outputs["password"].Value))
+}
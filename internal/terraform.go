@@ -1,3 +1,98 @@
 package internal
 
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
 
+// TerraformClient runs terraform commands against a single module working directory
+type TerraformClient interface {
+	// Init runs terraform init in the working directory
+	Init(ctx context.Context) error
+
+	// Workspace selects the named terraform workspace creating it if it does not exist yet
+	Workspace(ctx context.Context, name string) error
+
+	// Plan runs terraform plan writing the plan to planFile and returns whether the plan contains changes
+	Plan(ctx context.Context, planFile string) (bool, error)
+
+	// ShowPlan summarizes the resource changes in a plan file previously written by Plan
+	ShowPlan(ctx context.Context, planFile string) (*PlanSummary, error)
+
+	// Apply applies a plan file previously written by Plan
+	Apply(ctx context.Context, planFile string) error
+
+	// Output reads the root module outputs from the state of the selected workspace
+	Output(ctx context.Context) (map[string]OutputValue, error)
+
+	// ForceUnlock releases a state lock held by another process
+	ForceUnlock(ctx context.Context, lockID string) error
+
+	// Validate runs terraform validate returning the errors and warnings it found, the error is only set when
+	// terraform was unable to validate the module
+	Validate(ctx context.Context) ([]TerraformDiagnostic, error)
+
+	// FormatCheck returns the files of the module, relative to the working directory, that terraform fmt would change
+	FormatCheck(ctx context.Context) ([]string, error)
+
+	// Test runs the terraform test files of the module
+	Test(ctx context.Context) error
+}
+
+// TerraformDiagnostic is an error or warning terraform reported about a module
+type TerraformDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+// OutputValue is a root module output of a stack
+type OutputValue struct {
+	Value     json.RawMessage `json:"value"`
+	Sensitive bool            `json:"sensitive"`
+}
+
+// TerraformOptions configure a TerraformClient for a stack
+type TerraformOptions struct {
+	// Version selects an installed terraform version, the default terraform binary is used when it is empty
+	Version string
+
+	// BackendConfig is passed to terraform init as -backend-config key=value pairs
+	BackendConfig map[string]string
+
+	// NoBackend initializes the module without its backend, for checks of a module that do not need its state
+	NoBackend bool
+}
+
+// TerraformFactory creates TerraformClient instances for a working directory
+type TerraformFactory interface {
+	// NewClient creates a client for workDir, all terraform output is written to output when it is not nil
+	NewClient(workDir string, output io.Writer, opts TerraformOptions) (TerraformClient, error)
+}
+
+// StateLockedError is returned from a TerraformClient when terraform could not acquire the state lock because it is
+// held by someone else
+type StateLockedError struct {
+	LockID    string
+	Path      string
+	Operation string
+	Who       string
+	Created   string
+	Err       error
+}
+
+func (e *StateLockedError) Error() string {
+	if e.LockID == "" {
+		return fmt.Sprintf("terraform state is locked: %v", e.Err)
+	}
+	return fmt.Sprintf("terraform state is locked by %s (lock id: %s, operation: %s, created: %s)", e.Who, e.LockID, e.Operation, e.Created)
+}
+
+func (e *StateLockedError) Unwrap() error {
+	return e.Err
+}
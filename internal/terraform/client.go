@@ -1,17 +1,224 @@
 package terraform
 
 import (
-	"github.com/hashicorp/terraform-exec/tfexec"
+	"bytes"
+	"context"
+	"deploy-runner/internal"
+	"encoding/json"
+	"fmt"
+	tfjson "github.com/hashicorp/terraform-json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// planChangesExitCode is the exit code of plan -detailed-exitcode when the plan has changes
+	planChangesExitCode = 2
+
+	// fmtChangesExitCode is the exit code of fmt -check when files are not formatted
+	fmtChangesExitCode = 3
+)
+
+var (
+	stateLockErrRegexp  = regexp.MustCompile(`Error acquiring the state lock`)
+	noWorkspaceRegexp   = regexp.MustCompile(`Workspace ".+" doesn't exist`)
+	stateLockInfoRegexp = regexp.MustCompile(`Lock Info:\n\s*ID:\s*([^\n]+)\n\s*Path:\s*([^\n]+)\n\s*Operation:\s*([^\n]+)\n\s*Who:\s*([^\n]+)\n\s*Version:\s*([^\n]+)\n\s*Created:\s*([^\n]+)\n`)
 )
 
 type client struct {
-	tfClient *tfexec.Terraform
+	execPath    string
+	workDir     string
+	output      io.Writer
+	backend     map[string]string
+	noBackend   bool
+	lockTimeout string
+	gracePeriod time.Duration
+}
+
+func (c *client) Init(ctx context.Context) error {
+	args := []string{"init", "-input=false", "-no-color"}
+	if c.noBackend {
+		args = append(args, "-backend=false")
+	}
+
+	// Sorted so the command line is the same on every run
+	keys := make([]string, 0, len(c.backend))
+	for k := range c.backend {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-backend-config="+k+"="+c.backend[k])
+	}
+
+	_, err := c.run(ctx, args...)
+	return wrapError(err)
+}
+
+func (c *client) Workspace(ctx context.Context, name string) error {
+	if name == "" || name == "default" {
+		return nil
+	}
+
+	// select is run without output so a workspace that does not exist yet is not reported as an error in the run output
+	_, err := runCommand(ctx, c.execPath, c.workDir, nil, nil, c.gracePeriod, "workspace", "select", "-no-color", name)
+	if err != nil && ctx.Err() == nil && noWorkspaceRegexp.MatchString(err.Error()) {
+		_, err = c.run(ctx, "workspace", "new", "-no-color", "-lock-timeout="+c.lockTimeout, name)
+	}
+	return wrapError(err)
+}
+
+func (c *client) Plan(ctx context.Context, planFile string) (bool, error) {
+	code, err := c.run(ctx, "plan", "-input=false", "-no-color", "-detailed-exitcode", "-lock=true",
+		"-lock-timeout="+c.lockTimeout, "-out="+planFile)
+	if code == planChangesExitCode && ctx.Err() == nil {
+		return true, nil
+	}
+	return false, wrapError(err)
+}
+
+func (c *client) ShowPlan(ctx context.Context, planFile string) (*internal.PlanSummary, error) {
+	// Captured rather than written to the run output since the json plan holds the values of sensitive attributes
+	var out bytes.Buffer
+	if _, err := runCommand(ctx, c.execPath, c.workDir, &out, nil, c.gracePeriod, "show", "-json", "-no-color", planFile); err != nil {
+		return nil, wrapError(err)
+	}
+
+	var plan tfjson.Plan
+	if err := json.Unmarshal(out.Bytes(), &plan); err != nil {
+		return nil, fmt.Errorf("unable to decode terraform plan: %w", err)
+	}
+	return summarizePlan(&plan), nil
 }
 
-func NewClient(workDir, execPath string) *client {
-	cl, err := tfexec.NewTerraform(workDir, execPath)
-	if err != nil {
+func (c *client) Apply(ctx context.Context, planFile string) error {
+	_, err := c.run(ctx, "apply", "-input=false", "-no-color", "-auto-approve", "-lock=true",
+		"-lock-timeout="+c.lockTimeout, planFile)
+	return wrapError(err)
+}
+
+func (c *client) Output(ctx context.Context) (map[string]internal.OutputValue, error) {
+	// Captured rather than written to the run output since outputs can be sensitive, only stdout is decoded since
+	// stderr can hold deprecation and provider warnings
+	var out bytes.Buffer
+	if _, err := runCommand(ctx, c.execPath, c.workDir, &out, nil, c.gracePeriod, "output", "-json", "-no-color"); err != nil {
+		return nil, wrapError(err)
+	}
+
+	outputs := make(map[string]internal.OutputValue)
+	if err := json.Unmarshal(out.Bytes(), &outputs); err != nil {
+		return nil, fmt.Errorf("unable to decode terraform output: %w", err)
+	}
+	return outputs, nil
+}
+
+func (c *client) ForceUnlock(ctx context.Context, lockID string) error {
+	_, err := c.run(ctx, "force-unlock", "-force", lockID)
+	return err
+}
+
+func (c *client) Validate(ctx context.Context) ([]internal.TerraformDiagnostic, error) {
+	// validate exits non zero when the module is invalid, the diagnostics are still written to stdout as json. Only
+	// stdout is parsed, stderr can hold warnings and is kept for the error message
+	var out bytes.Buffer
+	_, runErr := runCommand(ctx, c.execPath, c.workDir, &out, nil, c.gracePeriod, "validate", "-json", "-no-color")
+
+	var result tfjson.ValidateOutput
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		if runErr != nil {
+			return nil, runErr
+		}
+		return nil, fmt.Errorf("unable to decode terraform validate output: %w", err)
+	}
+
+	diagnostics := make([]internal.TerraformDiagnostic, 0, len(result.Diagnostics))
+	for _, d := range result.Diagnostics {
+		diagnostic := internal.TerraformDiagnostic{
+			Severity: string(d.Severity),
+			Summary:  d.Summary,
+			Detail:   d.Detail,
+		}
+		if d.Range != nil {
+			diagnostic.Filename = d.Range.Filename
+			diagnostic.Line = d.Range.Start.Line
+			diagnostic.Column = d.Range.Start.Column
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics, nil
+}
+
+func (c *client) FormatCheck(ctx context.Context) ([]string, error) {
+	// The unformatted files are listed on stdout, stderr is kept for the error message
+	var out bytes.Buffer
+	code, err := runCommand(ctx, c.execPath, c.workDir, &out, nil, c.gracePeriod, "fmt", "-check", "-recursive", "-list=true", "-no-color")
+	if err != nil && (code != fmtChangesExitCode || ctx.Err() != nil) {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+func (c *client) Test(ctx context.Context) error {
+	_, err := c.run(ctx, "test", "-no-color")
+	return err
+}
+
+func (c *client) run(ctx context.Context, args ...string) (int, error) {
+	output := newLockedWriter(c.output)
+	return runCommand(ctx, c.execPath, c.workDir, output, output, c.gracePeriod, args...)
+}
+
+func summarizePlan(plan *tfjson.Plan) *internal.PlanSummary {
+	summary := &internal.PlanSummary{}
+	for _, rc := range plan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+		actions := rc.Change.Actions
+		switch {
+		case actions.Replace():
+			summary.Add++
+			summary.Destroy++
+		case actions.Create():
+			summary.Add++
+		case actions.Update():
+			summary.Change++
+		case actions.Delete():
+			summary.Destroy++
+		}
+	}
+	return summary
+}
+
+// wrapError converts terraform state lock errors into internal.StateLockedError so callers can tell them apart from
+// other failures
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	if !stateLockErrRegexp.MatchString(message) {
+		return err
+	}
 
+	lockedErr := &internal.StateLockedError{Err: err}
+	if info := stateLockInfoRegexp.FindStringSubmatch(message); len(info) == 7 {
+		lockedErr.LockID = strings.TrimSpace(info[1])
+		lockedErr.Path = strings.TrimSpace(info[2])
+		lockedErr.Operation = strings.TrimSpace(info[3])
+		lockedErr.Who = strings.TrimSpace(info[4])
+		lockedErr.Created = strings.TrimSpace(info[6])
 	}
-	return &client{tfClient: cl}
+	return lockedErr
 }
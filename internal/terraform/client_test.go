@@ -0,0 +1,45 @@
+package terraform
+
+import (
+	"deploy-runner/internal"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWrapError(t *testing.T) {
+	t.Run("TestNil", testWrapNil)
+	t.Run("TestParsedLockError", testWrapParsedLockError)
+	t.Run("TestUnparsedLockError", testWrapUnparsedLockError)
+	t.Run("TestOtherError", testWrapOtherError)
+}
+
+func testWrapNil(t *testing.T) {
+	assert.NoError(t, wrapError(nil))
+}
+
+func testWrapParsedLockError(t *testing.T) {
+	err := wrapError(errors.New("terraform apply failed: exit status 1\nError: Error acquiring the state lock\n\n" +
+		"Lock Info:\n  ID:        abc-123\n  Path:      state/terraform.tfstate\n  Operation: OperationTypeApply\n" +
+		"  Who:       someone@host\n  Version:   1.0.11\n  Created:   2021-11-02 10:00:00 +0000 UTC\n  Info:\n"))
+
+	var locked *internal.StateLockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.Equal(t, "abc-123", locked.LockID)
+	assert.Equal(t, "someone@host", locked.Who)
+}
+
+func testWrapUnparsedLockError(t *testing.T) {
+	err := wrapError(errors.New("exit status 1\nError: Error acquiring the state lock\n\nsomething unexpected"))
+
+	var locked *internal.StateLockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.Empty(t, locked.LockID)
+}
+
+func testWrapOtherError(t *testing.T) {
+	err := wrapError(errors.New("Error: Invalid provider configuration"))
+
+	var locked *internal.StateLockedError
+	assert.False(t, errors.As(err, &locked))
+}
@@ -0,0 +1,120 @@
+package terraform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxErrOutput is the number of bytes at the end of stderr kept for the error of a failed command
+const maxErrOutput = 16 * 1024
+
+// runCommand runs terraform in workDir and returns its exit code. When ctx is done terraform is sent an interrupt so it
+// can release the state lock and persist state, it is killed if it has not exited once gracePeriod has passed or right
+// away when it can't be interrupted. stdout and stderr receive the output streams of terraform and can be nil, a
+// writer getting both streams has to be wrapped with newLockedWriter since the streams are copied from separate
+// goroutines.
+func runCommand(ctx context.Context, execPath, workDir string, stdout, stderr io.Writer, gracePeriod time.Duration, args ...string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return -1, err
+	}
+
+	errOutput := &tailBuffer{max: maxErrOutput}
+	cmd := exec.Command(execPath, args...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1", "TF_INPUT=0")
+	cmd.Stdout = stdout
+	cmd.Stderr = errOutput
+	if stderr != nil {
+		cmd.Stderr = io.MultiWriter(stderr, errOutput)
+	}
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return -1, fmt.Errorf("unable to start terraform %s: %w", args[0], err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if interruptErr := interrupt(cmd); interruptErr != nil {
+			_ = kill(cmd)
+			<-done
+			return -1, fmt.Errorf("terraform %s could not be interrupted (%v) and was killed, the state lock may still be held: %w", args[0], interruptErr, ctx.Err())
+		}
+		select {
+		case err = <-done:
+		case <-time.After(gracePeriod):
+			_ = kill(cmd)
+			err = <-done
+			return -1, fmt.Errorf("terraform %s did not exit within %s of being interrupted and was killed, the state lock may still be held: %w", args[0], gracePeriod, ctx.Err())
+		}
+		return exitCode(err), fmt.Errorf("terraform %s was interrupted: %w", args[0], ctx.Err())
+	}
+
+	if err != nil {
+		return exitCode(err), fmt.Errorf("terraform %s failed: %w\n%s", args[0], err, strings.TrimSpace(errOutput.String()))
+	}
+	return 0, nil
+}
+
+// tailBuffer keeps the last max bytes written to it, it is only written to from the goroutine copying stderr
+type tailBuffer struct {
+	buf []byte
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.max; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// lockedWriter serializes writes so the stdout and stderr of terraform can be written to the same writer
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newLockedWriter returns w wrapped in a lockedWriter, or nil when w is nil so runCommand discards the output
+func newLockedWriter(w io.Writer) io.Writer {
+	if w == nil {
+		return nil
+	}
+	return &lockedWriter{w: w}
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	if err == nil {
+		return 0
+	}
+	return -1
+}
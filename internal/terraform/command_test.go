@@ -0,0 +1,151 @@
+//go:build !windows
+// +build !windows
+
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/internal"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunCommand(t *testing.T) {
+	t.Run("TestExitCode", testRunCommandExitCode)
+	t.Run("TestInterrupt", testRunCommandInterrupt)
+	t.Run("TestKillAfterGracePeriod", testRunCommandKill)
+	t.Run("TestValidateDiagnostics", testValidateDiagnostics)
+	t.Run("TestFormatCheck", testFormatCheck)
+	t.Run("TestOutput", testOutput)
+	t.Run("TestShowPlan", testShowPlan)
+	t.Run("TestWorkspace", testWorkspace)
+	t.Run("TestErrOutputTail", testErrOutputTail)
+}
+
+// fakeTerraform writes a shell script standing in for the terraform binary
+func fakeTerraform(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "terraform")
+	assert.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+func testRunCommandExitCode(t *testing.T) {
+	tf := fakeTerraform(t, "echo planned\necho locked >&2\nexit 2\n")
+	var out bytes.Buffer
+
+	combined := newLockedWriter(&out)
+	code, err := runCommand(context.Background(), tf, t.TempDir(), combined, combined, time.Second, "plan")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "locked")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, out.String(), "planned\n")
+	assert.Contains(t, out.String(), "locked\n")
+}
+
+func testRunCommandInterrupt(t *testing.T) {
+	tf := fakeTerraform(t, "trap 'echo interrupted; exit 1' INT\nwhile true; do sleep 0.05; done\n")
+	var out bytes.Buffer
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	_, err := runCommand(ctx, tf, t.TempDir(), &out, nil, 5*time.Second, "apply")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, out.String(), "interrupted")
+}
+
+func testRunCommandKill(t *testing.T) {
+	tf := fakeTerraform(t, "trap '' INT\nwhile true; do sleep 0.05; done\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := runCommand(ctx, tf, t.TempDir(), nil, nil, 200*time.Millisecond, "apply")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Contains(t, err.Error(), "killed")
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
+
+func testValidateDiagnostics(t *testing.T) {
+	tf := fakeTerraform(t, `echo 'Warning: provider deprecated' >&2
+echo '{"format_version":"1.0","valid":false,"error_count":1,"warning_count":0,"diagnostics":[{"severity":"error","summary":"Unsupported argument","detail":"Not expected here.","range":{"filename":"main.tf","start":{"line":3,"column":5,"byte":40},"end":{"line":3,"column":9,"byte":44}}}]}'
+exit 1
+`)
+	c := &client{execPath: tf, workDir: t.TempDir(), gracePeriod: time.Second}
+
+	diagnostics, err := c.Validate(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.TerraformDiagnostic{
+		{Severity: "error", Summary: "Unsupported argument", Detail: "Not expected here.", Filename: "main.tf", Line: 3, Column: 5},
+	}, diagnostics)
+}
+
+func testFormatCheck(t *testing.T) {
+	tf := fakeTerraform(t, "echo main.tf\necho 'Warning: provider deprecated' >&2\necho modules/vpc/variables.tf\nexit 3\n")
+	c := &client{execPath: tf, workDir: t.TempDir(), gracePeriod: time.Second}
+
+	files, err := c.FormatCheck(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"main.tf", "modules/vpc/variables.tf"}, files)
+
+	c.execPath = fakeTerraform(t, "echo broken >&2\nexit 2\n")
+	_, err = c.FormatCheck(context.Background())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "broken")
+}
+
+func testOutput(t *testing.T) {
+	tf := fakeTerraform(t, `echo 'Warning: Deprecated attribute' >&2
+echo '{"vpc_id":{"sensitive":false,"type":"string","value":"vpc-123"}}'
+`)
+	c := &client{execPath: tf, workDir: t.TempDir(), gracePeriod: time.Second}
+
+	outputs, err := c.Output(context.Background())
+	assert.NoError(t, err)
+	assert.JSONEq(t, `"vpc-123"`, string(outputs["vpc_id"].Value))
+}
+
+func testShowPlan(t *testing.T) {
+	tf := fakeTerraform(t, `echo '{"format_version":"1.0","resource_changes":[{"address":"aws_db_instance.main","change":{"actions":["create"],"after":{"password":"SUPERSECRET"}}},{"address":"aws_vpc.main","change":{"actions":["delete","create"]}}]}'
+`)
+	var output bytes.Buffer
+	c := &client{execPath: tf, workDir: t.TempDir(), output: &output, gracePeriod: time.Second}
+
+	summary, err := c.ShowPlan(context.Background(), "plan.tfplan")
+	assert.NoError(t, err)
+	assert.Equal(t, &internal.PlanSummary{Add: 2, Destroy: 1}, summary)
+	// The json plan holds sensitive values so it never reaches the run output
+	assert.NotContains(t, output.String(), "SUPERSECRET")
+	assert.Empty(t, output.String())
+}
+
+func testWorkspace(t *testing.T) {
+	dir := t.TempDir()
+	tf := fakeTerraform(t, `echo "$@" >> calls
+if [ "$2" = select ]; then
+  printf 'Workspace "%s" doesn'"'"'t exist.\n\nYou can create this workspace with the "new" subcommand.\n' "$4" >&2
+  exit 1
+fi
+echo "Created and switched to workspace \"$5\"!"
+`)
+	var out bytes.Buffer
+	c := &client{execPath: tf, workDir: dir, output: &out, lockTimeout: "1m0s", gracePeriod: time.Second}
+
+	assert.NoError(t, c.Workspace(context.Background(), "prod"))
+	calls, err := os.ReadFile(filepath.Join(dir, "calls"))
+	assert.NoError(t, err)
+	assert.Equal(t, "workspace select -no-color prod\nworkspace new -no-color -lock-timeout=1m0s prod\n", string(calls))
+	assert.Equal(t, "Created and switched to workspace \"prod\"!\n", out.String())
+}
+
+func testErrOutputTail(t *testing.T) {
+	tf := fakeTerraform(t, "head -c 100000 /dev/zero | tr '\\0' x >&2\necho 'Error: Unsupported argument' >&2\nexit 1\n")
+
+	_, err := runCommand(context.Background(), tf, t.TempDir(), nil, nil, time.Second, "plan")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error: Unsupported argument")
+	assert.Less(t, len(err.Error()), maxErrOutput+100)
+}
@@ -1 +1,16 @@
 package terraform
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent(
+	"terraform",
+	[]config.EnvVar{
+		config.EnvTerraformExecPath,
+		config.EnvTerraformInstallDir,
+		config.EnvTerraformLockTimeout,
+		config.EnvTerraformInterruptGracePeriod},
+	NewFactory,
+)
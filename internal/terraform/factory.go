@@ -0,0 +1,50 @@
+package terraform
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+type factory struct {
+	execPath    string
+	installDir  string
+	lockTimeout string
+	gracePeriod time.Duration
+}
+
+func NewFactory(cfg *viper.Viper) internal.TerraformFactory {
+	return &factory{
+		execPath:    cfg.GetString(config.TerraformExecPath.String()),
+		installDir:  cfg.GetString(config.TerraformInstallDir.String()),
+		lockTimeout: cfg.GetDuration(config.TerraformLockTimeout.String()).String(),
+		gracePeriod: cfg.GetDuration(config.TerraformInterruptGracePeriod.String()),
+	}
+}
+
+func (f *factory) NewClient(workDir string, output io.Writer, opts internal.TerraformOptions) (internal.TerraformClient, error) {
+	binary := f.execPath
+	if opts.Version != "" {
+		binary = filepath.Join(f.installDir, opts.Version, "terraform")
+	}
+
+	execPath, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find terraform binary %s: %w", binary, err)
+	}
+
+	return &client{
+		execPath:    execPath,
+		workDir:     workDir,
+		output:      output,
+		backend:     opts.BackendConfig,
+		noBackend:   opts.NoBackend,
+		lockTimeout: f.lockTimeout,
+		gracePeriod: f.gracePeriod,
+	}, nil
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package terraform
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs terraform in its own process group so signals reach the provider plugins it starts as well
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func interrupt(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGINT)
+}
+
+func kill(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
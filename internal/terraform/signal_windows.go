@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package terraform
+
+import (
+	"golang.org/x/sys/windows"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs terraform in its own process group so it can be sent a ctrl-break without reaching the runner,
+// windows has no support for sending processes an interrupt signal
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: windows.CREATE_NEW_PROCESS_GROUP}
+}
+
+// interrupt fails when the runner has no console, terraform is killed right away then
+func interrupt(cmd *exec.Cmd) error {
+	return windows.GenerateConsoleCtrlEvent(windows.CTRL_BREAK_EVENT, uint32(cmd.Process.Pid))
+}
+
+func kill(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrDeliveryNotFound is returned by a DeliveryStore when a webhook delivery does not exist
+var ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+// DeliveryStatus is the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending means the delivery has not succeeded yet and will be attempted again
+	DeliveryStatusPending DeliveryStatus = "pending"
+
+	// DeliveryStatusSucceeded means the subscriber responded with a 2xx status
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded"
+
+	// DeliveryStatusFailed means every attempt failed, the delivery can still be redelivered by hand
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is an event sent, or waiting to be sent, to a webhook subscription
+type WebhookDelivery struct {
+	ID           string          `json:"id"`
+	Subscription string          `json:"subscription"`
+	URL          string          `json:"url"`
+	EventID      string          `json:"event_id"`
+	EventType    EventType       `json:"event_type"`
+	RunID        string          `json:"run_id,omitempty"`
+	Payload      json.RawMessage `json:"payload"`
+	Status       DeliveryStatus  `json:"status"`
+	Attempts     int             `json:"attempts"`
+	ResponseCode int             `json:"response_code,omitempty"`
+	LastError    string          `json:"last_error,omitempty"`
+
+	// RedeliveryOf is the id of the delivery this one was redelivered from
+	RedeliveryOf string `json:"redelivery_of,omitempty"`
+
+	NextAttemptAt time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DeliveryFilter narrows down the deliveries returned by DeliveryStore.ListDeliveries, zero values match everything
+type DeliveryFilter struct {
+	Subscription string
+	RunID        string
+	Status       DeliveryStatus
+
+	// Limit is the max number of deliveries in a page
+	Limit int
+
+	// Cursor is the DeliveryPage.NextCursor of the previous page
+	Cursor string
+}
+
+// DeliveryPage is a page of webhook deliveries ordered newest first
+type DeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// DeliveryStore persists the webhook delivery log
+type DeliveryStore interface {
+	// CreateDelivery saves a new delivery
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// UpdateDelivery saves an existing delivery
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// GetDelivery loads a delivery by id
+	GetDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+
+	// FindDelivery loads the first delivery of an event to a subscription, redeliveries are not returned
+	FindDelivery(ctx context.Context, eventID, subscription string) (*WebhookDelivery, error)
+
+	// ListDeliveries returns a page of deliveries matching filter
+	ListDeliveries(ctx context.Context, filter DeliveryFilter) (DeliveryPage, error)
+
+	// PendingDeliveries returns up to limit pending deliveries due to be attempted at or before now
+	PendingDeliveries(ctx context.Context, now time.Time, limit int) ([]*WebhookDelivery, error)
+}
+
+// WebhookDispatcher sends webhook deliveries
+type WebhookDispatcher interface {
+	// Redeliver sends the payload of an existing delivery again as a new delivery
+	Redeliver(ctx context.Context, id string) (*WebhookDelivery, error)
+}
@@ -0,0 +1,17 @@
+package webhooks
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+)
+
+var Component = internal.NewComponent(
+	"webhooks",
+	[]config.EnvVar{
+		config.EnvWebhookMaxAttempts,
+		config.EnvWebhookInitialBackoff,
+		config.EnvWebhookMaxBackoff,
+		config.EnvWebhookTimeout},
+	NewDispatcher,
+	NewConfigValidator,
+)
@@ -0,0 +1,92 @@
+package webhooks
+
+import (
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"net/url"
+	"time"
+)
+
+type subscription struct {
+	Name   string   `mapstructure:"name"`
+	URL    string   `mapstructure:"url"`
+	Secret string   `mapstructure:"secret"`
+	Events []string `mapstructure:"events"`
+}
+
+// wants reports if the subscription receives events of the given type, no events means every type
+func (s subscription) wants(eventType internal.EventType) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == "*" || internal.EventType(e) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// backoff returns the wait after the given failed attempt, doubling each time up to the max
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	wait := p.initialBackoff
+	for i := 1; i < attempt && wait < p.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.maxBackoff {
+		wait = p.maxBackoff
+	}
+	return wait
+}
+
+func loadSubscriptions(cfg *viper.Viper) ([]subscription, error) {
+	var subs []subscription
+	if err := cfg.UnmarshalKey(config.WebhookSubscriptions.String(), &subs); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", config.WebhookSubscriptions, err)
+	}
+
+	names := make(map[string]bool)
+	for _, s := range subs {
+		switch {
+		case s.Name == "":
+			return nil, fmt.Errorf("invalid %s: every subscription needs a name", config.WebhookSubscriptions)
+		case names[s.Name]:
+			return nil, fmt.Errorf("invalid %s: subscription %s is defined more than once", config.WebhookSubscriptions, s.Name)
+		case s.Secret == "":
+			return nil, fmt.Errorf("invalid %s: subscription %s needs a secret", config.WebhookSubscriptions, s.Name)
+		}
+		if err := validateURL(s.URL); err != nil {
+			return nil, fmt.Errorf("invalid %s: subscription %s: %w", config.WebhookSubscriptions, s.Name, err)
+		}
+		names[s.Name] = true
+	}
+	return subs, nil
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https url")
+	}
+	return nil
+}
+
+func loadRetryPolicy(cfg *viper.Viper) retryPolicy {
+	return retryPolicy{
+		maxAttempts:    cfg.GetInt(config.WebhookMaxAttempts.String()),
+		initialBackoff: cfg.GetDuration(config.WebhookInitialBackoff.String()),
+		maxBackoff:     cfg.GetDuration(config.WebhookMaxBackoff.String()),
+	}
+}
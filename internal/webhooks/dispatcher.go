@@ -0,0 +1,276 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/app"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	pollInterval = time.Second
+	batchSize    = 100
+)
+
+type dispatcherOut struct {
+	fx.Out
+	Service    app.Service              `group:"services"`
+	Subscriber internal.EventSubscriber `group:"eventSubscribers"`
+	Dispatcher internal.WebhookDispatcher
+}
+
+// dispatcher records a delivery for every subscription that wants a published event and sends pending deliveries in
+// the background, retrying failed ones with exponential backoff
+type dispatcher struct {
+	log           internal.BackgroundLog
+	store         internal.DeliveryStore
+	client        *http.Client
+	subscriptions map[string]subscription
+	ordered       []subscription
+	retries       retryPolicy
+	interval      time.Duration
+	wake          chan struct{}
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+}
+
+func NewDispatcher(cfg *viper.Viper, log internal.BackgroundLog, store internal.DeliveryStore) (dispatcherOut, error) {
+	subs, err := loadSubscriptions(cfg)
+	if err != nil {
+		return dispatcherOut{}, err
+	}
+	d := newDispatcher(log, store, subs, loadRetryPolicy(cfg), cfg.GetDuration(config.WebhookTimeout.String()))
+	return dispatcherOut{Service: d, Subscriber: d, Dispatcher: d}, nil
+}
+
+func newDispatcher(log internal.BackgroundLog, store internal.DeliveryStore, subs []subscription, retries retryPolicy, timeout time.Duration) *dispatcher {
+	d := &dispatcher{
+		log:           log.ChildLog("webhooks"),
+		store:         store,
+		client:        &http.Client{Timeout: timeout},
+		subscriptions: make(map[string]subscription),
+		ordered:       subs,
+		retries:       retries,
+		interval:      pollInterval,
+		wake:          make(chan struct{}, 1),
+	}
+	for _, s := range subs {
+		d.subscriptions[s.Name] = s
+	}
+	return d
+}
+
+func (d *dispatcher) Name() string {
+	return "webhooks"
+}
+
+// Handle records a pending delivery of the event for each subscription that wants it and has no delivery of it yet,
+// the deliveries are sent by the background loop so a slow subscriber never holds up publishing
+func (d *dispatcher) Handle(ctx context.Context, event internal.Event) error {
+	var payload []byte
+	for _, s := range d.ordered {
+		if !s.wants(event.Type) {
+			continue
+		}
+
+		// The same event can be handed over again when publishing it to another subscriber failed, it is only
+		// delivered once to each subscription
+		if _, err := d.store.FindDelivery(ctx, event.ID, s.Name); err == nil {
+			continue
+		} else if !errors.Is(err, internal.ErrDeliveryNotFound) {
+			return fmt.Errorf("unable to look up webhook delivery for %s: %w", s.Name, err)
+		}
+
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("unable to encode event: %w", err)
+			}
+		}
+
+		now := time.Now().UTC()
+		delivery := &internal.WebhookDelivery{
+			ID:            internal.NewRunID(),
+			Subscription:  s.Name,
+			URL:           s.URL,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       payload,
+			Status:        internal.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if event.Run != nil {
+			delivery.RunID = event.Run.ID
+		}
+		if err := d.store.CreateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("unable to save webhook delivery for %s: %w", s.Name, err)
+		}
+	}
+
+	if payload != nil {
+		d.notify()
+	}
+	return nil
+}
+
+func (d *dispatcher) Redeliver(ctx context.Context, id string) (*internal.WebhookDelivery, error) {
+	original, err := d.store.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := d.subscriptions[original.Subscription]
+	if !ok {
+		return nil, fmt.Errorf("%w: subscription %s is no longer configured", internal.ErrInvalidRequest, original.Subscription)
+	}
+
+	now := time.Now().UTC()
+	delivery := &internal.WebhookDelivery{
+		ID:            internal.NewRunID(),
+		Subscription:  s.Name,
+		URL:           s.URL,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		RunID:         original.RunID,
+		Payload:       original.Payload,
+		Status:        internal.DeliveryStatusPending,
+		RedeliveryOf:  original.ID,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := d.store.CreateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("unable to save webhook delivery: %w", err)
+	}
+
+	d.notify()
+	return delivery, nil
+}
+
+func (d *dispatcher) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+
+	d.log.Infof("Starting webhook dispatcher for %d subscriptions", len(d.ordered))
+	d.wg.Add(1)
+	go d.run(runCtx)
+	return nil
+}
+
+func (d *dispatcher) Stop(ctx context.Context) error {
+	if d.cancel == nil {
+		return nil
+	}
+	d.cancel()
+	d.wg.Wait()
+	return nil
+}
+
+func (d *dispatcher) Disabled() bool {
+	return false
+}
+
+func (d *dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) run(ctx context.Context) {
+	defer d.wg.Done()
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.dispatchPending(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *dispatcher) dispatchPending(ctx context.Context) {
+	deliveries, err := d.store.PendingDeliveries(ctx, time.Now().UTC(), batchSize)
+	if err != nil {
+		d.log.Err(err, "Unable to load pending webhook deliveries")
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.attempt(ctx, delivery)
+	}
+}
+
+// attempt sends a delivery once and saves the outcome, scheduling a retry if it failed and has attempts left
+func (d *dispatcher) attempt(ctx context.Context, delivery *internal.WebhookDelivery) {
+	delivery.Attempts++
+	delivery.ResponseCode = 0
+	delivery.LastError = ""
+
+	s, ok := d.subscriptions[delivery.Subscription]
+	if !ok {
+		delivery.LastError = fmt.Sprintf("subscription %s is no longer configured", delivery.Subscription)
+		delivery.Status = internal.DeliveryStatusFailed
+	} else if err := d.send(ctx, s, delivery); err != nil {
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= d.retries.maxAttempts {
+			delivery.Status = internal.DeliveryStatusFailed
+		} else {
+			delivery.NextAttemptAt = time.Now().UTC().Add(d.retries.backoff(delivery.Attempts))
+		}
+	} else {
+		delivery.Status = internal.DeliveryStatusSucceeded
+	}
+
+	if delivery.Status == internal.DeliveryStatusFailed {
+		d.log.Warnw("Webhook delivery failed", "delivery_id", delivery.ID, "subscription", delivery.Subscription, "attempts", delivery.Attempts, "error", delivery.LastError)
+	}
+
+	delivery.UpdatedAt = time.Now().UTC()
+	if err := d.store.UpdateDelivery(ctx, delivery); err != nil {
+		d.log.Errw(err, "Unable to save webhook delivery", "delivery_id", delivery.ID)
+	}
+}
+
+func (d *dispatcher) send(ctx context.Context, s subscription, delivery *internal.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(headerEvent, string(delivery.EventType))
+	req.Header.Set(headerDelivery, delivery.ID)
+	req.Header.Set(headerSignature, sign(s.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// The body is drained so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	delivery.ResponseCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("subscriber responded with %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"deploy-runner/config"
+	"deploy-runner/internal"
+	"deploy-runner/internal/logging"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDispatcher(t *testing.T) {
+	t.Run("TestSignedDelivery", testSignedDelivery)
+	t.Run("TestRetriesWithBackoff", testRetriesWithBackoff)
+	t.Run("TestRedeliver", testRedeliver)
+	t.Run("TestHandleOncePerEvent", testHandleOncePerEvent)
+}
+
+type memDeliveryStore struct {
+	mu         sync.Mutex
+	deliveries map[string]internal.WebhookDelivery
+	order      []string
+}
+
+func newMemDeliveryStore() *memDeliveryStore {
+	return &memDeliveryStore{deliveries: make(map[string]internal.WebhookDelivery)}
+}
+
+func (s *memDeliveryStore) CreateDelivery(ctx context.Context, d *internal.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[d.ID] = *d
+	s.order = append(s.order, d.ID)
+	return nil
+}
+
+func (s *memDeliveryStore) UpdateDelivery(ctx context.Context, d *internal.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries[d.ID] = *d
+	return nil
+}
+
+func (s *memDeliveryStore) GetDelivery(ctx context.Context, id string) (*internal.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[id]
+	if !ok {
+		return nil, internal.ErrDeliveryNotFound
+	}
+	return &d, nil
+}
+
+func (s *memDeliveryStore) FindDelivery(ctx context.Context, eventID, subscription string) (*internal.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range s.order {
+		d := s.deliveries[id]
+		if d.EventID == eventID && d.Subscription == subscription && d.RedeliveryOf == "" {
+			return &d, nil
+		}
+	}
+	return nil, internal.ErrDeliveryNotFound
+}
+
+func (s *memDeliveryStore) ListDeliveries(ctx context.Context, filter internal.DeliveryFilter) (internal.DeliveryPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	page := internal.DeliveryPage{}
+	for _, id := range s.order {
+		d := s.deliveries[id]
+		page.Deliveries = append(page.Deliveries, &d)
+	}
+	return page, nil
+}
+
+func (s *memDeliveryStore) PendingDeliveries(ctx context.Context, now time.Time, limit int) ([]*internal.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make([]*internal.WebhookDelivery, 0)
+	for _, id := range s.order {
+		d := s.deliveries[id]
+		if d.Status == internal.DeliveryStatusPending && !d.NextAttemptAt.After(now) {
+			pending = append(pending, &d)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memDeliveryStore) all() []internal.WebhookDelivery {
+	page, _ := s.ListDeliveries(context.Background(), internal.DeliveryFilter{})
+	result := make([]internal.WebhookDelivery, 0)
+	for _, d := range page.Deliveries {
+		result = append(result, *d)
+	}
+	return result
+}
+
+// receiver is a webhook subscriber that fails the first failures requests
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func startDispatcher(t *testing.T, store internal.DeliveryStore, subs []subscription, maxAttempts int) *dispatcher {
+	cfg := viper.New()
+	cfg.Set(config.LogFormat.String(), "console")
+	cfg.Set(config.LogLevel.String(), "error")
+
+	d := newDispatcher(logging.NewBackgroundLog(cfg), store, subs, retryPolicy{maxAttempts: maxAttempts, initialBackoff: time.Millisecond, maxBackoff: 5 * time.Millisecond}, time.Second)
+	d.interval = time.Millisecond
+	assert.NoError(t, d.Start(context.Background()))
+	t.Cleanup(func() {
+		assert.NoError(t, d.Stop(context.Background()))
+	})
+	return d
+}
+
+func runEvent(eventType internal.EventType) internal.Event {
+	return internal.NewEvent(eventType, &internal.Run{ID: "run-1", Stack: "network"}, "")
+}
+
+func testSignedDelivery(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := newMemDeliveryStore()
+	d := startDispatcher(t, store, []subscription{
+		{Name: "applied-only", URL: server.URL, Secret: "s3cret", Events: []string{"run.applied"}},
+		{Name: "failed-only", URL: server.URL, Secret: "other", Events: []string{"run.failed"}},
+	}, 3)
+
+	assert.NoError(t, d.Handle(context.Background(), runEvent(internal.EventRunApplied)))
+	assert.Eventually(t, func() bool {
+		all := store.all()
+		return len(all) == 1 && all[0].Status == internal.DeliveryStatusSucceeded
+	}, time.Second, time.Millisecond)
+
+	delivery := store.all()[0]
+	assert.Equal(t, "applied-only", delivery.Subscription)
+	assert.Equal(t, "run-1", delivery.RunID)
+	assert.Equal(t, http.StatusNoContent, delivery.ResponseCode)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	req, body := rc.requests[0], rc.bodies[0]
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, "sha256="+hex.EncodeToString(mac.Sum(nil)), req.Header.Get(headerSignature))
+	assert.Equal(t, "run.applied", req.Header.Get(headerEvent))
+	assert.Equal(t, delivery.ID, req.Header.Get(headerDelivery))
+
+	var event internal.Event
+	assert.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, internal.EventRunApplied, event.Type)
+	assert.Equal(t, "network", event.Run.Stack)
+}
+
+func testRetriesWithBackoff(t *testing.T) {
+	rc := &receiver{failures: 2}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := newMemDeliveryStore()
+	d := startDispatcher(t, store, []subscription{{Name: "all", URL: server.URL, Secret: "s3cret"}}, 5)
+
+	assert.NoError(t, d.Handle(context.Background(), runEvent(internal.EventRunFailed)))
+	assert.Eventually(t, func() bool {
+		all := store.all()
+		return len(all) == 1 && all[0].Status == internal.DeliveryStatusSucceeded
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, store.all()[0].Attempts)
+	assert.Equal(t, 3, rc.count())
+}
+
+func testRedeliver(t *testing.T) {
+	rc := &receiver{failures: 2}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := newMemDeliveryStore()
+	d := startDispatcher(t, store, []subscription{{Name: "all", URL: server.URL, Secret: "s3cret"}}, 2)
+
+	assert.NoError(t, d.Handle(context.Background(), runEvent(internal.EventRunApplied)))
+	assert.Eventually(t, func() bool {
+		all := store.all()
+		return len(all) == 1 && all[0].Status == internal.DeliveryStatusFailed
+	}, time.Second, time.Millisecond)
+	failed := store.all()[0]
+	assert.Equal(t, 2, failed.Attempts)
+	assert.Contains(t, failed.LastError, "500")
+
+	redelivery, err := d.Redeliver(context.Background(), failed.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, failed.ID, redelivery.RedeliveryOf)
+	assert.Eventually(t, func() bool {
+		d, err := store.GetDelivery(context.Background(), redelivery.ID)
+		return err == nil && d.Status == internal.DeliveryStatusSucceeded
+	}, time.Second, time.Millisecond)
+
+	_, err = d.Redeliver(context.Background(), "missing")
+	assert.ErrorIs(t, err, internal.ErrDeliveryNotFound)
+}
+
+func testHandleOncePerEvent(t *testing.T) {
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	store := newMemDeliveryStore()
+	d := startDispatcher(t, store, []subscription{{Name: "all", URL: server.URL, Secret: "s3cret"}}, 3)
+
+	// The relay hands the event over again when another subscriber failed to handle it
+	event := runEvent(internal.EventRunApplied)
+	assert.NoError(t, d.Handle(context.Background(), event))
+	assert.NoError(t, d.Handle(context.Background(), event))
+	assert.Eventually(t, func() bool {
+		all := store.all()
+		return len(all) == 1 && all[0].Status == internal.DeliveryStatusSucceeded
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, rc.count())
+}
@@ -0,0 +1,23 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	headerEvent     = "X-Deploy-Runner-Event"
+	headerDelivery  = "X-Deploy-Runner-Delivery"
+	headerSignature = "X-Deploy-Runner-Signature-256"
+
+	signaturePrefix = "sha256="
+)
+
+// sign returns the signature header value of a payload, the hex encoded HMAC-SHA256 of the body using the
+// subscription's secret in the same format GitHub uses so existing verification code can be reused
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
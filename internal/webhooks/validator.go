@@ -0,0 +1,39 @@
+package webhooks
+
+import (
+	"deploy-runner/config"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+)
+
+type validatorOut struct {
+	fx.Out
+	Validator config.Validator `group:"configValidators"`
+}
+
+type configValidator struct {
+	cfg *viper.Viper
+}
+
+func NewConfigValidator(cfg *viper.Viper) validatorOut {
+	return validatorOut{Validator: &configValidator{cfg: cfg}}
+}
+
+func (v *configValidator) Validate() error {
+	if _, err := loadSubscriptions(v.cfg); err != nil {
+		return err
+	}
+
+	p := loadRetryPolicy(v.cfg)
+	if p.maxAttempts < 1 {
+		return fmt.Errorf("%s must be at least 1", config.WebhookMaxAttempts)
+	}
+	if p.initialBackoff <= 0 || p.maxBackoff < p.initialBackoff {
+		return fmt.Errorf("%s must be greater than zero and no more than %s", config.WebhookInitialBackoff, config.WebhookMaxBackoff)
+	}
+	if v.cfg.GetDuration(config.WebhookTimeout.String()) <= 0 {
+		return fmt.Errorf("%s must be greater than zero", config.WebhookTimeout)
+	}
+	return nil
+}